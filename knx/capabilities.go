@@ -0,0 +1,98 @@
+// Licensed under the MIT license which can be found in the LICENSE file.
+
+package knx
+
+import (
+	"errors"
+	"time"
+
+	"github.com/LB-00/knx-go/knx/cemi"
+)
+
+// DeviceCapabilities describes which management services a device supports.
+type DeviceCapabilities struct {
+	// MaskVersion is the device descriptor type 0 of the device.
+	MaskVersion uint16
+
+	// Known indicates whether the mask version is known. Unknown mask versions report no
+	// capabilities, as it is not safe to assume any.
+	Known bool
+
+	// Memory indicates support for A_Memory_Read and A_Memory_Write.
+	Memory bool
+
+	// UserMemory indicates support for A_UserMemory_Read and A_UserMemory_Write.
+	UserMemory bool
+
+	// Properties indicates support for A_PropertyValue_Read and A_PropertyValue_Write.
+	Properties bool
+
+	// ExtendedMemory indicates support for A_MemoryExtended_Read and A_MemoryExtended_Write.
+	ExtendedMemory bool
+
+	// ExtendedProperties indicates support for the extended property services.
+	ExtendedProperties bool
+}
+
+// maskCapabilities maps known mask versions to the capabilities of the respective profile.
+var maskCapabilities = map[uint16]DeviceCapabilities{
+	// System 1 (BCU 1)
+	0x0010: {Memory: true, UserMemory: true},
+	0x0011: {Memory: true, UserMemory: true},
+	0x0012: {Memory: true, UserMemory: true},
+
+	// System 2 (BCU 2)
+	0x0020: {Memory: true, UserMemory: true, Properties: true},
+	0x0021: {Memory: true, UserMemory: true, Properties: true},
+	0x0025: {Memory: true, UserMemory: true, Properties: true},
+
+	// System 7 (BIM M 112)
+	0x0700: {Memory: true, UserMemory: true, Properties: true},
+	0x0701: {Memory: true, UserMemory: true, Properties: true},
+	0x0705: {Memory: true, UserMemory: true, Properties: true},
+
+	// System B
+	0x07b0: {Memory: true, Properties: true, ExtendedMemory: true, ExtendedProperties: true},
+
+	// KNXnet/IP router
+	0x091a: {Memory: true, Properties: true},
+
+	// IP devices implementing System B
+	0x57b0: {Memory: true, Properties: true, ExtendedMemory: true, ExtendedProperties: true},
+}
+
+// CapabilitiesForMask looks up the capabilities of devices with the given mask version.
+func CapabilitiesForMask(mask uint16) DeviceCapabilities {
+	caps, ok := maskCapabilities[mask]
+	caps.MaskVersion = mask
+	caps.Known = ok
+
+	return caps
+}
+
+// DefaultCapabilitiesTimeout is how long CapabilitiesDefault waits for the response of the device.
+const DefaultCapabilitiesTimeout = 6 * time.Second
+
+// CapabilitiesDefault works like Capabilities, but waits up to DefaultCapabilitiesTimeout for the
+// response.
+func (conn *P2PConnection) CapabilitiesDefault() (DeviceCapabilities, error) {
+	return conn.Capabilities(DefaultCapabilitiesTimeout)
+}
+
+// Capabilities reads the device descriptor type 0 (mask version) of the device and reports which
+// management services it supports. It waits up to t for the response.
+func (conn *P2PConnection) Capabilities(t time.Duration) (DeviceCapabilities, error) {
+	req := conn.newRequest(cemi.MaskVersionRead, []byte{0})
+
+	res, err := conn.Send(req, cemi.MaskVersionResponse, t)
+	if err != nil {
+		return DeviceCapabilities{}, err
+	}
+
+	app := res.(*cemi.LDataInd).LData.Data.(*cemi.AppData)
+	if len(app.Data) < 3 || app.Data[0] != 0 {
+		return DeviceCapabilities{}, errors.New("invalid device descriptor response")
+	}
+
+	return CapabilitiesForMask(uint16(app.Data[1])<<8 | uint16(app.Data[2])), nil
+}
@@ -0,0 +1,68 @@
+// Licensed under the MIT license which can be found in the LICENSE file.
+
+package knx
+
+import (
+	"testing"
+
+	"github.com/LB-00/knx-go/knx/cemi"
+)
+
+func TestCapabilitiesForMask(t *testing.T) {
+	t.Run("System7", func(t *testing.T) {
+		caps := CapabilitiesForMask(0x0705)
+		if !caps.Known || !caps.Memory || !caps.Properties {
+			t.Errorf("Unexpected capabilities: %+v", caps)
+		}
+
+		if caps.ExtendedMemory || caps.ExtendedProperties {
+			t.Error("System 7 does not support extended services")
+		}
+	})
+
+	t.Run("SystemB", func(t *testing.T) {
+		caps := CapabilitiesForMask(0x07b0)
+		if !caps.Known || !caps.ExtendedMemory || !caps.ExtendedProperties {
+			t.Errorf("Unexpected capabilities: %+v", caps)
+		}
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		caps := CapabilitiesForMask(0xabcd)
+		if caps != (DeviceCapabilities{MaskVersion: 0xabcd}) {
+			t.Errorf("Unexpected capabilities: %+v", caps)
+		}
+	})
+}
+
+func TestP2PConnection_Capabilities(t *testing.T) {
+	addr := cemi.NewIndividualAddr3(1, 1, 5)
+
+	m, tunnel := makeTestManagement(t)
+	confirmConnect(tunnel)
+
+	conn, err := m.Connect(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Disconnect(addr)
+
+	// The device acknowledges the request and responds with a System B mask version.
+	tunnel.inbound <- &cemi.LDataInd{LData: cemi.LData{Source: addr, Data: cemi.TAck(0)}}
+	tunnel.inbound <- &cemi.LDataInd{LData: cemi.LData{
+		Source: addr,
+		Data: &cemi.AppData{
+			Command: cemi.MaskVersionResponse,
+			Data:    []byte{0x00, 0x07, 0xb0},
+		},
+	}}
+
+	caps, err := conn.CapabilitiesDefault()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if caps.MaskVersion != 0x07b0 || !caps.ExtendedMemory {
+		t.Errorf("Unexpected capabilities: %+v", caps)
+	}
+}
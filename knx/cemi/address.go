@@ -5,6 +5,7 @@
 package cemi
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strconv"
@@ -17,6 +18,12 @@ import (
 // Individual address zero (0.0.0) is not allowed.
 type IndividualAddr uint16
 
+// IsValidIndividualAddr reports whether the given individual address may be used as a device
+// address. Individual address zero (0.0.0) is not allowed.
+func IsValidIndividualAddr(addr IndividualAddr) bool {
+	return addr != 0
+}
+
 // NewIndividualAddr3 generates an individual address from an
 // "a.b.c" representation, where a is the Area Address [0..15],
 // b is the Line Address [0..15] and c is the Device Address [0..255].
@@ -85,6 +92,55 @@ func (addr IndividualAddr) String() string {
 	return fmt.Sprintf("%d.%d.%d", uint8(addr>>12)&0xF, uint8(addr>>8)&0xF, uint8(addr))
 }
 
+// MarshalJSON encodes the individual address as its "a.b.c" string representation.
+func (addr IndividualAddr) MarshalJSON() ([]byte, error) {
+	return json.Marshal(addr.String())
+}
+
+// UnmarshalJSON decodes an individual address from any string representation accepted by
+// NewIndividualAddrString. Plain numbers are accepted as raw addresses. The zero address 0.0.0
+// is accepted so that zero values survive a round trip.
+func (addr *IndividualAddr) UnmarshalJSON(data []byte) error {
+	var raw uint16
+	if err := json.Unmarshal(data, &raw); err == nil {
+		*addr = IndividualAddr(raw)
+		return nil
+	}
+
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+
+	if str == "0.0.0" {
+		*addr = 0
+		return nil
+	}
+
+	parsed, err := NewIndividualAddrString(str)
+	if err != nil {
+		return err
+	}
+
+	*addr = parsed
+	return nil
+}
+
+// GroupAddrFormat determines how group addresses are represented as strings when encoded.
+type GroupAddrFormat uint8
+
+const (
+	// GroupAddrFormat3Level is the "main/middle/sub" representation.
+	GroupAddrFormat3Level GroupAddrFormat = iota
+
+	// GroupAddrFormat2Level is the "main/sub" representation.
+	GroupAddrFormat2Level
+)
+
+// GroupAddrJSONFormat is the representation used when encoding group addresses as JSON.
+// Decoding accepts either representation regardless of this setting.
+var GroupAddrJSONFormat = GroupAddrFormat3Level
+
 // GroupAddr is an address for a KNX group object. Group address
 // zero (0/0/0) is not allowed.
 type GroupAddr uint16
@@ -156,3 +212,53 @@ func NewGroupAddrString(addr string) (GroupAddr, error) {
 func (addr GroupAddr) String() string {
 	return fmt.Sprintf("%d/%d/%d", uint8(addr>>11)&0x1F, uint8(addr>>8)&0x7, uint8(addr))
 }
+
+// String2 generates a string representation with groups "a/b" where
+// a = Main Group = 5 bits, b = Sub Group = 11 bits.
+func (addr GroupAddr) String2() string {
+	return fmt.Sprintf("%d/%d", uint8(addr>>11)&0x1F, uint16(addr)&0x7FF)
+}
+
+// Format generates a string representation in the given format.
+func (addr GroupAddr) Format(format GroupAddrFormat) string {
+	if format == GroupAddrFormat2Level {
+		return addr.String2()
+	}
+
+	return addr.String()
+}
+
+// MarshalJSON encodes the group address as a string in the representation selected by
+// GroupAddrJSONFormat.
+func (addr GroupAddr) MarshalJSON() ([]byte, error) {
+	return json.Marshal(addr.Format(GroupAddrJSONFormat))
+}
+
+// UnmarshalJSON decodes a group address from any string representation accepted by
+// NewGroupAddrString. Plain numbers are accepted as raw addresses. The zero address is
+// accepted so that zero values survive a round trip.
+func (addr *GroupAddr) UnmarshalJSON(data []byte) error {
+	var raw uint16
+	if err := json.Unmarshal(data, &raw); err == nil {
+		*addr = GroupAddr(raw)
+		return nil
+	}
+
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+
+	if str == "0/0/0" || str == "0/0" {
+		*addr = 0
+		return nil
+	}
+
+	parsed, err := NewGroupAddrString(str)
+	if err != nil {
+		return err
+	}
+
+	*addr = parsed
+	return nil
+}
@@ -4,6 +4,7 @@
 package cemi
 
 import (
+	"encoding/json"
 	"testing"
 )
 
@@ -53,6 +54,17 @@ func Test_IndividualAddresses(t *testing.T) {
 	}
 }
 
+// Test Individual Address validity
+func Test_IsValidIndividualAddr(t *testing.T) {
+	if IsValidIndividualAddr(0) {
+		t.Error("0.0.0 must not be valid.")
+	}
+
+	if !IsValidIndividualAddr(NewIndividualAddr3(1, 1, 1)) {
+		t.Error("1.1.1 must be valid.")
+	}
+}
+
 // Test Group Addresses
 func Test_GroupAddresses(t *testing.T) {
 	type Addr struct {
@@ -90,3 +102,85 @@ func Test_GroupAddresses(t *testing.T) {
 		}
 	}
 }
+
+func TestIndividualAddr_JSON(t *testing.T) {
+	type payload struct {
+		Source IndividualAddr
+	}
+
+	for _, addr := range []IndividualAddr{0, NewIndividualAddr3(1, 1, 5), 0xFFFF} {
+		data, err := json.Marshal(payload{addr})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := `{"Source":"` + addr.String() + `"}`
+		if string(data) != expected {
+			t.Errorf("Marshalled %v as %s, expected %s", addr, data, expected)
+		}
+
+		var decoded payload
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatal(err)
+		}
+
+		if decoded.Source != addr {
+			t.Errorf("Round trip of %v yielded %v", addr, decoded.Source)
+		}
+	}
+
+	var addr IndividualAddr
+	if err := json.Unmarshal([]byte("4357"), &addr); err != nil || addr != NewIndividualAddr3(1, 1, 5) {
+		t.Errorf("Raw address decoded as %v (%v)", addr, err)
+	}
+
+	for _, invalid := range []string{`"16.1.1"`, `"foo"`, `true`, `-1`} {
+		if err := json.Unmarshal([]byte(invalid), &addr); err == nil {
+			t.Errorf("%s should not be decoded", invalid)
+		}
+	}
+}
+
+func TestGroupAddr_JSON(t *testing.T) {
+	defer func(format GroupAddrFormat) { GroupAddrJSONFormat = format }(GroupAddrJSONFormat)
+
+	addr := NewGroupAddr3(1, 2, 3)
+
+	for format, expected := range map[GroupAddrFormat]string{
+		GroupAddrFormat3Level: `"1/2/3"`,
+		GroupAddrFormat2Level: `"1/515"`,
+	} {
+		GroupAddrJSONFormat = format
+
+		data, err := json.Marshal(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(data) != expected {
+			t.Errorf("Marshalled %v as %s, expected %s", addr, data, expected)
+		}
+
+		var decoded GroupAddr
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatal(err)
+		}
+
+		if decoded != addr {
+			t.Errorf("Round trip of %v yielded %v", addr, decoded)
+		}
+
+		var zero GroupAddr = 1
+		data, _ = json.Marshal(GroupAddr(0))
+		if err := json.Unmarshal(data, &zero); err != nil || zero != 0 {
+			t.Errorf("Round trip of zero address yielded %v (%v)", zero, err)
+		}
+	}
+
+	var decoded GroupAddr
+	for _, invalid := range []string{`"32/0/0"`, `"1/2048"`, `"foo"`} {
+		if err := json.Unmarshal([]byte(invalid), &decoded); err == nil {
+			t.Errorf("%s should not be decoded", invalid)
+		}
+	}
+}
@@ -35,37 +35,80 @@ const (
 	// LRawConCode is the message code for L_Raw.con.
 	LRawConCode MessageCode = 0x2F
 
-	// LPollDataReqCode MessageCode = 0x13
-	// LPollDataConCode MessageCode = 0x25
-)
+	// LPollDataReqCode is the message code for L_PollData.req.
+	LPollDataReqCode MessageCode = 0x13
 
-// String converts the message code to a string.
-func (code MessageCode) String() string {
-	switch code {
-	case LBusmonIndCode:
-		return "LBusmon.ind"
+	// LPollDataConCode is the message code for L_PollData.con.
+	LPollDataConCode MessageCode = 0x25
 
-	case LDataReqCode:
-		return "LData.req"
+	// MPropReadReqCode is the message code for M_PropRead.req.
+	MPropReadReqCode MessageCode = 0xFC
 
-	case LDataIndCode:
-		return "LData.ind"
+	// MPropReadConCode is the message code for M_PropRead.con.
+	MPropReadConCode MessageCode = 0xFB
 
-	case LDataConCode:
-		return "LData.con"
+	// MPropWriteReqCode is the message code for M_PropWrite.req.
+	MPropWriteReqCode MessageCode = 0xF6
 
-	case LRawReqCode:
-		return "LRaw.req"
+	// MPropWriteConCode is the message code for M_PropWrite.con.
+	MPropWriteConCode MessageCode = 0xF5
 
-	case LRawIndCode:
-		return "LRaw.ind"
+	// MPropInfoIndCode is the message code for M_PropInfo.ind.
+	MPropInfoIndCode MessageCode = 0xF7
 
-	case LRawConCode:
-		return "LRaw.con"
+	// MFuncPropCommandReqCode is the message code for M_FuncPropCommand.req.
+	MFuncPropCommandReqCode MessageCode = 0xF8
 
-	default:
-		return fmt.Sprintf("%#x", uint8(code))
+	// MFuncPropStateReadReqCode is the message code for M_FuncPropStateRead.req.
+	MFuncPropStateReadReqCode MessageCode = 0xF9
+
+	// MFuncPropConCode is the message code for M_FuncPropCommand.con and M_FuncPropStateRead.con.
+	MFuncPropConCode MessageCode = 0xFA
+
+	// MResetReqCode is the message code for M_Reset.req.
+	MResetReqCode MessageCode = 0xF1
+
+	// MResetIndCode is the message code for M_Reset.ind.
+	MResetIndCode MessageCode = 0xF0
+)
+
+// messageCodeNames maps the known message codes to their names.
+var messageCodeNames = map[MessageCode]string{
+	LBusmonIndCode:            "LBusmon.ind",
+	LDataReqCode:              "LData.req",
+	LDataIndCode:              "LData.ind",
+	LDataConCode:              "LData.con",
+	LRawReqCode:               "LRaw.req",
+	LRawIndCode:               "LRaw.ind",
+	LRawConCode:               "LRaw.con",
+	LPollDataReqCode:          "LPollData.req",
+	LPollDataConCode:          "LPollData.con",
+	MPropReadReqCode:          "MPropRead.req",
+	MPropReadConCode:          "MPropRead.con",
+	MPropWriteReqCode:         "MPropWrite.req",
+	MPropWriteConCode:         "MPropWrite.con",
+	MPropInfoIndCode:          "MPropInfo.ind",
+	MFuncPropCommandReqCode:   "MFuncPropCommand.req",
+	MFuncPropStateReadReqCode: "MFuncPropStateRead.req",
+	MFuncPropConCode:          "MFuncProp.con",
+	MResetReqCode:             "MReset.req",
+	MResetIndCode:             "MReset.ind",
+}
+
+// DecodeMessageCode returns the name of the message code in the first byte of a CEMI-encoded frame
+// and whether the code is known.
+func DecodeMessageCode(b byte) (string, bool) {
+	name, ok := messageCodeNames[MessageCode(b)]
+	return name, ok
+}
+
+// String converts the message code to a string.
+func (code MessageCode) String() string {
+	if name, ok := DecodeMessageCode(uint8(code)); ok {
+		return name
 	}
+
+	return fmt.Sprintf("%#x", uint8(code))
 }
 
 // Info is the additional info segment of a CEMI-encoded frame.
@@ -187,6 +230,28 @@ func Unpack(data []byte, message *Message) (n uint, err error) {
 	case LRawIndCode:
 		body = &LRawInd{}
 
+	case MPropReadReqCode:
+		body = &MPropReadReq{}
+
+	case MPropReadConCode:
+		body = &MPropReadCon{}
+
+	case MPropWriteReqCode:
+		body = &MPropWriteReq{}
+
+	case MPropWriteConCode:
+		body = &MPropWriteCon{}
+
+	case MPropInfoIndCode:
+		body = &MPropInfoInd{}
+
+	case MResetReqCode:
+		body = &MResetReq{}
+
+	case MResetIndCode:
+		body = &MResetInd{}
+
+	// The remaining known codes, like L_PollData and M_FuncProp, are kept as UnsupportedMessage.
 	default:
 		body = &UnsupportedMessage{Code: code}
 	}
@@ -6,6 +6,7 @@ package cemi
 import (
 	"bytes"
 	"math/rand"
+	"reflect"
 	"testing"
 )
 
@@ -61,3 +62,95 @@ func TestUnpack(t *testing.T) {
 		}
 	}
 }
+
+func TestDecodeMessageCode(t *testing.T) {
+	codes := map[byte]string{
+		0x2B: "LBusmon.ind",
+		0x11: "LData.req",
+		0x29: "LData.ind",
+		0x2E: "LData.con",
+		0x10: "LRaw.req",
+		0x2D: "LRaw.ind",
+		0x2F: "LRaw.con",
+		0x13: "LPollData.req",
+		0x25: "LPollData.con",
+		0xFC: "MPropRead.req",
+		0xFB: "MPropRead.con",
+		0xF6: "MPropWrite.req",
+		0xF5: "MPropWrite.con",
+		0xF7: "MPropInfo.ind",
+		0xF8: "MFuncPropCommand.req",
+		0xF9: "MFuncPropStateRead.req",
+		0xFA: "MFuncProp.con",
+		0xF1: "MReset.req",
+		0xF0: "MReset.ind",
+	}
+
+	for b, expected := range codes {
+		if name, ok := DecodeMessageCode(b); !ok || name != expected {
+			t.Errorf("Unexpected name of %#x: %s, %v", b, name, ok)
+		}
+	}
+
+	if name, ok := DecodeMessageCode(0x42); ok {
+		t.Errorf("Unexpected name of unknown code: %s", name)
+	}
+
+	if name := MessageCode(0x42).String(); name != "0x42" {
+		t.Errorf("Unexpected string of unknown code: %s", name)
+	}
+}
+
+func TestUnpack_MessageTypes(t *testing.T) {
+	// M_PropRead.con of PID_MAX_APDU_LENGTH of the cEMI server object.
+	mprop := []byte{0x00, 0x08, 0x01, 0x38, 0x10, 0x01, 0x00, 0xfe}
+
+	testCases := []struct {
+		data     []byte
+		expected Message
+	}{
+		{append([]byte{0x2B}, 0x01, 0x02), &LBusmonInd{0x01, 0x02}},
+		{append([]byte{0xFC}, mprop[:6]...), &MPropReadReq{MProp{
+			MPropHeader{ObjectType: 8, ObjectInstance: 1, PropertyID: 56, Count: 1, StartIndex: 1}, []byte{},
+		}}},
+		{append([]byte{0xFB}, mprop...), &MPropReadCon{MProp{
+			MPropHeader{ObjectType: 8, ObjectInstance: 1, PropertyID: 56, Count: 1, StartIndex: 1}, []byte{0x00, 0xfe},
+		}}},
+		{append([]byte{0xF6}, mprop...), &MPropWriteReq{MProp{
+			MPropHeader{ObjectType: 8, ObjectInstance: 1, PropertyID: 56, Count: 1, StartIndex: 1}, []byte{0x00, 0xfe},
+		}}},
+		{append([]byte{0xF5}, mprop[:6]...), &MPropWriteCon{MProp{
+			MPropHeader{ObjectType: 8, ObjectInstance: 1, PropertyID: 56, Count: 1, StartIndex: 1}, []byte{},
+		}}},
+		{append([]byte{0xF7}, mprop...), &MPropInfoInd{MProp{
+			MPropHeader{ObjectType: 8, ObjectInstance: 1, PropertyID: 56, Count: 1, StartIndex: 1}, []byte{0x00, 0xfe},
+		}}},
+		{[]byte{0xF1}, &MResetReq{}},
+		{[]byte{0xF0}, &MResetInd{}},
+		{[]byte{0xF8, 0x01}, &UnsupportedMessage{Code: MFuncPropCommandReqCode, Data: []byte{0x01}}},
+	}
+
+	for _, testCase := range testCases {
+		var msg Message
+		n, err := Unpack(testCase.data, &msg)
+		if err != nil {
+			t.Errorf("Unexpected error for %x: %v", testCase.data, err)
+			continue
+		}
+
+		if n != uint(len(testCase.data)) {
+			t.Errorf("Unpacked %d of %d bytes", n, len(testCase.data))
+		}
+
+		if !reflect.DeepEqual(msg, testCase.expected) {
+			t.Errorf("Unexpected message %#v, expected %#v", msg, testCase.expected)
+		}
+
+		// The message packs into the same frame.
+		buffer := make([]byte, Size(msg))
+		Pack(buffer, msg)
+		if !bytes.Equal(buffer, testCase.data) {
+			t.Errorf("Packed % x, expected % x", buffer, testCase.data)
+		}
+	}
+}
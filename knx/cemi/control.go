@@ -43,22 +43,101 @@ const (
 	Control1HasError ControlField1 = 1
 )
 
+// IsExtendedFrame determines if the frame uses the extended frame format.
+func (ctrl1 ControlField1) IsExtendedFrame() bool {
+	return ctrl1&Control1StdFrame == 0
+}
+
+// Priority returns the priority of the frame.
+func (ctrl1 ControlField1) Priority() Priority {
+	return Priority(ctrl1>>2) & 3
+}
+
 // Control1Prio generates the control field 1 flag for the given priority.
 func Control1Prio(prio Priority) ControlField1 {
 	return ControlField1(prio&3) << 2
 }
 
-// ControlField2 contains various control information.
+// ControlField2 contains various control information. The most significant bit determines the
+// destination address type, the following three bits contain the hop count and the lower four bits
+// the extended frame format.
 type ControlField2 uint8
 
+// AddressType determines how the destination address of a frame is interpreted.
+type AddressType uint8
+
+const (
+	// AddressTypeIndividual indicates an individual destination address.
+	AddressTypeIndividual AddressType = 0
+
+	// AddressTypeGroup indicates a group destination address.
+	AddressTypeGroup AddressType = 1
+)
+
+// String returns a readable representation of the address type.
+func (typ AddressType) String() string {
+	if typ == AddressTypeGroup {
+		return "group"
+	}
+
+	return "individual"
+}
+
 // IsGroupAddr determines if the destination address is a group address.
 func (ctrl2 ControlField2) IsGroupAddr() bool {
 	return ctrl2&Control2GroupAddr == Control2GroupAddr
 }
 
+// AddressType retrieves the type of the destination address.
+func (ctrl2 ControlField2) AddressType() AddressType {
+	if ctrl2.IsGroupAddr() {
+		return AddressTypeGroup
+	}
+
+	return AddressTypeIndividual
+}
+
 // Hops retrieves the number of hops.
 func (ctrl2 ControlField2) Hops() uint8 {
-	return uint8(ctrl2>>7) & 7
+	return uint8(ctrl2>>4) & 7
+}
+
+// HopCount retrieves the number of hops. It is equivalent to Hops.
+func (ctrl2 ControlField2) HopCount() uint8 {
+	return ctrl2.Hops()
+}
+
+// ExtendedFrameFormat retrieves the extended frame format. Zero indicates a frame with a standard
+// or extended destination address, 0b01xx indicates LTE-HEE addressing.
+func (ctrl2 ControlField2) ExtendedFrameFormat() uint8 {
+	return uint8(ctrl2) & 0xF
+}
+
+// IsLTEFrame determines if the frame uses LTE-HEE (Logical Tag Extended) addressing, which is used
+// by HVAC devices.
+func (ctrl2 ControlField2) IsLTEFrame() bool {
+	return ctrl2.ExtendedFrameFormat()&0xC == 0x4
+}
+
+// WithAddressType returns a copy of the control field with the given destination address type.
+func (ctrl2 ControlField2) WithAddressType(typ AddressType) ControlField2 {
+	if typ == AddressTypeGroup {
+		return ctrl2 | Control2GroupAddr
+	}
+
+	return ctrl2 &^ Control2GroupAddr
+}
+
+// WithHopCount returns a copy of the control field with the given number of hops. Values greater
+// than 7 are clamped.
+func (ctrl2 ControlField2) WithHopCount(hops uint8) ControlField2 {
+	return ctrl2&^(7<<4) | Control2Hops(hops)
+}
+
+// WithExtendedFrameFormat returns a copy of the control field with the given extended frame
+// format. Only the lower four bits are used.
+func (ctrl2 ControlField2) WithExtendedFrameFormat(format uint8) ControlField2 {
+	return ctrl2&^0xF | ControlField2(format&0xF)
 }
 
 const (
@@ -0,0 +1,59 @@
+// Licensed under the MIT license which can be found in the LICENSE file.
+
+package cemi
+
+import (
+	"testing"
+)
+
+func TestControlField2(t *testing.T) {
+	for _, test := range []struct {
+		ctrl2  ControlField2
+		typ    AddressType
+		hops   uint8
+		format uint8
+		lte    bool
+	}{
+		{0xE0, AddressTypeGroup, 6, 0, false},
+		{0x60, AddressTypeIndividual, 6, 0, false},
+		{0xF0, AddressTypeGroup, 7, 0, false},
+		{0x00, AddressTypeIndividual, 0, 0, false},
+		{0xE4, AddressTypeGroup, 6, 4, true},
+		{0xD7, AddressTypeGroup, 5, 7, true},
+		{0x6F, AddressTypeIndividual, 6, 0xF, false},
+	} {
+		if typ := test.ctrl2.AddressType(); typ != test.typ {
+			t.Errorf("%#02x: address type is %v, expected %v", uint8(test.ctrl2), typ, test.typ)
+		}
+
+		if hops := test.ctrl2.HopCount(); hops != test.hops {
+			t.Errorf("%#02x: hop count is %d, expected %d", uint8(test.ctrl2), hops, test.hops)
+		}
+
+		if format := test.ctrl2.ExtendedFrameFormat(); format != test.format {
+			t.Errorf("%#02x: extended frame format is %d, expected %d", uint8(test.ctrl2), format, test.format)
+		}
+
+		if lte := test.ctrl2.IsLTEFrame(); lte != test.lte {
+			t.Errorf("%#02x: LTE frame is %v, expected %v", uint8(test.ctrl2), lte, test.lte)
+		}
+
+		// Building the control field from its parts yields the same value.
+		built := ControlField2(0).
+			WithAddressType(test.typ).
+			WithHopCount(test.hops).
+			WithExtendedFrameFormat(test.format)
+		if built != test.ctrl2 {
+			t.Errorf("Built %#02x, expected %#02x", uint8(built), uint8(test.ctrl2))
+		}
+	}
+
+	ctrl2 := Control2GroupAddr | Control2Hops(6) | Control2LTEFrame
+	if ctrl2 = ctrl2.WithHopCount(9); ctrl2.HopCount() != 7 || !ctrl2.IsGroupAddr() || !ctrl2.IsLTEFrame() {
+		t.Errorf("Setting the hop count changed other fields: %#02x", uint8(ctrl2))
+	}
+
+	if ctrl2 = ctrl2.WithAddressType(AddressTypeIndividual); ctrl2 != 0x74 {
+		t.Errorf("Unexpected control field %#02x", uint8(ctrl2))
+	}
+}
@@ -0,0 +1,64 @@
+// Licensed under the MIT license which can be found in the LICENSE file.
+
+package cemi
+
+import "fmt"
+
+// DecodedTelegram is the application view of a link-layer data frame. It separates the information
+// relevant to applications from the encoding of the frame.
+type DecodedTelegram struct {
+	Source      IndividualAddr
+	Destination uint16 // Either a GroupAddr or an IndividualAddr, depending on IsGroup
+	IsGroup     bool
+	Priority    Priority
+	APCI        APCI
+	Payload     []byte // Includes the lower 6 bits of the APCI octet, see AppData
+	Numbered    bool
+	SeqNumber   uint8
+}
+
+// GroupDestination returns the destination as group address. It is only meaningful if IsGroup is
+// set.
+func (tg DecodedTelegram) GroupDestination() GroupAddr {
+	return GroupAddr(tg.Destination)
+}
+
+// IndividualDestination returns the destination as individual address. It is only meaningful if
+// IsGroup is not set.
+func (tg DecodedTelegram) IndividualDestination() IndividualAddr {
+	return IndividualAddr(tg.Destination)
+}
+
+// Decode extracts the application data of a L_Data.req, L_Data.con or L_Data.ind. Other messages
+// and frames which only carry transport layer control information, like T_ACK, cannot be decoded.
+// The payload refers to the data of the message.
+func Decode(msg Message) (DecodedTelegram, error) {
+	var ldata *LData
+
+	switch msg := msg.(type) {
+	case *LDataReq:
+		ldata = &msg.LData
+	case *LDataCon:
+		ldata = &msg.LData
+	case *LDataInd:
+		ldata = &msg.LData
+	default:
+		return DecodedTelegram{}, fmt.Errorf("message %T is not a link-layer data frame", msg)
+	}
+
+	app, ok := ldata.Data.(*AppData)
+	if !ok {
+		return DecodedTelegram{}, fmt.Errorf("transport unit %T carries no application data", ldata.Data)
+	}
+
+	return DecodedTelegram{
+		Source:      ldata.Source,
+		Destination: ldata.Destination,
+		IsGroup:     ldata.Control2.IsGroupAddr(),
+		Priority:    ldata.Control1.Priority(),
+		APCI:        app.Command,
+		Payload:     app.Data,
+		Numbered:    app.Numbered,
+		SeqNumber:   app.SeqNumber,
+	}, nil
+}
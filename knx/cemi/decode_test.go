@@ -0,0 +1,82 @@
+// Licensed under the MIT license which can be found in the LICENSE file.
+
+package cemi
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	// A group write with urgent priority.
+	t.Run("Group", func(t *testing.T) {
+		msg := &LDataInd{LData: LData{
+			Control1:    Control1StdFrame | Control1Prio(PrioUrgent),
+			Control2:    Control2GroupAddr | Control2Hops(6),
+			Source:      NewIndividualAddr3(1, 1, 5),
+			Destination: uint16(NewGroupAddr3(1, 2, 3)),
+			Data:        &AppData{Command: GroupValueWrite, Data: []byte{0x01}},
+		}}
+
+		tg, err := Decode(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !tg.IsGroup || tg.GroupDestination() != NewGroupAddr3(1, 2, 3) {
+			t.Errorf("Unexpected destination %v, group %v", tg.Destination, tg.IsGroup)
+		}
+
+		if tg.Source != NewIndividualAddr3(1, 1, 5) || tg.Priority != PrioUrgent || tg.APCI != GroupValueWrite {
+			t.Errorf("Unexpected telegram %+v", tg)
+		}
+
+		if !bytes.Equal(tg.Payload, []byte{0x01}) || tg.Numbered {
+			t.Errorf("Unexpected payload %x, numbered %v", tg.Payload, tg.Numbered)
+		}
+	})
+
+	// A connection-oriented memory read with low priority.
+	t.Run("Individual", func(t *testing.T) {
+		msg := &LDataReq{LData: LData{
+			Control1:    Control1StdFrame | Control1Prio(PrioLow),
+			Control2:    Control2Hops(6),
+			Source:      NewIndividualAddr3(0, 0, 1),
+			Destination: uint16(NewIndividualAddr3(1, 1, 5)),
+			Data: &AppData{
+				Numbered:  true,
+				SeqNumber: 7,
+				Command:   MemoryRead,
+				Data:      []byte{0x04, 0x40, 0x00},
+			},
+		}}
+
+		tg, err := Decode(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if tg.IsGroup || tg.IndividualDestination() != NewIndividualAddr3(1, 1, 5) {
+			t.Errorf("Unexpected destination %v, group %v", tg.Destination, tg.IsGroup)
+		}
+
+		if tg.Priority != PrioLow || tg.APCI != MemoryRead || !tg.Numbered || tg.SeqNumber != 7 {
+			t.Errorf("Unexpected telegram %+v", tg)
+		}
+
+		if !bytes.Equal(tg.Payload, []byte{0x04, 0x40, 0x00}) {
+			t.Errorf("Unexpected payload %x", tg.Payload)
+		}
+	})
+
+	// Transport layer control telegrams carry no application data.
+	t.Run("Control", func(t *testing.T) {
+		if _, err := Decode(&LDataInd{LData: LData{Data: TAck(3)}}); err == nil {
+			t.Error("Decoding T_ACK should not succeed")
+		}
+
+		if _, err := Decode(&UnsupportedMessage{}); err == nil {
+			t.Error("Decoding an unsupported message should not succeed")
+		}
+	})
+}
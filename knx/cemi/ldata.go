@@ -5,6 +5,13 @@ package cemi
 
 import "github.com/LB-00/knx-go/knx/util"
 
+// Maximum values of the length field of a transport unit, that is the APDU length, for standard and
+// extended frames.
+const (
+	MaxStdFrameLength = 15
+	MaxExtFrameLength = 254
+)
+
 // A LData is a link-layer data frame. L_Data.req, L_Data.con and L_Data.ind share this structure.
 type LData struct {
 	Info        Info
@@ -52,6 +59,17 @@ func (ldata *LData) Pack(buffer []byte) {
 	)
 }
 
+// SetFrameFormat selects the standard frame format if the transport unit fits into a standard
+// frame, otherwise the extended frame format.
+func (ldata *LData) SetFrameFormat() {
+	// The packed transport unit includes the length and TPCI octets.
+	if ldata.Data == nil || ldata.Data.Size()-2 <= MaxStdFrameLength {
+		ldata.Control1 |= Control1StdFrame
+	} else {
+		ldata.Control1 &^= Control1StdFrame
+	}
+}
+
 // A LDataReq represents a L_Data.req message body.
 type LDataReq struct {
 	LData
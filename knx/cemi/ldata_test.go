@@ -69,3 +69,72 @@ func TestLData_Unpack(t *testing.T) {
 		}
 	}
 }
+
+func TestLData_ExtendedFrame(t *testing.T) {
+	payload := makeRandBuffer(50)
+
+	ldata := LData{
+		Control1:    Control1NoRepeat | Control1NoSysBroadcast,
+		Control2:    Control2Hops(6),
+		Source:      NewIndividualAddr3(1, 1, 5),
+		Destination: uint16(NewIndividualAddr3(1, 1, 1)),
+		Data:        &AppData{Command: PropertyValueResponse, Data: payload},
+	}
+
+	ldata.SetFrameFormat()
+
+	if !ldata.Control1.IsExtendedFrame() {
+		t.Fatal("Expected extended frame format")
+	}
+
+	buffer := make([]byte, ldata.Size())
+	ldata.Pack(buffer)
+
+	// The length field holds the APDU length which exceeds the limit of standard frames.
+	tpdu := buffer[ldata.Info.Size()+6:]
+	if tpdu[0] != 51 {
+		t.Fatalf("Unexpected length field: %d", tpdu[0])
+	}
+
+	var unit TransportUnit
+	num, err := unpackTransportUnit(tpdu, &unit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if num != uint(len(tpdu)) {
+		t.Errorf("Unexpected length: %d != %d", num, len(tpdu))
+	}
+
+	app, ok := unit.(*AppData)
+	if !ok {
+		t.Fatalf("Unexpected result type: %T", unit)
+	}
+
+	if app.Command != PropertyValueResponse || !bytes.Equal(app.Data, payload) {
+		t.Errorf("Unexpected application data: %v", app)
+	}
+
+	// The complete frame survives the round trip as well.
+	var result LData
+	if _, err := result.Unpack(buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	if !result.Control1.IsExtendedFrame() {
+		t.Error("Expected extended frame format")
+	}
+
+	// Truncated frames must be rejected.
+	if _, err := unpackTransportUnit(tpdu[:len(tpdu)-1], &unit); err == nil {
+		t.Error("Should not succeed")
+	}
+
+	// Short payloads fit into a standard frame.
+	ldata.Data = &AppData{Command: PropertyValueResponse, Data: payload[:10]}
+	ldata.SetFrameFormat()
+
+	if ldata.Control1.IsExtendedFrame() {
+		t.Error("Expected standard frame format")
+	}
+}
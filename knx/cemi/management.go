@@ -121,3 +121,25 @@ func TNak(seqNumber uint8) *ControlNak {
 		},
 	}
 }
+
+// NewBroadcastReq creates a new L_Data.req message which is broadcast to all devices, as used by
+// commissioning services like A_IndividualAddress_Write and A_IndividualAddress_Read. Broadcasts
+// are addressed to group address 0/0/0 and sent with system priority. Like AppData.Data, the first
+// octet of data shares its lower 6 bits with the APCI.
+func NewBroadcastReq(src IndividualAddr, cmd APCI, data []byte) *LDataReq {
+	ldata := LData{
+		Control1: Control1StdFrame | Control1NoRepeat | Control1NoSysBroadcast |
+			Control1Prio(PrioSystem),
+		Control2:    Control2GroupAddr | Control2Hops(6),
+		Source:      src,
+		Destination: 0,
+		Data: &AppData{
+			Command: cmd,
+			Data:    data,
+		},
+	}
+
+	return &LDataReq{
+		LData: ldata,
+	}
+}
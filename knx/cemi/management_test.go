@@ -0,0 +1,40 @@
+// Licensed under the MIT license which can be found in the LICENSE file.
+
+package cemi
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNewBroadcastReq(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		req      *LDataReq
+		expected []byte
+	}{
+		{
+			"IndividualAddrRequest",
+			NewBroadcastReq(0, IndividualAddrRequest, nil),
+			[]byte{0x11, 0x00, 0xB0, 0xE0, 0x00, 0x00, 0x00, 0x00, 0x01, 0x01, 0x00},
+		},
+		{
+			"IndividualAddrWrite",
+			NewBroadcastReq(0, IndividualAddrWrite, []byte{0x00, 0x11, 0x05}),
+			[]byte{0x11, 0x00, 0xB0, 0xE0, 0x00, 0x00, 0x00, 0x00, 0x03, 0x00, 0xC0, 0x11, 0x05},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			buffer := make([]byte, 1+test.req.Size())
+			Pack(buffer, test.req)
+
+			if !bytes.Equal(buffer, test.expected) {
+				t.Errorf("Packed % x, expected % x", buffer, test.expected)
+			}
+
+			if !test.req.Control2.IsGroupAddr() || test.req.Destination != 0 {
+				t.Error("Broadcasts must be sent to group address 0/0/0")
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+// Licensed under the MIT license which can be found in the LICENSE file.
+
+package cemi
+
+import "github.com/LB-00/knx-go/knx/util"
+
+// MPropHeader addresses elements of a property of an interface object in the device management
+// messages of the cEMI server, i.e. the KNXnet/IP interface itself.
+type MPropHeader struct {
+	ObjectType     uint16
+	ObjectInstance uint8
+	PropertyID     uint8
+	Count          uint8  // Number of elements (4 bits)
+	StartIndex     uint16 // Index of the first element (12 bits)
+}
+
+// Size returns the packed size.
+func (MPropHeader) Size() uint {
+	return 6
+}
+
+// Pack the header into the buffer.
+func (header *MPropHeader) Pack(buffer []byte) {
+	util.PackSome(
+		buffer,
+		header.ObjectType,
+		header.ObjectInstance,
+		header.PropertyID,
+		uint16(header.Count&0xf)<<12|header.StartIndex&0xfff,
+	)
+}
+
+// Unpack initializes the structure by parsing the given data.
+func (header *MPropHeader) Unpack(data []byte) (n uint, err error) {
+	var countIndex uint16
+
+	if n, err = util.UnpackSome(
+		data, &header.ObjectType, &header.ObjectInstance, &header.PropertyID, &countIndex,
+	); err != nil {
+		return
+	}
+
+	header.Count = uint8(countIndex >> 12)
+	header.StartIndex = countIndex & 0xfff
+
+	return
+}
+
+// MProp is the body of the M_PropRead, M_PropWrite and M_PropInfo messages. M_PropRead.req
+// carries no data. A confirmation with a count of zero carries an error code as data.
+type MProp struct {
+	MPropHeader
+	Data []byte
+}
+
+// Size returns the packed size.
+func (prop *MProp) Size() uint {
+	return prop.MPropHeader.Size() + uint(len(prop.Data))
+}
+
+// Pack the message body into the buffer.
+func (prop *MProp) Pack(buffer []byte) {
+	prop.MPropHeader.Pack(buffer)
+	copy(buffer[prop.MPropHeader.Size():], prop.Data)
+}
+
+// Unpack initializes the structure by parsing the given data.
+func (prop *MProp) Unpack(data []byte) (n uint, err error) {
+	if n, err = prop.MPropHeader.Unpack(data); err != nil {
+		return
+	}
+
+	prop.Data = make([]byte, len(data)-int(n))
+	n += uint(copy(prop.Data, data[n:]))
+
+	return
+}
+
+// A MPropReadReq represents a M_PropRead.req message body.
+type MPropReadReq struct {
+	MProp
+}
+
+// MessageCode returns the message code for M_PropRead.req.
+func (MPropReadReq) MessageCode() MessageCode {
+	return MPropReadReqCode
+}
+
+// A MPropReadCon represents a M_PropRead.con message body.
+type MPropReadCon struct {
+	MProp
+}
+
+// MessageCode returns the message code for M_PropRead.con.
+func (MPropReadCon) MessageCode() MessageCode {
+	return MPropReadConCode
+}
+
+// A MPropWriteReq represents a M_PropWrite.req message body.
+type MPropWriteReq struct {
+	MProp
+}
+
+// MessageCode returns the message code for M_PropWrite.req.
+func (MPropWriteReq) MessageCode() MessageCode {
+	return MPropWriteReqCode
+}
+
+// A MPropWriteCon represents a M_PropWrite.con message body.
+type MPropWriteCon struct {
+	MProp
+}
+
+// MessageCode returns the message code for M_PropWrite.con.
+func (MPropWriteCon) MessageCode() MessageCode {
+	return MPropWriteConCode
+}
+
+// A MPropInfoInd represents a M_PropInfo.ind message body.
+type MPropInfoInd struct {
+	MProp
+}
+
+// MessageCode returns the message code for M_PropInfo.ind.
+func (MPropInfoInd) MessageCode() MessageCode {
+	return MPropInfoIndCode
+}
+
+// A MResetReq represents a M_Reset.req message, which restarts the cEMI server. It has no body.
+type MResetReq struct{}
+
+// MessageCode returns the message code for M_Reset.req.
+func (MResetReq) MessageCode() MessageCode {
+	return MResetReqCode
+}
+
+// Size returns the packed size.
+func (MResetReq) Size() uint {
+	return 0
+}
+
+// Pack the message body into the buffer.
+func (MResetReq) Pack(buffer []byte) {}
+
+// Unpack initializes the structure by parsing the given data.
+func (*MResetReq) Unpack(data []byte) (uint, error) {
+	return 0, nil
+}
+
+// A MResetInd represents a M_Reset.ind message, which the cEMI server sends after it has been
+// restarted. It has no body.
+type MResetInd struct{}
+
+// MessageCode returns the message code for M_Reset.ind.
+func (MResetInd) MessageCode() MessageCode {
+	return MResetIndCode
+}
+
+// Size returns the packed size.
+func (MResetInd) Size() uint {
+	return 0
+}
+
+// Pack the message body into the buffer.
+func (MResetInd) Pack(buffer []byte) {}
+
+// Unpack initializes the structure by parsing the given data.
+func (*MResetInd) Unpack(data []byte) (uint, error) {
+	return 0, nil
+}
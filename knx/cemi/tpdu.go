@@ -21,10 +21,15 @@ const (
 )
 
 const (
+	PrefixExtended    uint8 = 0b0111 // 7
 	PrefixUserMessage uint8 = 0b1011 // 11
 	PrefixEscape      uint8 = 0b1111 // 15
 )
 
+// minExtendedCommand is the lowest command in the extended range which shares its prefix with
+// A_ADC_Response. Lower values are A_ADC_Response telegrams with a channel number.
+const minExtendedCommand = 0b001000
+
 // APCI is the Application-layer Protocol Control Information.
 type APCI uint16
 
@@ -131,6 +136,67 @@ func (apci APCI) IsStandardCommand() bool {
 	return apci != UserMemoryRead && (apci&0x3F) == 0 && (apci>>6) < 15
 }
 
+// expectedResponses maps the commands which a device answers to the commands of their responses.
+var expectedResponses = map[APCI]APCI{
+	GroupValueRead:                    GroupValueResponse,
+	IndividualAddrRequest:             IndividualAddrResponse,
+	AdcRead:                           AdcResponse,
+	MemoryRead:                        MemoryResponse,
+	MaskVersionRead:                   MaskVersionResponse,
+	SystemNetworkParameterRead:        SystemNetworkParameterResponse,
+	PropertyExtValueRead:              PropertyExtValueResponse,
+	PropertyExtValueWriteCon:          PropertyExtValueWriteConRes,
+	PropertyExtDescriptionRead:        PropertyExtDescriptionResponse,
+	FunctionPropertyExtCommand:        FunctionPropertyExtStateResponse,
+	FunctionPropertyExtStateRead:      FunctionPropertyExtStateResponse,
+	MemoryExtendedWrite:               MemoryExtendedWriteResponse,
+	MemoryExtendedRead:                MemoryExtendedReadResponse,
+	UserMemoryRead:                    UserMemoryResponse,
+	UserManufacturerInfoRead:          UserManufacturerInfoResponse,
+	FunctionPropertyCommand:           FunctionPropertyStateResponse,
+	FunctionPropertyStateRead:         FunctionPropertyStateResponse,
+	FilterTableRead:                   FilterTableResponse,
+	RouterMemoryRead:                  RouterMemoryResponse,
+	RouterStatusRead:                  RouterStatusResponse,
+	AuthorizeRequest:                  AuthorizeResponse,
+	KeyWrite:                          KeyResponse,
+	PropertyValueRead:                 PropertyValueResponse,
+	PropertyValueWrite:                PropertyValueResponse,
+	PropertyDescriptionRead:           PropertyDescriptionResponse,
+	NetworkParameterRead:              NetworkParameterResponse,
+	IndividualAddressSerialNumberRead: IndividualAddressSerialNumberResponse,
+	DomainAddressRead:                 DomainAddressResponse,
+	DomainAddressSelectiveRead:        DomainAddressResponse,
+	LinkRead:                          LinkResponse,
+	GroupPropValueRead:                GroupPropValueResponse,
+	DomainAddressSerialNumberRead:     DomainAddressSerialNumberResponse,
+}
+
+// ExpectedResponse returns the command of the response which a device sends for the given request
+// command. It returns false for commands which are not answered, like A_Memory_Write. A_Restart is
+// only answered for a master reset and therefore not considered to have a response.
+func ExpectedResponse(cmd APCI) (APCI, bool) {
+	res, ok := expectedResponses[cmd]
+	return res, ok
+}
+
+// RequiresConnection determines if the command may only be sent over a transport layer connection
+// (T_Data_Connected). This applies to memory access, ADC reads, authorization and key management.
+// Other commands are sent connectionless, either to groups, as broadcast or as T_Data_Individual,
+// although some of the latter, like A_PropertyValue_Read, may be sent over a connection as well.
+func RequiresConnection(cmd APCI) bool {
+	switch cmd {
+	case AdcRead, AdcResponse,
+		MemoryRead, MemoryResponse, MemoryWrite, MemoryBitWrite,
+		UserMemoryRead, UserMemoryResponse, UserMemoryWrite, UserMemoryBitWrite,
+		UserManufacturerInfoRead, UserManufacturerInfoResponse,
+		AuthorizeRequest, AuthorizeResponse, KeyWrite, KeyResponse:
+		return true
+	}
+
+	return false
+}
+
 // An AppData contains application data in a transport unit.
 type AppData struct {
 	Numbered  bool
@@ -139,6 +205,16 @@ type AppData struct {
 	Data      []byte
 }
 
+// maxDataLength returns how many bytes of data fit into a transport unit.
+func (app *AppData) maxDataLength() int {
+	if app.Command.IsStandardCommand() {
+		return MaxExtFrameLength
+	}
+
+	// Non-standard commands occupy an additional octet.
+	return MaxExtFrameLength - 1
+}
+
 // Size retrieves the packed size.
 func (app *AppData) Size() uint {
 	cmdLength := uint(2)
@@ -147,24 +223,28 @@ func (app *AppData) Size() uint {
 		cmdLength += 1
 	}
 
-	dataLength := uint(len(app.Data))
+	dataLength := len(app.Data)
 
-	if dataLength > 255 {
-		dataLength = 255
-	} else if dataLength < 1 {
+	if limit := app.maxDataLength(); dataLength > limit {
+		dataLength = limit
+	} else if dataLength < 1 && app.Command.IsStandardCommand() {
+		// Standard commands share the first data octet with the APCI. Commands without data, like
+		// A_GroupValue_Read, still need this octet for the lower bits of the APCI, so it is not an
+		// additional data byte.
 		dataLength = 1
 	}
 
-	return cmdLength + dataLength
+	return cmdLength + uint(dataLength)
 }
 
 // Pack into a transport data unit including its leading length byte.
 func (app *AppData) Pack(buffer []byte) {
 	dataLength := len(app.Data)
 
-	if dataLength > 255 {
-		dataLength = 255
-	} else if dataLength < 1 {
+	if limit := app.maxDataLength(); dataLength > limit {
+		dataLength = limit
+	} else if dataLength < 1 && app.Command.IsStandardCommand() {
+		// Standard commands share the first data octet with the APCI.
 		dataLength = 1
 	}
 
@@ -172,7 +252,9 @@ func (app *AppData) Pack(buffer []byte) {
 		dataLength += 1
 	}
 
+	// The buffer may be reused, hence the octets which are only partially set are cleared.
 	buffer[0] = byte(dataLength)
+	buffer[1] = 0
 
 	if app.Numbered {
 		buffer[1] |= 1<<6 | (app.SeqNumber&15)<<2
@@ -183,7 +265,8 @@ func (app *AppData) Pack(buffer []byte) {
 	buffer[1] |= byte(app.Command>>8) & 3
 
 	if app.Command.IsStandardCommand() {
-		copy(buffer[2:], app.Data)
+		buffer[2] = 0
+		copy(buffer[2:2+dataLength], app.Data)
 
 		// Zero out the first two bits of buffer[2] and set them
 		// to the remaining two bits of the 4 bit APCI.
@@ -194,7 +277,7 @@ func (app *AppData) Pack(buffer []byte) {
 		// byte to encode the command.
 		buffer[2] = byte(app.Command & 0xFF)
 
-		copy(buffer[3:], app.Data)
+		copy(buffer[3:2+dataLength], app.Data)
 	}
 }
 
@@ -259,18 +342,23 @@ func unpackTransportUnit(data []byte, unit *TransportUnit) (uint, error) {
 
 	dataLength := int(data[0])
 
-	if len(data) < 3 || dataLength+2 < len(data) {
+	if len(data) < 3 || dataLength+2 != len(data) {
 		return 0, io.ErrUnexpectedEOF
 	}
 
 	app := &AppData{
-		Numbered:  (data[1] & (1 << 6)) == 1<<6,
-		SeqNumber: (data[1] >> 2) & 15,
+		Numbered: (data[1] & (1 << 6)) == 1<<6,
+	}
+
+	// The sequence number is only packed for numbered telegrams.
+	if app.Numbered {
+		app.SeqNumber = (data[1] >> 2) & 15
 	}
 
 	p := (data[1]&3)<<2 | data[2]>>6
 
-	if p == PrefixUserMessage || p == PrefixEscape {
+	if p == PrefixUserMessage || p == PrefixEscape ||
+		(p == PrefixExtended && data[2]&63 >= minExtendedCommand) {
 		app.Command = APCI(uint16(p)<<6 | uint16(data[2]))
 
 		app.Data = make([]byte, dataLength-1)
@@ -11,13 +11,26 @@ import (
 	"github.com/LB-00/knx-go/knx/util"
 )
 
+// randStandardCommands are the standard commands which carry data in the lower six bits of the
+// APCI. A_ADC_Response is left out, as it shares its prefix with the extended commands.
+var randStandardCommands = []APCI{
+	GroupValueRead, GroupValueResponse, GroupValueWrite, IndividualAddrWrite, IndividualAddrRequest,
+	IndividualAddrResponse, AdcRead, MemoryRead, MemoryResponse, MemoryWrite, MaskVersionRead,
+	MaskVersionResponse, Restart,
+}
+
+// makeRandStandardCommand picks one of randStandardCommands.
+func makeRandStandardCommand() APCI {
+	return randStandardCommands[rand.Int()%len(randStandardCommands)]
+}
+
 func TestAppData_Pack(t *testing.T) {
 	for i := 0; i < 100; i++ {
 		app := AppData{
 			Numbered:  rand.Int()%2 == 0,
 			SeqNumber: uint8(rand.Int()) % 15,
-			Command:   APCI(rand.Int()) % 15,
-			Data:      makeRandBuffer(rand.Int() % 300),
+			Command:   makeRandStandardCommand(),
+			Data:      makeRandBuffer(rand.Int() % (MaxExtFrameLength + 1)),
 		}
 
 		if len(app.Data) > 0 {
@@ -32,9 +45,6 @@ func TestAppData_Pack(t *testing.T) {
 		}
 
 		dataLength := len(app.Data)
-		if dataLength > 255 {
-			dataLength = 255
-		}
 
 		if len(app.Data) > 0 && int(data[0]) != dataLength {
 			t.Error("Unexpected unit length:", data[0], app)
@@ -52,7 +62,7 @@ func TestAppData_Pack(t *testing.T) {
 			t.Error("Unexpected sequence number", (data[1]>>2)&15, app.SeqNumber)
 		}
 
-		apci := APCI((data[1]&3)<<2 | data[2]>>6)
+		apci := APCI((data[1]&3)<<2|data[2]>>6) << 6
 		if apci != app.Command {
 			t.Error("Unexpected command:", apci, app.Command)
 		}
@@ -67,6 +77,90 @@ func TestAppData_Pack(t *testing.T) {
 	}
 }
 
+func TestAppData_PackNoData(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		app      AppData
+		expected []byte
+	}{
+		// The length counts the octets following the TPCI octet, i.e. only the second APCI octet.
+		{"GroupValueRead", AppData{Command: GroupValueRead}, []byte{0x01, 0x00, 0x00}},
+		{"GroupValueReadZero", AppData{Command: GroupValueRead, Data: []byte{0}}, []byte{0x01, 0x00, 0x00}},
+		{"IndividualAddrRequest", AppData{Command: IndividualAddrRequest}, []byte{0x01, 0x01, 0x00}},
+		{"MaskVersionRead", AppData{Command: MaskVersionRead, Data: []byte{0}}, []byte{0x01, 0x03, 0x00}},
+		{"Numbered", AppData{Numbered: true, SeqNumber: 3, Command: GroupValueRead}, []byte{0x01, 0x4C, 0x00}},
+		{
+			"MemoryRead",
+			AppData{Command: MemoryRead, Data: []byte{0x04, 0x01, 0x00}},
+			[]byte{0x03, 0x02, 0x04, 0x01, 0x00},
+		},
+		{"Extended", AppData{Command: PropertyExtValueRead}, []byte{0x01, 0x01, 0xCC}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if size := test.app.Size(); size != uint(len(test.expected)) {
+				t.Errorf("Size is %d, expected %d", size, len(test.expected))
+			}
+
+			// Packing into a dirty buffer must not leak its contents.
+			buffer := bytes.Repeat([]byte{0xFF}, len(test.expected))
+			test.app.Pack(buffer)
+
+			if !bytes.Equal(buffer, test.expected) {
+				t.Errorf("Packed % x, expected % x", buffer, test.expected)
+			}
+		})
+	}
+}
+
+func TestAppData_RoundTrip(t *testing.T) {
+	// Standard commands carry their first data octet, even if it is zero, in the APCI octet.
+	apps := []AppData{
+		{Command: GroupValueWrite, Data: []byte{1}},
+		{Command: GroupValueWrite, Data: []byte{0, 0x0C, 0x1A}},
+		{Command: MaskVersionRead, Data: []byte{0}},
+		{Command: MemoryRead, Data: []byte{12, 0x01, 0x00}},
+		{Command: MemoryResponse, Data: []byte{2, 0x01, 0x00, 0xAB, 0xCD}},
+		{Command: AdcResponse, Data: []byte{3, 8, 0x12, 0x34}},
+		{Command: PropertyValueRead, Data: []byte{0, 11, 0x10, 0x01}},
+		{Command: PropertyExtValueRead, Data: []byte{0x00, 0x0B, 0x01, 0x0B, 0x10, 0x00, 0x01}},
+		{Command: UserMemoryRead, Data: []byte{0x01, 0x00, 0x00}},
+		{Command: UserManufacturerInfoRead},
+	}
+
+	for _, app := range apps {
+		for _, numbered := range []bool{false, true} {
+			for seq := uint8(0); seq < 16; seq++ {
+				if !numbered && seq > 0 {
+					break
+				}
+
+				app.Numbered, app.SeqNumber = numbered, seq
+				data := util.AllocAndPack(&app)
+
+				var unit TransportUnit
+				n, err := unpackTransportUnit(data, &unit)
+				if err != nil {
+					t.Fatalf("Unpacking %v failed: %v", app.Command, err)
+				}
+
+				if n != uint(len(data)) {
+					t.Errorf("Unpacked %d of %d bytes", n, len(data))
+				}
+
+				unpacked, ok := unit.(*AppData)
+				if !ok {
+					t.Fatalf("Unexpected unit %T", unit)
+				}
+
+				if unpacked.Numbered != app.Numbered || unpacked.SeqNumber != app.SeqNumber ||
+					unpacked.Command != app.Command || !bytes.Equal(unpacked.Data, app.Data) {
+					t.Errorf("Round trip of %+v yielded %+v", app, *unpacked)
+				}
+			}
+		}
+	}
+}
+
 func TestControlData_Pack(t *testing.T) {
 	for i := 0; i < 100; i++ {
 		control := ControlData{
@@ -107,8 +201,13 @@ func TestControlData_Pack(t *testing.T) {
 func TestUnpackTransportUnit(t *testing.T) {
 	t.Run("Control", func(t *testing.T) {
 		for i := 0; i < 100; i++ {
-			data := []byte{0, byte(rand.Int())}
-			data[1] |= 1 << 7
+			command := uint8(rand.Int()) % 4
+			data := []byte{0, 1<<7 | command}
+
+			// T_ACK and T_NAK are numbered, T_CONNECT and T_DISCONNECT are not.
+			if command == uint8(Ack) || command == uint8(Nak) {
+				data[1] |= 1<<6 | (uint8(rand.Int())%16)<<2
+			}
 
 			var unit TransportUnit
 			num, err := unpackTransportUnit(data, &unit)
@@ -123,8 +222,17 @@ func TestUnpackTransportUnit(t *testing.T) {
 				continue
 			}
 
-			control, ok := unit.(*ControlData)
-			if !ok {
+			var control *ControlData
+			switch unit := unit.(type) {
+			case *ControlConn:
+				control = &unit.ControlData
+			case *ControlDisc:
+				control = &unit.ControlData
+			case *ControlAck:
+				control = &unit.ControlData
+			case *ControlNak:
+				control = &unit.ControlData
+			default:
 				t.Errorf("Unexpected result type: %T %v", unit, data)
 				continue
 			}
@@ -145,11 +253,16 @@ func TestUnpackTransportUnit(t *testing.T) {
 
 	t.Run("App", func(t *testing.T) {
 		for i := 0; i < 100; i++ {
-			data := make([]byte, 3+rand.Int()%255)
+			data := make([]byte, 3+rand.Int()%MaxExtFrameLength)
 			rand.Read(data[1:])
 
+			// Set the upper four bits of the APCI to a standard command.
+			cmd := makeRandStandardCommand()
+
 			data[0] = byte(len(data) - 2)
 			data[1] &= ^(byte(1) << 7)
+			data[1] = data[1]&^3 | byte(cmd>>8)&3
+			data[2] = data[2]&63 | byte(cmd>>6)<<6
 
 			var unit TransportUnit
 			num, err := unpackTransportUnit(data, &unit)
@@ -178,9 +291,8 @@ func TestUnpackTransportUnit(t *testing.T) {
 				t.Error("Unexpected sequence number:", app.SeqNumber, (data[1]>>2)&15)
 			}
 
-			apci := APCI((data[1]&3)<<2 | data[2]>>6)
-			if app.Command != apci {
-				t.Error("Unexpected command:", app.Command, apci)
+			if app.Command != cmd {
+				t.Error("Unexpected command:", app.Command, cmd)
 			}
 
 			if len(app.Data) > 0 && data[2]&63 != app.Data[0]&63 {
@@ -193,3 +305,94 @@ func TestUnpackTransportUnit(t *testing.T) {
 		}
 	})
 }
+
+func TestExpectedResponse(t *testing.T) {
+	responses := []struct {
+		req, res APCI
+	}{
+		{GroupValueRead, GroupValueResponse},
+		{IndividualAddrRequest, IndividualAddrResponse},
+		{AdcRead, AdcResponse},
+		{MemoryRead, MemoryResponse},
+		{MaskVersionRead, MaskVersionResponse},
+		{SystemNetworkParameterRead, SystemNetworkParameterResponse},
+		{PropertyExtValueRead, PropertyExtValueResponse},
+		{PropertyExtValueWriteCon, PropertyExtValueWriteConRes},
+		{PropertyExtDescriptionRead, PropertyExtDescriptionResponse},
+		{FunctionPropertyExtCommand, FunctionPropertyExtStateResponse},
+		{FunctionPropertyExtStateRead, FunctionPropertyExtStateResponse},
+		{MemoryExtendedWrite, MemoryExtendedWriteResponse},
+		{MemoryExtendedRead, MemoryExtendedReadResponse},
+		{UserMemoryRead, UserMemoryResponse},
+		{UserManufacturerInfoRead, UserManufacturerInfoResponse},
+		{FunctionPropertyCommand, FunctionPropertyStateResponse},
+		{FunctionPropertyStateRead, FunctionPropertyStateResponse},
+		{FilterTableRead, FilterTableResponse},
+		{RouterMemoryRead, RouterMemoryResponse},
+		{RouterStatusRead, RouterStatusResponse},
+		{AuthorizeRequest, AuthorizeResponse},
+		{KeyWrite, KeyResponse},
+		{PropertyValueRead, PropertyValueResponse},
+		{PropertyValueWrite, PropertyValueResponse},
+		{PropertyDescriptionRead, PropertyDescriptionResponse},
+		{NetworkParameterRead, NetworkParameterResponse},
+		{IndividualAddressSerialNumberRead, IndividualAddressSerialNumberResponse},
+		{DomainAddressRead, DomainAddressResponse},
+		{DomainAddressSelectiveRead, DomainAddressResponse},
+		{LinkRead, LinkResponse},
+		{GroupPropValueRead, GroupPropValueResponse},
+		{DomainAddressSerialNumberRead, DomainAddressSerialNumberResponse},
+	}
+
+	for _, c := range responses {
+		res, ok := ExpectedResponse(c.req)
+		if !ok || res != c.res {
+			t.Errorf("Unexpected response to %#x: %#x, %v", c.req, res, ok)
+		}
+	}
+
+	if len(expectedResponses) != len(responses) {
+		t.Errorf("Mapping has %d entries, expected %d", len(expectedResponses), len(responses))
+	}
+
+	// Neither unconfirmed requests nor responses are answered.
+	for _, cmd := range []APCI{
+		GroupValueWrite, MemoryWrite, Restart, UserMemoryWrite, MemoryBitWrite,
+		PropertyExtValueWriteUnCon, IndividualAddrWrite, MemoryResponse, PropertyValueResponse,
+	} {
+		if res, ok := ExpectedResponse(cmd); ok {
+			t.Errorf("Unexpected response to %#x: %#x", cmd, res)
+		}
+	}
+}
+
+func TestRequiresConnection(t *testing.T) {
+	testCases := []struct {
+		cmd       APCI
+		connected bool
+	}{
+		{GroupValueRead, false},
+		{GroupValueWrite, false},
+		{IndividualAddrRequest, false},
+		{IndividualAddressSerialNumberRead, false},
+		{MaskVersionRead, false},
+		{PropertyValueRead, false},
+		{PropertyDescriptionRead, false},
+		{Restart, false},
+		{AdcRead, true},
+		{MemoryRead, true},
+		{MemoryWrite, true},
+		{MemoryResponse, true},
+		{MemoryBitWrite, true},
+		{UserMemoryRead, true},
+		{UserManufacturerInfoRead, true},
+		{AuthorizeRequest, true},
+		{KeyWrite, true},
+	}
+
+	for _, testCase := range testCases {
+		if connected := RequiresConnection(testCase.cmd); connected != testCase.connected {
+			t.Errorf("Unexpected result for %#x: %v", testCase.cmd, connected)
+		}
+	}
+}
@@ -4,6 +4,9 @@
 package knx
 
 import (
+	"errors"
+	"fmt"
+	"net"
 	"time"
 
 	"github.com/LB-00/knx-go/knx/knxnet"
@@ -18,6 +21,34 @@ func DescribeTunnel(address string, searchTimeout time.Duration) (*knxnet.Descri
 	}
 	defer socket.Close()
 
+	return describeTunnel(socket, searchTimeout)
+}
+
+// DescribeTunnelConn works like DescribeTunnel, but sends the Description Request over an existing
+// net.PacketConn, e.g. one bound to a specific local port. The connection is closed afterwards.
+func DescribeTunnelConn(
+	conn net.PacketConn,
+	address string,
+	searchTimeout time.Duration,
+) (*knxnet.DescriptionRes, error) {
+	addr, err := net.ResolveUDPAddr("udp4", address)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	socket, err := knxnet.NewTunnelSocketFromPacketConn(conn, addr)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	defer socket.Close()
+
+	return describeTunnel(socket, searchTimeout)
+}
+
+// describeTunnel sends a Description Request over the socket and waits for the response.
+func describeTunnel(socket knxnet.Socket, searchTimeout time.Duration) (*knxnet.DescriptionRes, error) {
 	addr := socket.LocalAddr()
 
 	req, err := knxnet.NewDescriptionReq(addr)
@@ -82,3 +113,61 @@ func DescribeTunnelExt(address string, searchTimeout time.Duration, params ...kn
 		}
 	}
 }
+
+// DescribeDIB requests only the DIB of the given type from a single KNXnet/IP server, e.g. its
+// tunnelling information, and returns it. It sends a Search Request Extended with a Request DIBs
+// parameter over unicast UDP to the given address "ip:port". Servers which respond with further
+// DIBs are tolerated, only the requested one is returned.
+func DescribeDIB(address string, typ knxnet.DescriptionType, timeout time.Duration) (knxnet.DIB, error) {
+	// Uses a UDP socket.
+	socket, err := knxnet.DialTunnelUDP(address)
+	if err != nil {
+		return nil, err
+	}
+	defer socket.Close()
+
+	return describeDIB(socket, socket.LocalAddr(), typ, timeout)
+}
+
+// describeDIB requests the DIB of the given type over the socket, responses are sent to addr.
+func describeDIB(
+	socket knxnet.Socket,
+	addr net.Addr,
+	typ knxnet.DescriptionType,
+	timeout time.Duration,
+) (knxnet.DIB, error) {
+	req, err := knxnet.NewSearchReqExt(addr, knxnet.NewRequestDIBs(false, typ))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := socket.Send(req); err != nil {
+		return nil, err
+	}
+
+	deadline := time.After(timeout)
+
+	for {
+		select {
+		case msg, open := <-socket.Inbound():
+			if !open {
+				return nil, errors.New("socket closed before the server responded")
+			}
+
+			res, ok := msg.(*knxnet.SearchResExt)
+			if !ok {
+				continue
+			}
+
+			dib, ok := res.DIB(typ)
+			if !ok {
+				return nil, fmt.Errorf("server did not provide DIB %v", typ)
+			}
+
+			return dib, nil
+
+		case <-deadline:
+			return nil, fmt.Errorf("server did not respond within %v", timeout)
+		}
+	}
+}
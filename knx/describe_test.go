@@ -0,0 +1,221 @@
+// Licensed under the MIT license which can be found in the LICENSE file.
+
+package knx
+
+import (
+	"net"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/LB-00/knx-go/knx/knxnet"
+)
+
+func TestDescribeDIB(t *testing.T) {
+	client, gateway := newDummySockets()
+	defer client.Close()
+	defer gateway.Close()
+
+	tunnelling := &knxnet.TunnellingInfoDIB{Type: knxnet.DescriptionTypeTunnellingInfo, APDUSize: 254}
+
+	// The server responds with the entire description in addition to the requested DIB.
+	go func() {
+		msg := <-gateway.Inbound()
+
+		req, ok := msg.(*knxnet.SearchReqExt)
+		if !ok {
+			t.Errorf("Unexpected request %T", msg)
+			return
+		}
+
+		if len(req.Parameters) != 1 {
+			t.Errorf("Unexpected parameters %v", req.Parameters)
+			return
+		}
+
+		srp, ok := req.Parameters[0].(*knxnet.RequestDIBs)
+		if !ok || !reflect.DeepEqual(srp.DescTypes, []knxnet.DescriptionType{knxnet.DescriptionTypeTunnellingInfo}) {
+			t.Errorf("Unexpected parameter %#v", req.Parameters[0])
+		}
+
+		gateway.sendAny(&knxnet.SearchResExt{
+			Control: knxnet.HostInfo{Protocol: knxnet.UDP4, Address: knxnet.Address{192, 168, 1, 10}, Port: 3671},
+			DIBs: []knxnet.DIB{
+				&knxnet.DeviceInformationBlock{Type: knxnet.DescriptionTypeDeviceInfo, HardwareAddr: make([]byte, 6)},
+				tunnelling,
+			},
+		})
+	}()
+
+	dib, err := describeDIB(client, client.LocalAddr(), knxnet.DescriptionTypeTunnellingInfo, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(dib, tunnelling) {
+		t.Errorf("Unexpected DIB %#v", dib)
+	}
+}
+
+func TestDescribeDIB_Missing(t *testing.T) {
+	client, gateway := newDummySockets()
+	defer client.Close()
+	defer gateway.Close()
+
+	// The server ignores the requested DIB.
+	go func() {
+		<-gateway.Inbound()
+
+		gateway.sendAny(&knxnet.SearchResExt{
+			Control: knxnet.HostInfo{Protocol: knxnet.UDP4, Address: knxnet.Address{192, 168, 1, 10}, Port: 3671},
+			DIBs: []knxnet.DIB{
+				&knxnet.DeviceInformationBlock{Type: knxnet.DescriptionTypeDeviceInfo, HardwareAddr: make([]byte, 6)},
+			},
+		})
+	}()
+
+	_, err := describeDIB(client, client.LocalAddr(), knxnet.DescriptionTypeTunnellingInfo, time.Second)
+	if err == nil || err.Error() != "server did not provide DIB TunnellingInfo" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+// memDatagram is a datagram in transit between two memPacketConns.
+type memDatagram struct {
+	data []byte
+	from net.Addr
+}
+
+// memPacketConn is an in-memory net.PacketConn. Datagrams written to any address are delivered to
+// its peer.
+type memPacketConn struct {
+	addr    net.Addr
+	in      chan memDatagram
+	peer    *memPacketConn
+	closed  chan struct{}
+	closeMu sync.Once
+
+	mu       sync.Mutex
+	deadline time.Time
+}
+
+// newMemPacketConns creates a pair of connected in-memory packet connections.
+func newMemPacketConns(a, b *net.UDPAddr) (*memPacketConn, *memPacketConn) {
+	connA := &memPacketConn{addr: a, in: make(chan memDatagram, 10), closed: make(chan struct{})}
+	connB := &memPacketConn{addr: b, in: make(chan memDatagram, 10), closed: make(chan struct{})}
+	connA.peer, connB.peer = connB, connA
+
+	return connA, connB
+}
+
+func (conn *memPacketConn) ReadFrom(buffer []byte) (int, net.Addr, error) {
+	conn.mu.Lock()
+	deadline := conn.deadline
+	conn.mu.Unlock()
+
+	var expired <-chan time.Time
+	if !deadline.IsZero() {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	select {
+	case datagram := <-conn.in:
+		return copy(buffer, datagram.data), datagram.from, nil
+	case <-conn.closed:
+		return 0, nil, net.ErrClosed
+	case <-expired:
+		return 0, nil, os.ErrDeadlineExceeded
+	}
+}
+
+func (conn *memPacketConn) WriteTo(buffer []byte, addr net.Addr) (int, error) {
+	return conn.writeFrom(buffer, conn.addr)
+}
+
+// writeFrom delivers the datagram to the peer as if it had been sent from the given address.
+func (conn *memPacketConn) writeFrom(buffer []byte, from net.Addr) (int, error) {
+	select {
+	case conn.peer.in <- memDatagram{append([]byte(nil), buffer...), from}:
+		return len(buffer), nil
+	case <-conn.closed:
+		return 0, net.ErrClosed
+	}
+}
+
+func (conn *memPacketConn) Close() error {
+	conn.closeMu.Do(func() { close(conn.closed) })
+	return nil
+}
+
+func (conn *memPacketConn) LocalAddr() net.Addr                { return conn.addr }
+func (conn *memPacketConn) SetDeadline(t time.Time) error      { return conn.SetReadDeadline(t) }
+func (conn *memPacketConn) SetWriteDeadline(t time.Time) error { return nil }
+
+func (conn *memPacketConn) SetReadDeadline(t time.Time) error {
+	conn.mu.Lock()
+	conn.deadline = t
+	conn.mu.Unlock()
+
+	return nil
+}
+
+func TestDescribeTunnelConn(t *testing.T) {
+	clientAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 1, 20), Port: 50000}
+	serverAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 1, 10), Port: 3671}
+
+	client, server := newMemPacketConns(clientAddr, serverAddr)
+	defer server.Close()
+
+	go func() {
+		buffer := make([]byte, 1024)
+
+		n, from, err := server.ReadFrom(buffer)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		srv, err := knxnet.Receive(buffer[:n])
+		if _, ok := srv.(*knxnet.DescriptionReq); !ok || err != nil {
+			t.Errorf("Unexpected request %T: %v", srv, err)
+			return
+		}
+
+		if from.String() != clientAddr.String() {
+			t.Errorf("Request has been sent from %v", from)
+		}
+
+		res := &knxnet.DescriptionRes{}
+		res.DeviceHardware.Type = knxnet.DescriptionTypeDeviceInfo
+		res.DeviceHardware.HardwareAddr = make([]byte, 6)
+		res.DeviceHardware.FriendlyName = "Gateway"
+		res.SupportedServices.Type = knxnet.DescriptionTypeSupportedServiceFamilies
+
+		// A response from a foreign address is ignored.
+		foreign := &net.UDPAddr{IP: net.IPv4(192, 168, 1, 99), Port: 3671}
+		res.DeviceHardware.FriendlyName = "Foreign"
+		server.writeFrom(knxnet.AllocAndPack(res), foreign)
+
+		res.DeviceHardware.FriendlyName = "Gateway"
+		server.WriteTo(knxnet.AllocAndPack(res), from)
+	}()
+
+	res, err := DescribeTunnelConn(client, serverAddr.String(), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res == nil || res.FriendlyName() != "Gateway" {
+		t.Fatalf("Unexpected description %+v", res)
+	}
+
+	select {
+	case <-client.closed:
+	default:
+		t.Error("Connection should be closed")
+	}
+}
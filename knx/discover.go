@@ -3,12 +3,49 @@
 package knx
 
 import (
+	"errors"
+	"fmt"
 	"net"
+	"os"
 	"time"
 
 	"github.com/LB-00/knx-go/knx/knxnet"
+	"github.com/LB-00/knx-go/knx/util"
 )
 
+// DefaultDiscoveryAddress is the standard multicast address and port of KNXnet/IP discovery.
+const DefaultDiscoveryAddress = "224.0.23.12:3671"
+
+// DialMulticast opens a socket for KNXnet/IP multicast on the given interface. On hosts with
+// several network interfaces, or in containers, the system-assigned multicast interface may not
+// face the KNX network, in which case discovery silently finds nothing. If the interface is nil,
+// the system-assigned multicast interface is used. Otherwise the interface must be up and support
+// multicast.
+func DialMulticast(ifi *net.Interface, multicastAddress string) (*knxnet.RouterSocket, error) {
+	if err := checkMulticastInterface(ifi); err != nil {
+		return nil, err
+	}
+
+	return knxnet.ListenRouterOnInterface(ifi, multicastAddress, false)
+}
+
+// checkMulticastInterface verifies that multicast packets can be exchanged on the interface.
+func checkMulticastInterface(ifi *net.Interface) error {
+	if ifi == nil {
+		return nil
+	}
+
+	if ifi.Flags&net.FlagUp == 0 {
+		return fmt.Errorf("interface %s is down", ifi.Name)
+	}
+
+	if ifi.Flags&net.FlagMulticast == 0 {
+		return fmt.Errorf("interface %s does not support multicast", ifi.Name)
+	}
+
+	return nil
+}
+
 // Discover all KNXnet/IP servers.
 func Discover(multicastDiscoveryAddress string, searchTimeout time.Duration) ([]*knxnet.SearchRes, error) {
 	return DiscoverOnInterface(nil, multicastDiscoveryAddress, searchTimeout)
@@ -17,7 +54,7 @@ func Discover(multicastDiscoveryAddress string, searchTimeout time.Duration) ([]
 // DiscoverOnInterface discovers all KNXnet/IP servers on a specific interface. If the
 // interface is nil, the system-assigned multicast interface is used.
 func DiscoverOnInterface(ifi *net.Interface, multicastDiscoveryAddress string, searchTimeout time.Duration) ([]*knxnet.SearchRes, error) {
-	socket, err := knxnet.ListenRouterOnInterface(ifi, multicastDiscoveryAddress, false)
+	socket, err := DialMulticast(ifi, multicastDiscoveryAddress)
 	if err != nil {
 		return nil, err
 	}
@@ -52,3 +89,209 @@ loop:
 
 	return results, nil
 }
+
+// CountInterfaces counts the KNXnet/IP servers which respond to a search within the timeout. A
+// server which responds several times, e.g. once per network interface, is counted once, as
+// responders are told apart by their serial number. The responses are not unpacked, only their
+// serial numbers are read, which keeps counting cheap for large installations.
+func CountInterfaces(timeout time.Duration) (int, error) {
+	return CountInterfacesOnInterface(nil, timeout)
+}
+
+// CountInterfacesOnInterface works like CountInterfaces, but searches on a specific network
+// interface. If the interface is nil, the system-assigned multicast interface is used.
+func CountInterfacesOnInterface(ifi *net.Interface, timeout time.Duration) (int, error) {
+	if err := checkMulticastInterface(ifi); err != nil {
+		return 0, err
+	}
+
+	conn, addr, err := knxnet.ListenMulticastUDP(ifi, DefaultDiscoveryAddress, false)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	return countInterfaces(conn, addr, timeout)
+}
+
+// countInterfaces performs the search of CountInterfaces through the connection. The search is
+// sent to addr, where the responses are expected as well.
+func countInterfaces(conn net.PacketConn, addr net.Addr, timeout time.Duration) (int, error) {
+	req, err := knxnet.NewSearchReq(addr)
+	if err != nil {
+		return 0, err
+	}
+
+	frame, err := knxnet.PackFrame(req)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return 0, err
+	}
+
+	if _, err := conn.WriteTo(frame, addr); err != nil {
+		return 0, err
+	}
+
+	serials := make(map[knxnet.DeviceSerialNumber]struct{})
+	buffer := make([]byte, 1024)
+
+	for {
+		n, _, err := conn.ReadFrom(buffer)
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return len(serials), nil
+		} else if err != nil {
+			return len(serials), err
+		}
+
+		// Other datagrams, including the search request itself, are skipped.
+		serial, err := knxnet.SearchResSerialNumber(buffer[:n])
+		if err != nil {
+			continue
+		}
+
+		serials[serial] = struct{}{}
+	}
+}
+
+// DiscoverProgrammingModeDetailed discovers the KNXnet/IP servers which are in programming mode
+// using an extended search, and asks them to include the given DIBs in their responses. Servers
+// are trusted to honour the parameters, but responses which do not report programming mode or lack
+// one of the requested DIBs are dropped.
+func DiscoverProgrammingModeDetailed(timeout time.Duration, dibs ...knxnet.DescriptionType) ([]knxnet.SearchResExt, error) {
+	return DiscoverProgrammingModeDetailedOnInterface(nil, timeout, dibs...)
+}
+
+// DiscoverProgrammingModeDetailedOnInterface works like DiscoverProgrammingModeDetailed, but
+// searches on a specific network interface. If the interface is nil, the system-assigned multicast
+// interface is used.
+func DiscoverProgrammingModeDetailedOnInterface(
+	ifi *net.Interface,
+	timeout time.Duration,
+	dibs ...knxnet.DescriptionType,
+) ([]knxnet.SearchResExt, error) {
+	socket, err := DialMulticast(ifi, DefaultDiscoveryAddress)
+	if err != nil {
+		return nil, err
+	}
+	defer socket.Close()
+
+	return discoverProgrammingMode(socket, socket.Addr(), timeout, dibs)
+}
+
+// discoverProgrammingMode performs the extended search of DiscoverProgrammingModeDetailed through
+// the socket. Responses are sent to addr.
+func discoverProgrammingMode(
+	socket knxnet.Socket,
+	addr net.Addr,
+	timeout time.Duration,
+	dibs []knxnet.DescriptionType,
+) ([]knxnet.SearchResExt, error) {
+	params := []knxnet.SRPBlock{knxnet.NewSelectProgMode(true)}
+	if len(dibs) > 0 {
+		params = append(params, knxnet.NewRequestDIBs(false, dibs...))
+	}
+
+	req, err := knxnet.NewSearchReqExt(addr, params...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := socket.Send(req); err != nil {
+		return nil, err
+	}
+
+	results := []knxnet.SearchResExt{}
+	deadline := time.After(timeout)
+
+	for {
+		select {
+		case msg, open := <-socket.Inbound():
+			if !open {
+				return results, nil
+			}
+
+			res, ok := msg.(*knxnet.SearchResExt)
+			if !ok {
+				// Noncompliant servers may answer with a plain search response.
+				if msg.Service() == knxnet.SearchResService {
+					util.Log(socket, "Dropping search response: %v", &knxnet.ServiceMismatchError{
+						Expected: knxnet.SearchResExtService,
+						Actual:   msg.Service(),
+					})
+				}
+				continue
+			}
+
+			if err := checkProgModeResponse(res, dibs); err != nil {
+				util.Log(socket, "Dropping search response: %v", err)
+				continue
+			}
+
+			results = append(results, *res)
+
+		case <-deadline:
+			return results, nil
+		}
+	}
+}
+
+// checkProgModeResponse verifies that the response comes from a device in programming mode and
+// contains the requested DIBs.
+func checkProgModeResponse(res *knxnet.SearchResExt, dibs []knxnet.DescriptionType) error {
+	dib, _ := res.DIB(knxnet.DescriptionTypeDeviceInfo)
+
+	info, ok := dib.(*knxnet.DeviceInformationBlock)
+	if !ok {
+		return errors.New("device information is missing")
+	}
+
+	if !info.Status.ProgrammingMode() {
+		return fmt.Errorf("device %v is not in programming mode", info.Source)
+	}
+
+	for _, ty := range dibs {
+		if _, ok := res.DIB(ty); !ok {
+			return fmt.Errorf("requested DIB %v is missing", ty)
+		}
+	}
+
+	return nil
+}
+
+// DialSearchRes establishes a data-link layer tunnel to the control endpoint advertised in a search
+// response. If the server asks for route back, the tunnel is established with the address the
+// response has been received from. The timeout is used as the tunnel's response timeout.
+func DialSearchRes(res *knxnet.SearchRes, timeout time.Duration) (*Tunnel, error) {
+	if res == nil {
+		return nil, errors.New("search response is nil")
+	}
+
+	addr, port := res.Control.Address, res.Control.Port
+	if addr == (knxnet.Address{}) || port == 0 {
+		if res.Sender == nil {
+			return nil, errors.New("control endpoint is route back but the sender is unknown")
+		}
+
+		if addr == (knxnet.Address{}) {
+			ip := res.Sender.IP.To4()
+			if ip == nil {
+				return nil, fmt.Errorf("sender %v is not an IPv4 address", res.Sender)
+			}
+
+			copy(addr[:], ip)
+		}
+
+		if port == 0 {
+			port = knxnet.Port(res.Sender.Port)
+		}
+	}
+
+	config := DefaultTunnelConfig
+	config.ResponseTimeout = timeout
+	config.UseTCP = res.Control.Protocol == knxnet.TCP4
+
+	return NewTunnel(fmt.Sprintf("%v:%d", addr, port), knxnet.TunnelLayerData, config)
+}
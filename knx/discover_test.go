@@ -0,0 +1,243 @@
+// Licensed under the MIT license which can be found in the LICENSE file.
+
+package knx
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/LB-00/knx-go/knx/knxnet"
+)
+
+// serveFakeGateway answers connection requests received on the given UDP connection. Description
+// requests are answered with the given description, if any.
+func serveFakeGateway(conn *net.UDPConn, description *knxnet.DescriptionRes) {
+	buffer := make([]byte, 1024)
+
+	for {
+		n, sender, err := conn.ReadFromUDP(buffer)
+		if err != nil {
+			return
+		}
+
+		var srv knxnet.Service
+		if _, err := knxnet.Unpack(buffer[:n], &srv); err != nil {
+			continue
+		}
+
+		switch srv.(type) {
+		case *knxnet.ConnReq:
+			res := &knxnet.ConnRes{Channel: 1, Status: knxnet.NoError}
+			res.Control, _ = knxnet.HostInfoFromAddress(conn.LocalAddr())
+
+			conn.WriteToUDP(knxnet.AllocAndPack(res), sender)
+
+		case *knxnet.DescriptionReq:
+			if description != nil {
+				conn.WriteToUDP(knxnet.AllocAndPack(description), sender)
+			}
+
+		case *knxnet.DiscReq:
+			conn.WriteToUDP(knxnet.AllocAndPack(&knxnet.DiscRes{Channel: 1}), sender)
+		}
+	}
+}
+
+func TestDialSearchRes(t *testing.T) {
+	gateway, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gateway.Close()
+
+	go serveFakeGateway(gateway, nil)
+
+	gatewayAddr := gateway.LocalAddr().(*net.UDPAddr)
+
+	t.Run("Control", func(t *testing.T) {
+		res := &knxnet.SearchRes{}
+		res.Control, _ = knxnet.HostInfoFromAddress(gatewayAddr)
+
+		tunnel, err := DialSearchRes(res, time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tunnel.Close()
+	})
+
+	// Route back uses the address the response came from.
+	t.Run("RouteBack", func(t *testing.T) {
+		res := &knxnet.SearchRes{
+			Control: knxnet.HostInfo{Protocol: knxnet.UDP4},
+			Sender:  gatewayAddr,
+		}
+
+		tunnel, err := DialSearchRes(res, time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tunnel.Close()
+	})
+
+	t.Run("RouteBackUnknownSender", func(t *testing.T) {
+		res := &knxnet.SearchRes{
+			Control: knxnet.HostInfo{Protocol: knxnet.UDP4},
+		}
+
+		if _, err := DialSearchRes(res, time.Second); err == nil {
+			t.Fatal("Should not succeed")
+		}
+	})
+}
+
+func TestDiscoverProgrammingMode(t *testing.T) {
+	client, gateway := newDummySockets()
+	defer client.Close()
+	defer gateway.Close()
+
+	control := knxnet.HostInfo{Protocol: knxnet.UDP4, Address: knxnet.Address{192, 168, 1, 10}, Port: 3671}
+
+	makeResponse := func(status knxnet.DeviceStatus, dibs ...knxnet.DIB) *knxnet.SearchResExt {
+		info := &knxnet.DeviceInformationBlock{
+			Type:         knxnet.DescriptionTypeDeviceInfo,
+			Status:       status,
+			HardwareAddr: make([]byte, 6),
+		}
+
+		return &knxnet.SearchResExt{Control: control, DIBs: append([]knxnet.DIB{info}, dibs...)}
+	}
+
+	tunnelling := &knxnet.TunnellingInfoDIB{Type: knxnet.DescriptionTypeTunnellingInfo, APDUSize: 254}
+
+	// The fake server honours both SRPs, whereas two misbehaving servers ignore one of them.
+	go func() {
+		msg := <-gateway.Inbound()
+
+		req, ok := msg.(*knxnet.SearchReqExt)
+		if !ok {
+			t.Errorf("Unexpected request %T", msg)
+			return
+		}
+
+		if len(req.Parameters) != 2 {
+			t.Errorf("Unexpected parameters %v", req.Parameters)
+			return
+		}
+
+		if srp, ok := req.Parameters[0].(*knxnet.SelectProgMode); !ok || !srp.Mandatory {
+			t.Errorf("Unexpected parameter %#v", req.Parameters[0])
+		}
+
+		srp, ok := req.Parameters[1].(*knxnet.RequestDIBs)
+		if !ok || len(srp.DescTypes) != 1 || srp.DescTypes[0] != knxnet.DescriptionTypeTunnellingInfo {
+			t.Errorf("Unexpected parameter %#v", req.Parameters[1])
+		}
+
+		gateway.sendAny(makeResponse(0x01, tunnelling))
+		gateway.sendAny(makeResponse(0x00, tunnelling))
+		gateway.sendAny(makeResponse(0x01))
+	}()
+
+	results, err := discoverProgrammingMode(
+		client, client.LocalAddr(), 200*time.Millisecond,
+		[]knxnet.DescriptionType{knxnet.DescriptionTypeTunnellingInfo},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 1 {
+		t.Fatalf("Unexpected number of results: %d", len(results))
+	}
+
+	if _, ok := results[0].DIB(knxnet.DescriptionTypeTunnellingInfo); !ok {
+		t.Error("Tunnelling information is missing")
+	}
+}
+
+func TestCountInterfaces(t *testing.T) {
+	group := &net.UDPAddr{IP: net.IPv4(224, 0, 23, 12), Port: 3671}
+	client, gateway := newMemPacketConns(group, &net.UDPAddr{IP: net.IPv4(192, 168, 1, 10), Port: 3671})
+	defer client.Close()
+	defer gateway.Close()
+
+	makeResponse := func(serial knxnet.DeviceSerialNumber, ip byte) []byte {
+		res := &knxnet.SearchRes{
+			Control: knxnet.HostInfo{Protocol: knxnet.UDP4, Address: knxnet.Address{192, 168, 1, ip}, Port: 3671},
+		}
+		res.DescriptionB.DeviceHardware.Type = knxnet.DescriptionTypeDeviceInfo
+		res.DescriptionB.DeviceHardware.HardwareAddr = make([]byte, 6)
+		res.DescriptionB.DeviceHardware.SerialNumber = serial
+		res.DescriptionB.SupportedServices.Type = knxnet.DescriptionTypeSupportedServiceFamilies
+
+		return knxnet.AllocAndPack(res)
+	}
+
+	// The optional DIBs are not decoded, hence a malformed one does not prevent counting.
+	malformed := append(makeResponse(knxnet.DeviceSerialNumber{0x00, 0xfa, 0, 0, 0, 3}, 30),
+		0x03, byte(knxnet.DescriptionTypeIPConfig), 0x00)
+	malformed[4], malformed[5] = 0, byte(len(malformed))
+
+	go func() {
+		buffer := make([]byte, 1024)
+
+		n, _, err := gateway.ReadFrom(buffer)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if srv, err := knxnet.Receive(buffer[:n]); err != nil || srv.Service() != knxnet.SearchReqService {
+			t.Errorf("Unexpected request %v: %v", srv, err)
+			return
+		}
+
+		// The first server responds on two of its interfaces.
+		for _, datagram := range [][]byte{
+			makeResponse(knxnet.DeviceSerialNumber{0x00, 0xfa, 0, 0, 0, 1}, 10),
+			makeResponse(knxnet.DeviceSerialNumber{0x00, 0xfa, 0, 0, 0, 2}, 20),
+			makeResponse(knxnet.DeviceSerialNumber{0x00, 0xfa, 0, 0, 0, 1}, 11),
+			malformed,
+			{0x13, 0x37},
+		} {
+			gateway.WriteTo(datagram, group)
+		}
+	}()
+
+	count, err := countInterfaces(client, group, 200*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 3 {
+		t.Errorf("Unexpected count: %d", count)
+	}
+}
+
+func TestCheckMulticastInterface(t *testing.T) {
+	// The system-assigned interface is always acceptable.
+	if err := checkMulticastInterface(nil); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	ifi := &net.Interface{Name: "eth1", Flags: net.FlagUp | net.FlagMulticast}
+	if err := checkMulticastInterface(ifi); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	ifi.Flags = net.FlagMulticast
+	if err := checkMulticastInterface(ifi); err == nil {
+		t.Error("Interfaces which are down must be rejected")
+	}
+
+	ifi.Flags = net.FlagUp
+	if err := checkMulticastInterface(ifi); err == nil {
+		t.Error("Interfaces without multicast must be rejected")
+	}
+
+	// DialMulticast rejects the interface before opening a socket.
+	if _, err := DialMulticast(ifi, DefaultDiscoveryAddress); err == nil {
+		t.Error("Should not succeed")
+	}
+}
@@ -0,0 +1,94 @@
+// Licensed under the MIT license which can be found in the LICENSE file.
+
+package knx
+
+import (
+	"time"
+
+	"github.com/LB-00/knx-go/knx/cemi"
+	"github.com/LB-00/knx-go/knx/util"
+)
+
+// SetDryRun enables or disables the dry run mode of the connection. In dry run mode, the write
+// procedures WriteMemoryBits, WriteUserMemory and Restart do not transmit their telegrams, but
+// encode, log and record them, so that they can be inspected with DryRunFrames. Read procedures
+// are not affected. Enabling the mode discards the telegrams recorded before.
+func (conn *P2PConnection) SetDryRun(enabled bool) {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	conn.dryRun = enabled
+	if enabled {
+		conn.dryFrames = nil
+	}
+}
+
+// DryRun reports whether the connection is in dry run mode.
+func (conn *P2PConnection) DryRun() bool {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	return conn.dryRun
+}
+
+// DryRunFrames returns the cEMI frames which the write procedures would have sent, in the order in
+// which they have been produced.
+func (conn *P2PConnection) DryRunFrames() [][]byte {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	frames := make([][]byte, len(conn.dryFrames))
+	copy(frames, conn.dryFrames)
+
+	return frames
+}
+
+// sendWrite sends the request of a write procedure like sendTracked. In dry run mode, the request
+// is recorded instead and dry is true.
+func (conn *P2PConnection) sendWrite(req cemi.Message, t time.Duration) (dry bool, err error) {
+	if err := conn.begin(); err != nil {
+		return false, err
+	}
+	defer conn.active.Done()
+
+	conn.mu.Lock()
+	dryRun := conn.dryRun
+	seq := (conn.seqNumber + 1) % 16
+	conn.mu.Unlock()
+
+	if !dryRun {
+		return false, conn.sendAcked(req, t)
+	}
+
+	// The request is encoded as it would be sent next, without consuming the sequence number.
+	if err := conn.setSeqNum(req, seq); err != nil {
+		return true, err
+	}
+
+	if err := conn.checkAPDULength(req); err != nil {
+		return true, err
+	}
+
+	frame := make([]byte, cemi.Size(req))
+	cemi.Pack(frame, req)
+
+	util.Log(conn, "Dry run, not sending % x", frame)
+
+	conn.mu.Lock()
+	conn.dryFrames = append(conn.dryFrames, frame)
+	conn.mu.Unlock()
+
+	return true, nil
+}
+
+// SetDryRun enables or disables the dry run mode of all current and future connections, see
+// P2PConnection.SetDryRun.
+func (m *Management) SetDryRun(enabled bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.dryRun = enabled
+	for _, conn := range m.connections {
+		conn.SetDryRun(enabled)
+	}
+}
@@ -0,0 +1,94 @@
+// Licensed under the MIT license which can be found in the LICENSE file.
+
+package knx
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/LB-00/knx-go/knx/cemi"
+)
+
+func TestManagement_DryRun(t *testing.T) {
+	addr := cemi.NewIndividualAddr3(1, 1, 5)
+
+	m, tunnel, sent := makeRecordingManagement(t)
+	m.SetDryRun(true)
+
+	confirmConnect(tunnel)
+	conn, err := m.Connect(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Disconnect(addr)
+
+	// Skip the T_CONNECT.
+	<-sent
+
+	if !conn.DryRun() {
+		t.Fatal("Connection should inherit the dry run mode")
+	}
+
+	if err := conn.WriteMemoryBits(0x0116, []byte{0xfe}, []byte{0x01}, time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := conn.WriteUserMemory(0xf0102, []byte{0x01, 0x02}, time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	if d, err := conn.Restart(EraseFactoryReset, 0, time.Second); err != nil || d != 0 {
+		t.Fatalf("Unexpected restart result %v, %v", d, err)
+	}
+
+	select {
+	case req := <-sent:
+		t.Fatalf("Request %+v has been sent in dry run mode", req)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	expected := []struct {
+		cmd  cemi.APCI
+		data []byte
+	}{
+		{cemi.MemoryBitWrite, []byte{0x01, 0x01, 0x16, 0xfe, 0x01}},
+		{cemi.UserMemoryWrite, []byte{0xf2, 0x01, 0x02, 0x01, 0x02}},
+		{cemi.Restart, []byte{restartMasterReset, byte(EraseFactoryReset), 0}},
+	}
+
+	frames := conn.DryRunFrames()
+	if len(frames) != len(expected) {
+		t.Fatalf("Recorded %d frames, expected %d", len(frames), len(expected))
+	}
+
+	for i, frame := range frames {
+		var msg cemi.Message
+		if _, err := cemi.Unpack(frame, &msg); err != nil {
+			t.Fatal(err)
+		}
+
+		req, ok := msg.(*cemi.LDataReq)
+		if !ok {
+			t.Fatalf("Unexpected message %T", msg)
+		}
+
+		app := req.Data.(*cemi.AppData)
+		// The sequence number is the one of the next telegram which is actually sent.
+		if req.Destination != uint16(addr) || !app.Numbered || app.SeqNumber != 0 ||
+			app.Command != expected[i].cmd || !bytes.Equal(app.Data, expected[i].data) {
+			t.Errorf("Unexpected frame % x", frame)
+		}
+	}
+
+	// Leaving the dry run mode sends the telegrams again.
+	m.SetDryRun(false)
+
+	tunnel.inbound <- &cemi.LDataInd{LData: cemi.LData{Source: addr, Data: cemi.TAck(0)}}
+
+	if err := conn.WriteUserMemory(0xf0102, []byte{0x01, 0x02}, time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	expectRequest(t, sent, cemi.UserMemoryWrite)
+}
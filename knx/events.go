@@ -0,0 +1,134 @@
+// Licensed under the MIT license which can be found in the LICENSE file.
+
+package knx
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/LB-00/knx-go/knx/cemi"
+)
+
+// ConnectionEventType describes what has happened to a connection of a Management.
+type ConnectionEventType uint8
+
+// These are the types of connection events.
+const (
+	// ConnectionOpened is emitted when a connection to a device has been established.
+	ConnectionOpened ConnectionEventType = iota
+
+	// ConnectionClosed is emitted when a connection has ended, no matter which side has closed it.
+	ConnectionClosed
+
+	// ConnectionReconnected is emitted instead of ConnectionOpened when a connection has been
+	// established to a device whose previous connection has ended unexpectedly, e.g. because the
+	// device has closed it or because it has been reset.
+	ConnectionReconnected
+)
+
+// String returns the name of the event type.
+func (typ ConnectionEventType) String() string {
+	switch typ {
+	case ConnectionOpened:
+		return "ConnectionOpened"
+	case ConnectionClosed:
+		return "ConnectionClosed"
+	case ConnectionReconnected:
+		return "ConnectionReconnected"
+	}
+
+	return fmt.Sprintf("Unknown(%d)", uint8(typ))
+}
+
+// ConnectionEvent reports a change of a connection which a Management handles.
+type ConnectionEvent struct {
+	Type ConnectionEventType
+	Addr cemi.IndividualAddr
+
+	// Err is the reason why the connection has ended. It is only set for ConnectionClosed and nil
+	// if the connection has been closed by the caller.
+	Err error
+}
+
+// Subscription receives the connection events of a Management.
+type Subscription struct {
+	m       *Management
+	events  chan ConnectionEvent
+	dropped uint64
+}
+
+// Subscribe registers a subscription for the connection events of all connections. The events are
+// buffered up to the given size. Emitting never blocks, events which do not fit into the buffer
+// of a slow subscriber are dropped and counted.
+func (m *Management) Subscribe(buffer int) *Subscription {
+	sub := &Subscription{
+		m:      m,
+		events: make(chan ConnectionEvent, buffer),
+	}
+
+	m.eventsMu.Lock()
+	m.subscriptions[sub] = struct{}{}
+	m.eventsMu.Unlock()
+
+	return sub
+}
+
+// Events returns the channel which transmits the events. It is closed when the subscription is
+// cancelled.
+func (sub *Subscription) Events() <-chan ConnectionEvent {
+	return sub.events
+}
+
+// Dropped returns the number of events which have been dropped, because the subscriber fell
+// behind.
+func (sub *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&sub.dropped)
+}
+
+// Cancel ends the subscription and closes its channel. Cancelling more than once has no effect.
+func (sub *Subscription) Cancel() {
+	sub.m.eventsMu.Lock()
+	defer sub.m.eventsMu.Unlock()
+
+	if _, ok := sub.m.subscriptions[sub]; ok {
+		delete(sub.m.subscriptions, sub)
+		close(sub.events)
+	}
+}
+
+// emit sends the event to all subscriptions. The caller must hold eventsMu.
+func (m *Management) emit(event ConnectionEvent) {
+	for sub := range m.subscriptions {
+		select {
+		case sub.events <- event:
+		default:
+			atomic.AddUint64(&sub.dropped, 1)
+		}
+	}
+}
+
+// connectionOpened emits the event for a new connection to the device.
+func (m *Management) connectionOpened(addr cemi.IndividualAddr) {
+	m.eventsMu.Lock()
+	defer m.eventsMu.Unlock()
+
+	event := ConnectionEvent{Type: ConnectionOpened, Addr: addr}
+	if m.lost[addr] {
+		event.Type = ConnectionReconnected
+		delete(m.lost, addr)
+	}
+
+	m.emit(event)
+}
+
+// connectionClosed emits the event for the end of a connection to the device.
+func (m *Management) connectionClosed(addr cemi.IndividualAddr, err error) {
+	m.eventsMu.Lock()
+	defer m.eventsMu.Unlock()
+
+	if err != nil {
+		m.lost[addr] = true
+	}
+
+	m.emit(ConnectionEvent{Type: ConnectionClosed, Addr: addr, Err: err})
+}
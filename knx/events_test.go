@@ -0,0 +1,85 @@
+// Licensed under the MIT license which can be found in the LICENSE file.
+
+package knx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/LB-00/knx-go/knx/cemi"
+)
+
+// expectEvent receives the next connection event.
+func expectEvent(t *testing.T, sub *Subscription) ConnectionEvent {
+	t.Helper()
+
+	select {
+	case event := <-sub.Events():
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("No event has been emitted")
+	}
+
+	return ConnectionEvent{}
+}
+
+func TestManagement_Subscribe(t *testing.T) {
+	addr := cemi.NewIndividualAddr3(1, 1, 5)
+
+	m, tunnel := makeTestManagement(t)
+
+	sub := m.Subscribe(10)
+	defer sub.Cancel()
+
+	// This subscriber never keeps up.
+	slow := m.Subscribe(0)
+	defer slow.Cancel()
+
+	confirmConnect(tunnel)
+	if _, err := m.Connect(addr); err != nil {
+		t.Fatal(err)
+	}
+
+	if event := expectEvent(t, sub); event != (ConnectionEvent{Type: ConnectionOpened, Addr: addr}) {
+		t.Errorf("Unexpected event %+v", event)
+	}
+
+	// The device closes the connection.
+	tunnel.inbound <- &cemi.LDataInd{LData: cemi.LData{Source: addr, Data: cemi.TDisconnect()}}
+
+	event := expectEvent(t, sub)
+	if event.Type != ConnectionClosed || event.Addr != addr || event.Err != ErrPeerDisconnected {
+		t.Errorf("Unexpected event %+v", event)
+	}
+
+	// Connecting again replaces the lost connection.
+	confirmConnect(tunnel)
+	if _, err := m.Connect(addr); err != nil {
+		t.Fatal(err)
+	}
+
+	if event := expectEvent(t, sub); event != (ConnectionEvent{Type: ConnectionReconnected, Addr: addr}) {
+		t.Errorf("Unexpected event %+v", event)
+	}
+
+	// Closing the connection deliberately carries no error.
+	if err := m.Disconnect(addr); err != nil {
+		t.Fatal(err)
+	}
+
+	if event := expectEvent(t, sub); event != (ConnectionEvent{Type: ConnectionClosed, Addr: addr}) {
+		t.Errorf("Unexpected event %+v", event)
+	}
+
+	if dropped := slow.Dropped(); dropped != 4 {
+		t.Errorf("Dropped %d events, expected 4", dropped)
+	}
+
+	// No events are delivered after cancelling.
+	sub.Cancel()
+	sub.Cancel()
+
+	if _, open := <-sub.Events(); open {
+		t.Error("Channel should be closed")
+	}
+}
@@ -27,7 +27,7 @@ func (cmd GroupCommand) String() string {
 	return "Unknown"
 }
 
-// These are known group commands.
+// These are known group commands. They correspond to the upper four bits of the 10-bit APCI.
 const (
 	GroupRead     GroupCommand = 0
 	GroupResponse GroupCommand = 1
@@ -63,7 +63,7 @@ func serveGroupInbound(inbound <-chan cemi.Message, outbound chan<- GroupEvent)
 
 			if app, ok := ind.Data.(*cemi.AppData); ok && app.Command.IsGroupCommand() {
 				outbound <- GroupEvent{
-					Command:     GroupCommand(app.Command),
+					Command:     GroupCommand(app.Command >> 6),
 					Source:      ind.Source,
 					Destination: cemi.GroupAddr(ind.Destination),
 					Data:        app.Data,
@@ -88,7 +88,7 @@ var defaultGroupLData = cemi.LData{
 func buildGroupOutbound(event GroupEvent) cemi.LData {
 	ldata := defaultGroupLData
 	ldata.Data = &cemi.AppData{
-		Command: cemi.APCI(event.Command),
+		Command: cemi.APCI(event.Command) << 6,
 		Data:    event.Data,
 	}
 	ldata.Source = event.Source
@@ -0,0 +1,148 @@
+// Licensed under the MIT license which can be found in the LICENSE file.
+
+package knx
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/LB-00/knx-go/knx/cemi"
+)
+
+// Locations of the tables in devices of realization type 1 (System 1).
+const (
+	addrTableAddrType1     = 0x0116
+	assocTablePtrAddrType1 = 0x0111
+)
+
+// Interface objects and property which reference the tables in devices of realization type 2.
+const (
+	objIndexAddrTable  = 1
+	objIndexAssocTable = 2
+	pidTableReference  = 7
+)
+
+// parseAddressTable parses a group address table. The first entry is the individual address of the
+// device, all following entries are group addresses.
+func parseAddressTable(data []byte) ([]uint16, error) {
+	if len(data) < 1 || len(data) < 1+2*int(data[0]) {
+		return nil, errors.New("address table is truncated")
+	}
+
+	entries := make([]uint16, data[0])
+	for i := range entries {
+		entries[i] = uint16(data[1+2*i])<<8 | uint16(data[2+2*i])
+	}
+
+	return entries, nil
+}
+
+// parseAssociationTable parses an association table into group object numbers keyed by their
+// address table index.
+func parseAssociationTable(data []byte, addrs []uint16) (map[uint8][]cemi.GroupAddr, error) {
+	if len(data) < 1 || len(data) < 1+2*int(data[0]) {
+		return nil, errors.New("association table is truncated")
+	}
+
+	objects := make(map[uint8][]cemi.GroupAddr)
+
+	for i := 0; i < int(data[0]); i++ {
+		index, object := data[1+2*i], data[2+2*i]
+
+		// Index 0 refers to the individual address.
+		if index == 0 || int(index) >= len(addrs) {
+			return nil, fmt.Errorf("association refers to invalid address table index %d", index)
+		}
+
+		objects[object] = append(objects[object], cemi.GroupAddr(addrs[index]))
+	}
+
+	return objects, nil
+}
+
+// readCountedTable reads a table which starts with a single byte entry count, each entry having
+// two bytes.
+func (conn *P2PConnection) readCountedTable(addr uint16, t time.Duration) ([]byte, error) {
+	count, err := conn.ReadMemory(addr, 1, t)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := conn.ReadMemory(addr+1, 2*uint(count[0]), t)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(count, entries...), nil
+}
+
+// readTableReference reads the memory address of the table which belongs to the interface object.
+func (conn *P2PConnection) readTableReference(objIndex uint8, t time.Duration) (uint16, error) {
+	data, err := conn.ReadProperty(objIndex, pidTableReference, 1, 1, t)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(data) != 4 || data[0] != 0 || data[1] != 0 {
+		return 0, fmt.Errorf("unsupported table reference %x", data)
+	}
+
+	return uint16(data[2])<<8 | uint16(data[3]), nil
+}
+
+// ReadGroupObjectTable reads the group address table and the association table of the device and
+// returns the group addresses associated with each group object number. Only devices of
+// realization type 1 (System 1) and 2 (System 2 and System 7) are supported. The former keep their
+// tables at fixed locations, the latter reference them through the table objects.
+func (conn *P2PConnection) ReadGroupObjectTable(t time.Duration) (map[uint8][]cemi.GroupAddr, error) {
+	caps, err := conn.Capabilities(t)
+	if err != nil {
+		return nil, err
+	}
+
+	var addrTableAddr, assocTableAddr uint16
+
+	switch caps.MaskVersion {
+	case 0x0010, 0x0011, 0x0012:
+		addrTableAddr = addrTableAddrType1
+
+		ptr, err := conn.ReadMemory(assocTablePtrAddrType1, 1, t)
+		if err != nil {
+			return nil, err
+		}
+
+		assocTableAddr = 0x0100 | uint16(ptr[0])
+
+	case 0x0020, 0x0021, 0x0025, 0x0700, 0x0701, 0x0705:
+		addrTableAddr, err = conn.readTableReference(objIndexAddrTable, t)
+		if err != nil {
+			return nil, err
+		}
+
+		assocTableAddr, err = conn.readTableReference(objIndexAssocTable, t)
+		if err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported mask version %#04x", caps.MaskVersion)
+	}
+
+	addrTable, err := conn.readCountedTable(addrTableAddr, t)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs, err := parseAddressTable(addrTable)
+	if err != nil {
+		return nil, err
+	}
+
+	assocTable, err := conn.readCountedTable(assocTableAddr, t)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseAssociationTable(assocTable, addrs)
+}
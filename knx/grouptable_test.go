@@ -0,0 +1,199 @@
+// Licensed under the MIT license which can be found in the LICENSE file.
+
+package knx
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/LB-00/knx-go/knx/cemi"
+)
+
+// serveFakeDevice acknowledges each request sent to the device and answers it with the result of
+// handle, unless that is nil.
+func serveFakeDevice(
+	t *testing.T,
+	tunnel *Tunnel,
+	sent <-chan *cemi.LDataReq,
+	addr cemi.IndividualAddr,
+	handle func(req *cemi.AppData) *cemi.AppData,
+) {
+	stop := make(chan struct{})
+	t.Cleanup(func() { close(stop) })
+
+	go func() {
+		var seqNumber uint8
+
+		for {
+			select {
+			case <-stop:
+				return
+
+			case req := <-sent:
+				app, ok := req.Data.(*cemi.AppData)
+				if !ok {
+					continue
+				}
+
+				tunnel.inbound <- &cemi.LDataInd{LData: cemi.LData{Source: addr, Data: cemi.TAck(app.SeqNumber)}}
+
+				if res := handle(app); res != nil {
+					res.Numbered = true
+					res.SeqNumber = seqNumber
+					seqNumber = (seqNumber + 1) % 16
+
+					tunnel.inbound <- &cemi.LDataInd{LData: cemi.LData{Source: addr, Data: res}}
+				}
+			}
+		}
+	}()
+}
+
+// makeMemoryDevice answers device descriptor and memory reads of a device with the given mask
+// version and memory image. Property reads are answered using props, keyed by object index and
+// property ID.
+func makeMemoryDevice(mask uint16, memory map[uint16]byte, props map[[2]uint8][]byte) func(*cemi.AppData) *cemi.AppData {
+	return func(req *cemi.AppData) *cemi.AppData {
+		switch req.Command {
+		case cemi.MaskVersionRead:
+			return &cemi.AppData{
+				Command: cemi.MaskVersionResponse,
+				Data:    []byte{0, byte(mask >> 8), byte(mask)},
+			}
+
+		case cemi.MemoryRead:
+			count := req.Data[0] & 0x3f
+			addr := uint16(req.Data[1])<<8 | uint16(req.Data[2])
+
+			data := append([]byte(nil), req.Data[:3]...)
+			for i := uint16(0); i < uint16(count); i++ {
+				data = append(data, memory[addr+i])
+			}
+
+			return &cemi.AppData{Command: cemi.MemoryResponse, Data: data}
+
+		case cemi.PropertyValueRead:
+			data := append([]byte(nil), req.Data...)
+			if value, ok := props[[2]uint8{req.Data[0], req.Data[1]}]; ok {
+				data = append(data, value...)
+			} else {
+				data[2] &= 0x0f
+			}
+
+			return &cemi.AppData{Command: cemi.PropertyValueResponse, Data: data}
+		}
+
+		return nil
+	}
+}
+
+// writeMemory copies the data into the memory image.
+func writeMemory(memory map[uint16]byte, addr uint16, data ...byte) {
+	for i, b := range data {
+		memory[addr+uint16(i)] = b
+	}
+}
+
+// groupTables is the content of the group address table and association table of a device with
+// individual address 1.1.5 and two group objects.
+var (
+	addrTable = []byte{
+		0x04,       // 4 entries
+		0x11, 0x05, // 1.1.5
+		0x08, 0x01, // 1/0/1
+		0x08, 0x02, // 1/0/2
+		0x10, 0x01, // 2/0/1
+	}
+	assocTable = []byte{
+		0x04,       // 4 associations
+		0x01, 0x00, // 1/0/1 -> object 0
+		0x02, 0x01, // 1/0/2 -> object 1
+		0x03, 0x00, // 2/0/1 -> object 0
+		0x03, 0x01, // 2/0/1 -> object 1
+	}
+	expectedGroupObjects = map[uint8][]cemi.GroupAddr{
+		0: {cemi.NewGroupAddr3(1, 0, 1), cemi.NewGroupAddr3(2, 0, 1)},
+		1: {cemi.NewGroupAddr3(1, 0, 2), cemi.NewGroupAddr3(2, 0, 1)},
+	}
+)
+
+func TestP2PConnection_ReadGroupObjectTable(t *testing.T) {
+	addr := cemi.NewIndividualAddr3(1, 1, 5)
+
+	// System 1 keeps the tables at fixed locations.
+	t.Run("RealizationType1", func(t *testing.T) {
+		conn, tunnel, sent := connectRecording(t, addr)
+
+		memory := map[uint16]byte{}
+		writeMemory(memory, 0x0111, 0x50)
+		writeMemory(memory, 0x0116, addrTable...)
+		writeMemory(memory, 0x0150, assocTable...)
+
+		serveFakeDevice(t, tunnel, sent, addr, makeMemoryDevice(0x0012, memory, nil))
+
+		objects, err := conn.ReadGroupObjectTable(time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(objects, expectedGroupObjects) {
+			t.Errorf("Unexpected group objects: %v", objects)
+		}
+	})
+
+	// System 2 references the tables through the table objects.
+	t.Run("RealizationType2", func(t *testing.T) {
+		conn, tunnel, sent := connectRecording(t, addr)
+
+		memory := map[uint16]byte{}
+		writeMemory(memory, 0x4000, addrTable...)
+		writeMemory(memory, 0x4100, assocTable...)
+
+		props := map[[2]uint8][]byte{
+			{objIndexAddrTable, pidTableReference}:  {0x00, 0x00, 0x40, 0x00},
+			{objIndexAssocTable, pidTableReference}: {0x00, 0x00, 0x41, 0x00},
+		}
+
+		serveFakeDevice(t, tunnel, sent, addr, makeMemoryDevice(0x0025, memory, props))
+
+		objects, err := conn.ReadGroupObjectTable(time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(objects, expectedGroupObjects) {
+			t.Errorf("Unexpected group objects: %v", objects)
+		}
+	})
+
+	t.Run("UnsupportedMask", func(t *testing.T) {
+		conn, tunnel, sent := connectRecording(t, addr)
+
+		serveFakeDevice(t, tunnel, sent, addr, makeMemoryDevice(0x07b0, nil, nil))
+
+		if _, err := conn.ReadGroupObjectTable(time.Second); err == nil {
+			t.Fatal("Should not succeed")
+		}
+	})
+}
+
+func TestParseAssociationTable(t *testing.T) {
+	addrs, err := parseAddressTable(addrTable)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(addrs) != 4 || addrs[0] != uint16(cemi.NewIndividualAddr3(1, 1, 5)) {
+		t.Fatalf("Unexpected address table: %v", addrs)
+	}
+
+	// Associations must not refer to the individual address.
+	if _, err := parseAssociationTable([]byte{0x01, 0x00, 0x00}, addrs); err == nil {
+		t.Error("Should not succeed")
+	}
+
+	if _, err := parseAddressTable(addrTable[:5]); err == nil {
+		t.Error("Truncated address table should not succeed")
+	}
+}
@@ -0,0 +1,100 @@
+// Licensed under the MIT license which can be found in the LICENSE file.
+
+package knx
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/LB-00/knx-go/knx/cemi"
+)
+
+// inboundRing is a fixed-size ring buffer for incoming messages. When the buffer is full, the
+// oldest message is discarded in favour of the new one.
+type inboundRing struct {
+	mu    sync.Mutex
+	buf   []cemi.Message
+	head  int
+	count int
+
+	// notify signals that new messages are available.
+	notify chan struct{}
+
+	// dropped counts the discarded messages.
+	dropped uint64
+}
+
+// newInboundRing creates a ring buffer with the given capacity.
+func newInboundRing(size uint) *inboundRing {
+	return &inboundRing{
+		buf:    make([]cemi.Message, size),
+		notify: make(chan struct{}, 1),
+	}
+}
+
+// push appends the message to the ring buffer, discarding the oldest message if necessary.
+func (ring *inboundRing) push(msg cemi.Message) {
+	ring.mu.Lock()
+
+	if ring.count == len(ring.buf) {
+		ring.buf[ring.head] = nil
+		ring.head = (ring.head + 1) % len(ring.buf)
+		ring.count--
+
+		atomic.AddUint64(&ring.dropped, 1)
+	}
+
+	ring.buf[(ring.head+ring.count)%len(ring.buf)] = msg
+	ring.count++
+
+	ring.mu.Unlock()
+
+	select {
+	case ring.notify <- struct{}{}:
+	default:
+	}
+}
+
+// pop removes the oldest message from the ring buffer.
+func (ring *inboundRing) pop() (cemi.Message, bool) {
+	ring.mu.Lock()
+	defer ring.mu.Unlock()
+
+	if ring.count == 0 {
+		return nil, false
+	}
+
+	msg := ring.buf[ring.head]
+	ring.buf[ring.head] = nil
+	ring.head = (ring.head + 1) % len(ring.buf)
+	ring.count--
+
+	return msg, true
+}
+
+// droppedCount returns the number of discarded messages.
+func (ring *inboundRing) droppedCount() uint64 {
+	return atomic.LoadUint64(&ring.dropped)
+}
+
+// serve relays the buffered messages to the inbound channel until stop is closed.
+func (ring *inboundRing) serve(inbound chan<- cemi.Message, stop <-chan struct{}) {
+	for {
+		msg, ok := ring.pop()
+		if !ok {
+			select {
+			case <-stop:
+				return
+			case <-ring.notify:
+			}
+
+			continue
+		}
+
+		select {
+		case <-stop:
+			return
+		case inbound <- msg:
+		}
+	}
+}
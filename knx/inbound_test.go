@@ -0,0 +1,104 @@
+// Licensed under the MIT license which can be found in the LICENSE file.
+
+package knx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/LB-00/knx-go/knx/cemi"
+)
+
+func TestInboundRing(t *testing.T) {
+	// Overrunning the buffer discards the oldest messages.
+	t.Run("Overrun", func(t *testing.T) {
+		ring := newInboundRing(4)
+
+		for i := 0; i < 10; i++ {
+			ring.push(&cemi.LDataInd{LData: cemi.LData{Destination: uint16(i)}})
+		}
+
+		if dropped := ring.droppedCount(); dropped != 6 {
+			t.Fatalf("Unexpected drop count: %d", dropped)
+		}
+
+		for i := 6; i < 10; i++ {
+			msg, ok := ring.pop()
+			if !ok {
+				t.Fatal("Expected a buffered message")
+			}
+
+			if dst := msg.(*cemi.LDataInd).Destination; dst != uint16(i) {
+				t.Errorf("Unexpected message order: %d != %d", dst, i)
+			}
+		}
+
+		if _, ok := ring.pop(); ok {
+			t.Fatal("Buffer should be empty")
+		}
+	})
+
+	// The relay forwards buffered messages to the inbound channel.
+	t.Run("Relay", func(t *testing.T) {
+		ring := newInboundRing(4)
+		inbound := make(chan cemi.Message)
+		stop := make(chan struct{})
+		defer close(stop)
+
+		go ring.serve(inbound, stop)
+
+		ring.push(&cemi.LDataInd{})
+
+		select {
+		case <-inbound:
+		case <-time.After(time.Second):
+			t.Fatal("Message has not been relayed")
+		}
+	})
+}
+
+func TestTunnel_DroppedCount(t *testing.T) {
+	client, gateway := newDummySockets()
+	defer client.Close()
+	defer gateway.Close()
+
+	conn := makeTunnelConn(client, DefaultTunnelConfig, 1)
+	conn.ring = newInboundRing(8)
+
+	for i := 0; i < 10; i++ {
+		conn.pushInbound(&cemi.LDataInd{})
+	}
+
+	if dropped := conn.DroppedCount(); dropped != 2 {
+		t.Fatalf("Unexpected drop count: %d", dropped)
+	}
+}
+
+func BenchmarkTunnel_pushInbound(b *testing.B) {
+	b.ReportAllocs()
+
+	conn := &Tunnel{
+		inbound: make(chan cemi.Message),
+		ring:    newInboundRing(256),
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go conn.ring.serve(conn.inbound, stop)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-conn.inbound:
+			}
+		}
+	}()
+
+	msg := &cemi.LDataInd{}
+
+	for i := 0; i < b.N; i++ {
+		conn.pushInbound(msg)
+	}
+}
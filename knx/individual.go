@@ -0,0 +1,78 @@
+// Licensed under the MIT license which can be found in the LICENSE file.
+
+package knx
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/LB-00/knx-go/knx/cemi"
+)
+
+// SendIndividual sends a request to the device without establishing a transport layer connection
+// (T_Data_Individual) and waits up to timeout for a response with the expected command. Neither
+// the request nor the response is numbered or acknowledged, which makes this faster than a
+// P2PConnection for one-shot procedures like A_DeviceDescriptor_Read. Like AppData.Data, the first
+// octet of data shares its lower 6 bits with the APCI. Procedures which require a connection, see
+// cemi.RequiresConnection, must use a P2PConnection instead.
+func SendIndividual(
+	tunnel *Tunnel,
+	dst cemi.IndividualAddr,
+	cmd cemi.APCI,
+	data []byte,
+	exp cemi.APCI,
+	timeout time.Duration,
+) (cemi.Message, error) {
+	if !cemi.IsValidIndividualAddr(dst) {
+		return nil, fmt.Errorf("invalid target address %s", dst)
+	}
+
+	req := &cemi.LDataReq{
+		LData: cemi.LData{
+			Control1:    cemi.Control1StdFrame | cemi.Control1NoRepeat | cemi.Control1NoSysBroadcast,
+			Control2:    cemi.Control2Hops(6),
+			Source:      tunnel.SourceAddr(),
+			Destination: uint16(dst),
+			Data: &cemi.AppData{
+				Command: cmd,
+				Data:    data,
+			},
+		},
+	}
+
+	// Watch for the response before sending the request, so that it cannot be missed.
+	w := tunnel.watch(func(msg cemi.Message) bool {
+		return matchIndividualResponse(msg, tunnel.SourceAddr(), dst, exp)
+	})
+	defer tunnel.unwatch(w)
+
+	if err := tunnel.Send(req); err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-time.After(timeout):
+		return nil, errors.New("timed out while waiting for individual response")
+
+	case msg, open := <-w.ch:
+		if !open {
+			return nil, errors.New("tunnel was closed before an individual response was received")
+		}
+
+		return msg, nil
+	}
+}
+
+// matchIndividualResponse reports whether the message is a connectionless response of the device
+// to the tunnel with the expected command.
+func matchIndividualResponse(msg cemi.Message, self, src cemi.IndividualAddr, exp cemi.APCI) bool {
+	ind, ok := msg.(*cemi.LDataInd)
+	if !ok || ind.LData.Control2.IsGroupAddr() ||
+		ind.LData.Source != src || ind.LData.Destination != uint16(self) {
+		return false
+	}
+
+	app, ok := ind.LData.Data.(*cemi.AppData)
+	return ok && !app.Numbered && app.Command == exp
+}
@@ -0,0 +1,75 @@
+// Licensed under the MIT license which can be found in the LICENSE file.
+
+package knx
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/LB-00/knx-go/knx/cemi"
+)
+
+func TestSendIndividual(t *testing.T) {
+	addr := cemi.NewIndividualAddr3(1, 1, 5)
+
+	_, tunnel, sent := makeRecordingManagement(t)
+
+	// The device answers the device descriptor read without a connection. A numbered response,
+	// one of another device and one to another client must be ignored.
+	go func() {
+		req := <-sent
+
+		app, ok := req.Data.(*cemi.AppData)
+		if !ok || app.Numbered || app.Command != cemi.MaskVersionRead || cemi.IndividualAddr(req.Destination) != addr {
+			t.Errorf("Unexpected request %+v", req.LData)
+			return
+		}
+
+		self := uint16(tunnel.SourceAddr())
+		tunnel.pushInbound(&cemi.LDataInd{LData: cemi.LData{
+			Source:      addr,
+			Destination: self,
+			Data:        &cemi.AppData{Numbered: true, Command: cemi.MaskVersionResponse, Data: []byte{0, 0x00, 0x10}},
+		}})
+		tunnel.pushInbound(&cemi.LDataInd{LData: cemi.LData{
+			Source:      cemi.NewIndividualAddr3(1, 1, 6),
+			Destination: self,
+			Data:        &cemi.AppData{Command: cemi.MaskVersionResponse, Data: []byte{0, 0x00, 0x12}},
+		}})
+		tunnel.pushInbound(&cemi.LDataInd{LData: cemi.LData{
+			Source:      addr,
+			Destination: uint16(cemi.NewIndividualAddr3(1, 1, 250)),
+			Data:        &cemi.AppData{Command: cemi.MaskVersionResponse, Data: []byte{0, 0x00, 0x14}},
+		}})
+		tunnel.pushInbound(&cemi.LDataInd{LData: cemi.LData{
+			Source:      addr,
+			Destination: self,
+			Data:        &cemi.AppData{Command: cemi.MaskVersionResponse, Data: []byte{0, 0x07, 0xb0}},
+		}})
+	}()
+
+	res, err := SendIndividual(tunnel, addr, cemi.MaskVersionRead, []byte{0}, cemi.MaskVersionResponse, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := res.(*cemi.LDataInd).LData.Data.(*cemi.AppData).Data
+	if !bytes.Equal(data, []byte{0, 0x07, 0xb0}) {
+		t.Errorf("Unexpected response %x", data)
+	}
+
+	// The telegrams are not taken away from the consumers of the tunnel.
+	for i := 0; i < 4; i++ {
+		select {
+		case <-tunnel.Inbound():
+		case <-time.After(time.Second):
+			t.Fatal("Telegram did not arrive at the inbound channel")
+		}
+	}
+
+	// Nothing is answered.
+	if _, err := SendIndividual(tunnel, addr, cemi.MaskVersionRead, []byte{0}, cemi.MaskVersionResponse, 50*time.Millisecond); err == nil {
+		t.Error("Should time out")
+	}
+}
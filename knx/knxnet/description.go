@@ -6,7 +6,7 @@ package knxnet
 import (
 	"net"
 
-	"github.com/LB-00/knx-go/knx/util"
+	"github.com/LB-00/knx-go/knx/cemi"
 )
 
 // NewDescriptionReq creates a new Description Request, addr defines where
@@ -43,15 +43,52 @@ func (DescriptionRes) Service() ServiceID {
 
 // Size returns the packed size of a Description Response.
 func (res DescriptionRes) Size() uint {
-	return res.DeviceHardware.Size() + res.SupportedServices.Size()
+	return DescriptionBlock(res).Size()
 }
 
 // Pack assembles the Description Response structure in the given buffer.
 func (res *DescriptionRes) Pack(buffer []byte) {
-	util.PackSome(buffer, res.DeviceHardware, res.SupportedServices)
+	(*DescriptionBlock)(res).Pack(buffer)
 }
 
-// Unpack parses the given service payload in order to initialize the Description Response.
+// Unpack parses the given service payload in order to initialize the Description Response. Only
+// the Device Information DIB and the Supported Service Families DIB are required, use Has to find
+// out which optional DIBs the server has sent.
 func (res *DescriptionRes) Unpack(data []byte) (n uint, err error) {
 	return (*DescriptionBlock)(res).Unpack(data)
 }
+
+// Has reports whether the response contains a DIB of the given type.
+func (res *DescriptionRes) Has(ty DescriptionType) bool {
+	return (*DescriptionBlock)(res).Has(ty)
+}
+
+// FriendlyName returns the friendly name of the device.
+func (res *DescriptionRes) FriendlyName() string {
+	return (*DescriptionBlock)(res).FriendlyName()
+}
+
+// Medium returns the KNX medium the device is attached to.
+func (res *DescriptionRes) Medium() KNXMedium {
+	return (*DescriptionBlock)(res).Medium()
+}
+
+// SupportedFamilies returns the service families supported by the device.
+func (res *DescriptionRes) SupportedFamilies() []ServiceFamily {
+	return (*DescriptionBlock)(res).SupportedFamilies()
+}
+
+// TunnellingSlots returns the tunnelling slots of the device, if it reports any.
+func (res *DescriptionRes) TunnellingSlots() []TunnellingSlot {
+	return (*DescriptionBlock)(res).TunnellingSlots()
+}
+
+// AdditionalAddresses returns the additional individual addresses of the device.
+func (res *DescriptionRes) AdditionalAddresses() []cemi.IndividualAddr {
+	return (*DescriptionBlock)(res).AdditionalAddresses()
+}
+
+// SecureStatus summarizes the KNX IP Secure capabilities of the device.
+func (res *DescriptionRes) SecureStatus() SecureStatus {
+	return (*DescriptionBlock)(res).SecureStatus()
+}
@@ -0,0 +1,178 @@
+// Licensed under the MIT license which can be found in the LICENSE file.
+
+package knxnet
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/LB-00/knx-go/knx/cemi"
+	"github.com/LB-00/knx-go/knx/util"
+)
+
+// descriptionResFrame is a description response as sent by a KNX IP interface with two
+// tunnelling slots.
+var descriptionResFrame = []byte{
+	0x06, 0x10, 0x02, 0x04, 0x00, 0x50,
+
+	// Device information
+	0x36, 0x01, 0x02, 0x00, 0x11, 0x00, 0x00, 0x00,
+	0x00, 0x83, 0x49, 0x7f, 0x01, 0xec,
+	0xe0, 0x00, 0x17, 0x0c,
+	0x00, 0x75, 0x4f, 0x12, 0x34, 0x56,
+	'K', 'N', 'X', ' ', 'I', 'P', ' ', 'I', 'n', 't', 'e', 'r', 'f', 'a', 'c', 'e',
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+
+	// Supported service families
+	0x08, 0x02, 0x02, 0x02, 0x03, 0x02, 0x04, 0x02,
+
+	// Tunnelling information
+	0x0c, 0x07, 0x00, 0xfe, 0x11, 0x01, 0x00, 0x05, 0x11, 0x02, 0x00, 0x06,
+}
+
+func TestDescriptionRes_Accessors(t *testing.T) {
+	var srv Service
+	if _, err := Unpack(descriptionResFrame, &srv); err != nil {
+		t.Fatal(err)
+	}
+
+	res, ok := srv.(*DescriptionRes)
+	if !ok {
+		t.Fatalf("Unexpected service %T", srv)
+	}
+
+	if name := res.FriendlyName(); name != "KNX IP Interface" {
+		t.Errorf("Unexpected friendly name %q", name)
+	}
+
+	if medium := res.Medium(); medium != KNXMediumTP1 {
+		t.Errorf("Unexpected medium %v", medium)
+	}
+
+	families := res.SupportedFamilies()
+	if len(families) != 3 || families[2] != (ServiceFamily{Type: ServiceFamilyTypeIPTunnelling, Version: 2}) {
+		t.Errorf("Unexpected service families %v", families)
+	}
+
+	slots := res.TunnellingSlots()
+	if len(slots) != 2 ||
+		slots[0] != (TunnellingSlot{Addr: cemi.NewIndividualAddr3(1, 1, 1), Status: 0x05}) ||
+		slots[1] != (TunnellingSlot{Addr: cemi.NewIndividualAddr3(1, 1, 2), Status: 0x06}) {
+		t.Errorf("Unexpected tunnelling slots %v", slots)
+	}
+
+	// The accessors return copies.
+	slots[0].Status = 0
+	if res.TunnellingInfo.Slots[0].Status != 0x05 {
+		t.Error("Tunnelling slots are shared with the description")
+	}
+
+	if status := res.SecureStatus(); status != (SecureStatus{}) {
+		t.Errorf("Unexpected secure status %v", status)
+	}
+
+	if addrs := res.AdditionalAddresses(); addrs != nil {
+		t.Errorf("Unexpected additional addresses %v", addrs)
+	}
+}
+
+func TestDescriptionRes_AccessorsEmpty(t *testing.T) {
+	var res DescriptionRes
+
+	if res.FriendlyName() != "" || res.SupportedFamilies() != nil || res.TunnellingSlots() != nil {
+		t.Error("Empty description should have no values")
+	}
+}
+
+// unpackDescriptionRes packs the DIBs into a description response frame and unpacks it.
+func unpackDescriptionRes(t *testing.T, dibs ...util.Packable) (*DescriptionRes, error) {
+	t.Helper()
+
+	data := append([]byte{0x06, 0x10, 0x02, 0x04, 0x00, 0x00}, util.AllocAndPack(dibs...)...)
+	util.Pack(data[4:], uint16(len(data)))
+
+	var srv Service
+	if _, err := Unpack(data, &srv); err != nil {
+		return nil, err
+	}
+
+	res, ok := srv.(*DescriptionRes)
+	if !ok {
+		t.Fatalf("Unexpected service %T", srv)
+	}
+
+	return res, nil
+}
+
+func TestDescriptionRes_OptionalDIBs(t *testing.T) {
+	device := &DeviceInformationBlock{
+		Type:         DescriptionTypeDeviceInfo,
+		Medium:       KNXMediumTP1,
+		HardwareAddr: make([]byte, 6),
+		FriendlyName: "KNX IP Router",
+	}
+	services := &SupportedServicesDIB{
+		Type:     DescriptionTypeSupportedServiceFamilies,
+		Families: []ServiceFamily{{Type: ServiceFamilyTypeIPCore, Version: 2}},
+	}
+
+	t.Run("Minimal", func(t *testing.T) {
+		res, err := unpackDescriptionRes(t, device, services)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if res.FriendlyName() != "KNX IP Router" || len(res.SupportedFamilies()) != 1 {
+			t.Errorf("Unexpected description %+v", res)
+		}
+
+		types := (*DescriptionBlock)(res).DescriptionTypes()
+		if !reflect.DeepEqual(types, standardDescriptionTypes[:2]) {
+			t.Errorf("Unexpected DIBs %v", types)
+		}
+
+		for _, ty := range standardDescriptionTypes[2:] {
+			if res.Has(ty) {
+				t.Errorf("DIB %v should be absent", ty)
+			}
+		}
+	})
+
+	t.Run("Complete", func(t *testing.T) {
+		res, err := unpackDescriptionRes(t,
+			device,
+			services,
+			&IPConfigDIB{Type: DescriptionTypeIPConfig, IP: Address{192, 168, 1, 10}},
+			&IPCurrentConfigDIB{Type: DescriptionTypeIPCurrentConfig, IP: Address{192, 168, 1, 10}},
+			&KNXAddrsDIB{Type: DescriptionTypeKNXAddresses, KNXAddrs: []cemi.IndividualAddr{0x1100}},
+			&SecuredServicesDIB{
+				Type:     DescriptionTypeSecuredServiceFamilies,
+				Families: []ServiceFamily{{Type: ServiceFamilyTypeIPTunnelling, Version: 1}},
+			},
+			&TunnellingInfoDIB{Type: DescriptionTypeTunnellingInfo, APDUSize: 254},
+			&ExtendedDeviceInfoDIB{Type: DescriptionTypeExtendedDeviceInfo, APDUSize: 254},
+			&ManufacturerDataDIB{Type: DescriptionTypeManufacturerData, ID: 0x00c5},
+			&UnknownService{Data: []byte{0x02, 0x42}},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, ty := range standardDescriptionTypes {
+			if !res.Has(ty) {
+				t.Errorf("DIB %v should be present", ty)
+			}
+		}
+
+		if !res.Has(0x42) {
+			t.Error("Unknown DIB should be present")
+		}
+	})
+
+	t.Run("MissingMandatory", func(t *testing.T) {
+		if _, err := unpackDescriptionRes(t, device); !errors.Is(err, ErrMissingMandatoryDIB) {
+			t.Errorf("Unexpected error %v", err)
+		}
+	})
+}
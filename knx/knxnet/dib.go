@@ -5,7 +5,13 @@ package knxnet
 import (
 	"errors"
 	"fmt"
+	"io"
+	"math"
 	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/LB-00/knx-go/knx/cemi"
 	"github.com/LB-00/knx-go/knx/util"
@@ -15,6 +21,127 @@ const (
 	friendlyNameMaxLen = 30
 )
 
+var tolerantDIBUnpack int32
+
+// SetTolerantDIBUnpack enables a tolerant unpacking mode for variable-length DIBs. Some devices
+// append padding or vendor extensions to these DIBs which do not form a complete entry. In strict
+// mode, which is the default, such bytes cause an error. In tolerant mode, they are preserved in
+// the Trailing field of the DIB instead.
+func SetTolerantDIBUnpack(enabled bool) {
+	var value int32
+	if enabled {
+		value = 1
+	}
+
+	atomic.StoreInt32(&tolerantDIBUnpack, value)
+}
+
+// TolerantDIBUnpack reports whether the tolerant unpacking mode for variable-length DIBs is
+// enabled.
+func TolerantDIBUnpack() bool {
+	return atomic.LoadInt32(&tolerantDIBUnpack) == 1
+}
+
+const (
+	// DefaultMaxDescriptionSize is the default limit for the number of bytes which are parsed as
+	// DIBs from a single description or extended search response.
+	DefaultMaxDescriptionSize = 1024
+
+	// DefaultMaxDescriptionBlocks is the default limit for the number of DIBs which are parsed from
+	// a single description or extended search response.
+	DefaultMaxDescriptionBlocks = 32
+)
+
+var (
+	maxDescriptionSize   uint32 = DefaultMaxDescriptionSize
+	maxDescriptionBlocks uint32 = DefaultMaxDescriptionBlocks
+)
+
+// SetMaxDescriptionSize limits the number of bytes which are parsed as DIBs from a single
+// description or extended search response. Zero disables the limit.
+func SetMaxDescriptionSize(size uint) {
+	atomic.StoreUint32(&maxDescriptionSize, clampUint32(size))
+}
+
+// MaxDescriptionSize returns the current limit for the size of descriptions.
+func MaxDescriptionSize() uint {
+	return uint(atomic.LoadUint32(&maxDescriptionSize))
+}
+
+// SetMaxDescriptionBlocks limits the number of DIBs which are parsed from a single description or
+// extended search response. Zero disables the limit.
+func SetMaxDescriptionBlocks(blocks uint) {
+	atomic.StoreUint32(&maxDescriptionBlocks, clampUint32(blocks))
+}
+
+// MaxDescriptionBlocks returns the current limit for the number of DIBs in descriptions.
+func MaxDescriptionBlocks() uint {
+	return uint(atomic.LoadUint32(&maxDescriptionBlocks))
+}
+
+// clampUint32 limits the value to the range of uint32.
+func clampUint32(value uint) uint32 {
+	if uint64(value) > math.MaxUint32 {
+		return math.MaxUint32
+	}
+
+	return uint32(value)
+}
+
+// ErrDescriptionTooLarge indicates that a description exceeds MaxDescriptionSize or
+// MaxDescriptionBlocks.
+var ErrDescriptionTooLarge = errors.New("description exceeds the configured limits")
+
+// checkDescriptionSize verifies that the DIBs in data do not exceed MaxDescriptionSize.
+func checkDescriptionSize(data []byte) error {
+	if max := MaxDescriptionSize(); max > 0 && uint(len(data)) > max {
+		return ErrDescriptionTooLarge
+	}
+
+	return nil
+}
+
+// ErrDIBTruncated indicates that a DIB declares more bytes than the data contains. It wraps
+// io.ErrUnexpectedEOF.
+var ErrDIBTruncated = fmt.Errorf("DIB length exceeds remaining buffer: %w", io.ErrUnexpectedEOF)
+
+// nextDIB reads the header of the DIB at offset n. count is the number of DIBs read so far.
+func nextDIB(data []byte, n uint, count int) (uint8, DescriptionType, error) {
+	if max := MaxDescriptionBlocks(); max > 0 && uint(count) >= max {
+		return 0, 0, ErrDescriptionTooLarge
+	}
+
+	// DIBs should always have a length and a type. They are read directly, as passing them to
+	// UnpackSome would move them to the heap.
+	if uint(len(data))-n < 2 {
+		return 0, 0, io.ErrUnexpectedEOF
+	}
+
+	length := data[n]
+	if length < 2 {
+		return 0, 0, errors.New("invalid DIB length")
+	}
+
+	if n+uint(length) > uint(len(data)) {
+		return 0, 0, fmt.Errorf("%w: DIB %v declares %d bytes, but only %d remain",
+			ErrDIBTruncated, DescriptionType(data[n+1]), length, uint(len(data))-n)
+	}
+
+	return length, DescriptionType(data[n+1]), nil
+}
+
+// unpackTrailing preserves the bytes between n and the declared length of a DIB.
+func unpackTrailing(data []byte, n uint, length uint8, trailing *[]byte) (uint, error) {
+	if uint(len(data)) < uint(length) {
+		return n, io.ErrUnexpectedEOF
+	}
+
+	*trailing = make([]byte, uint(length)-n)
+	copy(*trailing, data[n:length])
+
+	return uint(length), nil
+}
+
 // DescriptionType describes the type of a DeviceInformationBlock.
 type DescriptionType uint8
 
@@ -47,6 +174,55 @@ const (
 	DescriptionTypeManufacturerData DescriptionType = 0xfe
 )
 
+// String returns the name of the description type.
+func (ty DescriptionType) String() string {
+	switch ty {
+	case DescriptionTypeDeviceInfo:
+		return "DeviceInfo"
+
+	case DescriptionTypeSupportedServiceFamilies:
+		return "SupportedServiceFamilies"
+
+	case DescriptionTypeIPConfig:
+		return "IPConfig"
+
+	case DescriptionTypeIPCurrentConfig:
+		return "IPCurrentConfig"
+
+	case DescriptionTypeKNXAddresses:
+		return "KNXAddresses"
+
+	case DescriptionTypeSecuredServiceFamilies:
+		return "SecuredServiceFamilies"
+
+	case DescriptionTypeTunnellingInfo:
+		return "TunnellingInfo"
+
+	case DescriptionTypeExtendedDeviceInfo:
+		return "ExtendedDeviceInfo"
+
+	case DescriptionTypeManufacturerData:
+		return "ManufacturerData"
+
+	default:
+		return fmt.Sprintf("unknown(0x%02x)", uint8(ty))
+	}
+}
+
+// standardDescriptionTypes lists the standard description types in canonical order. The mandatory
+// Device Information and Supported Service Families DIBs come first.
+var standardDescriptionTypes = []DescriptionType{
+	DescriptionTypeDeviceInfo,
+	DescriptionTypeSupportedServiceFamilies,
+	DescriptionTypeIPConfig,
+	DescriptionTypeIPCurrentConfig,
+	DescriptionTypeKNXAddresses,
+	DescriptionTypeSecuredServiceFamilies,
+	DescriptionTypeTunnellingInfo,
+	DescriptionTypeExtendedDeviceInfo,
+	DescriptionTypeManufacturerData,
+}
+
 // KNXMedium describes the KNX medium type.
 type KNXMedium uint8
 
@@ -61,15 +237,92 @@ const (
 	KNXMediumIP KNXMedium = 0x20
 )
 
-// ProjectInstallationIdentifier describes a KNX project installation identifier.
+// IsKnown determines if the medium is one of the known KNX media.
+func (medium KNXMedium) IsKnown() bool {
+	switch medium {
+	case KNXMediumTP1, KNXMediumPL110, KNXMediumRF, KNXMediumIP:
+		return true
+	}
+
+	return false
+}
+
+// String converts the medium to a string.
+func (medium KNXMedium) String() string {
+	switch medium {
+	case KNXMediumTP1:
+		return "TP1"
+
+	case KNXMediumPL110:
+		return "PL110"
+
+	case KNXMediumRF:
+		return "RF"
+
+	case KNXMediumIP:
+		return "IP"
+
+	default:
+		return fmt.Sprintf("unknown(0x%02x)", uint8(medium))
+	}
+}
+
+// ProjectInstallationIdentifier describes a KNX project installation identifier. The upper 12 bits
+// hold the project number and the lower 4 bits hold the installation number.
 type ProjectInstallationIdentifier uint16
 
+// NewProjectInstallationIdentifier creates an identifier from the project number, which must not
+// exceed 12 bits, and the installation number, which must not exceed 4 bits.
+func NewProjectInstallationIdentifier(project uint16, installation uint8) (ProjectInstallationIdentifier, error) {
+	if project > 0xfff {
+		return 0, fmt.Errorf("project number %d exceeds 12 bits", project)
+	}
+
+	if installation > 0xf {
+		return 0, fmt.Errorf("installation number %d exceeds 4 bits", installation)
+	}
+
+	return ProjectInstallationIdentifier(project<<4 | uint16(installation)), nil
+}
+
+// ProjectNumber returns the project number.
+func (id ProjectInstallationIdentifier) ProjectNumber() uint16 {
+	return uint16(id) >> 4
+}
+
+// InstallationNumber returns the installation number.
+func (id ProjectInstallationIdentifier) InstallationNumber() uint8 {
+	return uint8(id & 0xf)
+}
+
 // DeviceStatus describes the device status.
 type DeviceStatus uint8
 
-// DeviceSerialNumber desribes the serial number of a device.
+// deviceStatusProgMode is the bit of the device status which indicates the programming mode.
+const deviceStatusProgMode DeviceStatus = 0x01
+
+// ProgrammingMode reports whether the device is in programming mode.
+func (status DeviceStatus) ProgrammingMode() bool {
+	return status&deviceStatusProgMode != 0
+}
+
+// SetProgrammingMode sets or clears the programming mode flag, leaving the other bits untouched.
+func (status *DeviceStatus) SetProgrammingMode(enabled bool) {
+	if enabled {
+		*status |= deviceStatusProgMode
+	} else {
+		*status &^= deviceStatusProgMode
+	}
+}
+
+// DeviceSerialNumber describes the serial number of a device.
 type DeviceSerialNumber [6]byte
 
+// String formats the serial number as colon-separated hexadecimal bytes, e.g. 00:fa:12:34:56:78.
+func (sn DeviceSerialNumber) String() string {
+	return net.HardwareAddr(sn[:]).String()
+}
+
 // DeviceInformationBlock contains information about a device.
 type DeviceInformationBlock struct {
 	Type                    DescriptionType
@@ -88,10 +341,23 @@ func (DeviceInformationBlock) Size() uint {
 	return 54
 }
 
-// Pack assembles the device information structure in the given buffer.
+// CheckFriendlyName verifies that the name fits into the 30 bytes of the friendly name field and
+// consists only of ISO 8859-1 characters. A name of exactly 30 bytes is valid, it is not
+// terminated by a NUL byte.
+func CheckFriendlyName(name string) error {
+	var buffer [friendlyNameMaxLen]byte
+
+	_, err := util.PackString(buffer[:], friendlyNameMaxLen, name)
+	return err
+}
+
+// Pack assembles the device information structure in the given buffer. A friendly name which does
+// not pass CheckFriendlyName is logged and packed as far as possible: it is truncated after the
+// last character that fits, and characters outside of ISO 8859-1 are replaced by '?'.
 func (dib *DeviceInformationBlock) Pack(buffer []byte) {
-	buf := make([]byte, friendlyNameMaxLen)
-	util.PackString(buf, friendlyNameMaxLen, dib.FriendlyName)
+	if dib == nil {
+		return
+	}
 
 	util.PackSome(
 		buffer,
@@ -102,8 +368,15 @@ func (dib *DeviceInformationBlock) Pack(buffer []byte) {
 		dib.SerialNumber[:],
 		dib.RoutingMulticastAddress[:],
 		[]byte(dib.HardwareAddr),
-		buf,
 	)
+
+	checkRoutingMulticastAddress(dib)
+
+	// The friendly name is packed in place to avoid a scratch buffer.
+	_, err := util.PackString(buffer[dib.Size()-friendlyNameMaxLen:], friendlyNameMaxLen, dib.FriendlyName)
+	if err != nil {
+		util.Log(dib, "Invalid friendly name: %v", err)
+	}
 }
 
 // Unpack parses the given data in order to initialize the structure.
@@ -124,23 +397,36 @@ func (dib *DeviceInformationBlock) Unpack(data []byte) (n uint, err error) {
 		return
 	}
 
+	// A structure which is too short must not cut the friendly name.
+	if length != uint8(dib.Size()) || uint(len(data)) < dib.Size() {
+		return n, errors.New("device info structure length is invalid")
+	}
+
 	nn, err := util.UnpackString(data[n:], friendlyNameMaxLen, &dib.FriendlyName)
 	if err != nil {
 		return n, err
 	}
 	n += nn
 
-	if length != uint8(dib.Size()) {
-		return n, errors.New("device info structure length is invalid")
-	}
+	checkRoutingMulticastAddress(dib)
 
 	return
 }
 
+// checkRoutingMulticastAddress logs a routing multicast address which is not a multicast address.
+// Devices without routing support report 0.0.0.0, which is fine.
+func checkRoutingMulticastAddress(dib *DeviceInformationBlock) {
+	addr := dib.RoutingMulticastAddress
+	if !addr.IsUnspecified() && !addr.IsMulticast() {
+		util.Log(dib, "Routing multicast address %v is not a multicast address", addr)
+	}
+}
+
 // SupportedServicesDIB contains information about the supported services of a device.
 type SupportedServicesDIB struct {
 	Type     DescriptionType
 	Families []ServiceFamily
+	Trailing []byte
 }
 
 // Size returns the packed size.
@@ -150,11 +436,15 @@ func (sdib SupportedServicesDIB) Size() uint {
 		size += f.Size()
 	}
 
-	return size
+	return size + uint(len(sdib.Trailing))
 }
 
 // Pack assembles the supported services structure in the given buffer.
 func (sdib *SupportedServicesDIB) Pack(buffer []byte) {
+	if sdib == nil {
+		return
+	}
+
 	util.PackSome(
 		buffer,
 		uint8(sdib.Size()), uint8(sdib.Type),
@@ -165,6 +455,8 @@ func (sdib *SupportedServicesDIB) Pack(buffer []byte) {
 		f.Pack(buffer[offset:])
 		offset += f.Size()
 	}
+
+	copy(buffer[offset:], sdib.Trailing)
 }
 
 // Unpack parses the given data in order to initialize the structure.
@@ -178,6 +470,13 @@ func (sdib *SupportedServicesDIB) Unpack(data []byte) (n uint, err error) {
 	}
 
 	for n < uint(length) {
+		if TolerantDIBUnpack() && uint(length)-n < serviceFamilySize {
+			if n, err = unpackTrailing(data, n, length, &sdib.Trailing); err != nil {
+				return
+			}
+			break
+		}
+
 		f := ServiceFamily{}
 		nn, err := f.Unpack(data[n:])
 		if err != nil {
@@ -212,6 +511,10 @@ func (IPConfigDIB) Size() uint {
 
 // Pack assembles the IP configuration structure in the given buffer.
 func (idib *IPConfigDIB) Pack(buffer []byte) {
+	if idib == nil {
+		return
+	}
+
 	util.PackSome(
 		buffer,
 		uint8(idib.Size()), uint8(idib.Type),
@@ -239,6 +542,58 @@ func (idib *IPConfigDIB) Unpack(data []byte) (n uint, err error) {
 	return
 }
 
+// These are the bits of the IP capabilities of a device.
+const (
+	IPCapabilityBootP  uint8 = 1 << 0
+	IPCapabilityDHCP   uint8 = 1 << 1
+	IPCapabilityAutoIP uint8 = 1 << 2
+)
+
+// These are the bits of the IP assignment methods of a device.
+const (
+	IPAssignmentManual uint8 = 1 << 0
+	IPAssignmentBootP  uint8 = 1 << 1
+	IPAssignmentDHCP   uint8 = 1 << 2
+	IPAssignmentAutoIP uint8 = 1 << 3
+)
+
+// ipAssignmentNames lists the names of the IP assignment methods in the order of their bits.
+var ipAssignmentNames = []string{"manual", "BootP", "DHCP", "AutoIP"}
+
+// assignmentMethods returns the names of the methods which are set in the given IP assignment.
+func assignmentMethods(assignment uint8) []string {
+	var methods []string
+	for bit, name := range ipAssignmentNames {
+		if assignment&(1<<bit) != 0 {
+			methods = append(methods, name)
+		}
+	}
+
+	return methods
+}
+
+// SupportsBootP reports whether the device is capable of obtaining its IP address via BootP.
+func (idib *IPConfigDIB) SupportsBootP() bool {
+	return idib.IPCapabilities&IPCapabilityBootP != 0
+}
+
+// SupportsDHCP reports whether the device is capable of obtaining its IP address via DHCP.
+func (idib *IPConfigDIB) SupportsDHCP() bool {
+	return idib.IPCapabilities&IPCapabilityDHCP != 0
+}
+
+// SupportsAutoIP reports whether the device is capable of assigning itself a link-local IP
+// address.
+func (idib *IPConfigDIB) SupportsAutoIP() bool {
+	return idib.IPCapabilities&IPCapabilityAutoIP != 0
+}
+
+// AssignmentMethods returns the names of the IP assignment methods which are enabled on the
+// device, i.e. "manual", "BootP", "DHCP" and "AutoIP".
+func (idib *IPConfigDIB) AssignmentMethods() []string {
+	return assignmentMethods(idib.IPAssignment)
+}
+
 // IPCurrentConfigDIB contains information about the current IP configuration of a device.
 type IPCurrentConfigDIB struct {
 	Type         DescriptionType
@@ -257,6 +612,10 @@ func (IPCurrentConfigDIB) Size() uint {
 
 // Pack assembles the current IP configuration structure in the given buffer.
 func (idib *IPCurrentConfigDIB) Pack(buffer []byte) {
+	if idib == nil {
+		return
+	}
+
 	util.PackSome(
 		buffer,
 		uint8(idib.Size()), uint8(idib.Type),
@@ -286,19 +645,30 @@ func (idib *IPCurrentConfigDIB) Unpack(data []byte) (n uint, err error) {
 	return
 }
 
+// AssignmentMethods returns the name of the method by which the current IP address has been
+// assigned, see IPConfigDIB.AssignmentMethods.
+func (idib *IPCurrentConfigDIB) AssignmentMethods() []string {
+	return assignmentMethods(idib.IPAssignment)
+}
+
 // KNXAddrsDIB contains information about the individual KNX addresses of a device.
 type KNXAddrsDIB struct {
 	Type     DescriptionType
 	KNXAddrs []cemi.IndividualAddr
+	Trailing []byte
 }
 
 // Size returns the packed size.
 func (kdib KNXAddrsDIB) Size() uint {
-	return uint(2 + len(kdib.KNXAddrs)*2)
+	return uint(2 + len(kdib.KNXAddrs)*2 + len(kdib.Trailing))
 }
 
 // Pack assembles the KNX addresses structure in the given buffer.
 func (kdib *KNXAddrsDIB) Pack(buffer []byte) {
+	if kdib == nil {
+		return
+	}
+
 	util.PackSome(
 		buffer, uint8(kdib.Size()), uint8(kdib.Type),
 	)
@@ -308,6 +678,8 @@ func (kdib *KNXAddrsDIB) Pack(buffer []byte) {
 		util.PackSome(buffer[offset:], uint16(addr))
 		offset += 2
 	}
+
+	copy(buffer[offset:], kdib.Trailing)
 }
 
 // Unpack parses the given data in order to initialize the structure.
@@ -321,6 +693,13 @@ func (kdib *KNXAddrsDIB) Unpack(data []byte) (n uint, err error) {
 	}
 
 	for n < uint(length) {
+		if TolerantDIBUnpack() && uint(length)-n < 2 {
+			if n, err = unpackTrailing(data, n, length, &kdib.Trailing); err != nil {
+				return
+			}
+			break
+		}
+
 		var addr cemi.IndividualAddr
 		nn, err := util.UnpackSome(data[n:], (*uint16)(&addr))
 		if err != nil {
@@ -337,6 +716,16 @@ func (kdib *KNXAddrsDIB) Unpack(data []byte) (n uint, err error) {
 	return
 }
 
+// String formats the individual addresses of the structure.
+func (kdib KNXAddrsDIB) String() string {
+	addrs := make([]string, len(kdib.KNXAddrs))
+	for i, addr := range kdib.KNXAddrs {
+		addrs[i] = addr.String()
+	}
+
+	return strings.Join(addrs, ", ")
+}
+
 // ManufacturerDataDIB contains information about manufacturer-specific data.
 type ManufacturerDataDIB struct {
 	Type DescriptionType
@@ -351,6 +740,10 @@ func (mdib ManufacturerDataDIB) Size() uint {
 
 // Pack assembles the manufacturer data structure in the given buffer.
 func (mdib *ManufacturerDataDIB) Pack(buffer []byte) {
+	if mdib == nil {
+		return
+	}
+
 	util.PackSome(
 		buffer,
 		uint8(mdib.Size()), uint8(mdib.Type),
@@ -371,7 +764,12 @@ func (mdib *ManufacturerDataDIB) Unpack(data []byte) (n uint, err error) {
 		return
 	}
 
-	mdib.Data = data[n:]
+	// The data is copied, because the given buffer may be reused by the caller.
+	mdib.Data = nil
+	if uint(len(data)) > n {
+		mdib.Data = make([]byte, uint(len(data))-n)
+		n += uint(copy(mdib.Data, data[n:]))
+	}
 
 	if length != uint8(mdib.Size()) {
 		return n, errors.New("invalid length for Manufacturer Data structure")
@@ -384,6 +782,7 @@ func (mdib *ManufacturerDataDIB) Unpack(data []byte) (n uint, err error) {
 type SecuredServicesDIB struct {
 	Type     DescriptionType
 	Families []ServiceFamily
+	Trailing []byte
 }
 
 // Size returns the packed size.
@@ -393,11 +792,15 @@ func (sdib SecuredServicesDIB) Size() uint {
 		size += f.Size()
 	}
 
-	return size
+	return size + uint(len(sdib.Trailing))
 }
 
 // Pack assembles the supported services structure in the given buffer.
 func (sdib *SecuredServicesDIB) Pack(buffer []byte) {
+	if sdib == nil {
+		return
+	}
+
 	util.PackSome(
 		buffer,
 		uint8(sdib.Size()), uint8(sdib.Type),
@@ -408,6 +811,8 @@ func (sdib *SecuredServicesDIB) Pack(buffer []byte) {
 		f.Pack(buffer[offset:])
 		offset += f.Size()
 	}
+
+	copy(buffer[offset:], sdib.Trailing)
 }
 
 // Unpack parses the given data in order to initialize the structure.
@@ -421,6 +826,13 @@ func (sdib *SecuredServicesDIB) Unpack(data []byte) (n uint, err error) {
 	}
 
 	for n < uint(length) {
+		if TolerantDIBUnpack() && uint(length)-n < serviceFamilySize {
+			if n, err = unpackTrailing(data, n, length, &sdib.Trailing); err != nil {
+				return
+			}
+			break
+		}
+
 		f := ServiceFamily{}
 		nn, err := f.Unpack(data[n:])
 		if err != nil {
@@ -466,7 +878,7 @@ func (ts *TunnellingSlot) Unpack(data []byte) (n uint, err error) {
 	if err != nil {
 		return n, fmt.Errorf("unable to unpack TunnelingSlot: %w", err)
 	}
-	if ts.Addr == 0 {
+	if !cemi.IsValidIndividualAddr(ts.Addr) {
 		return n, fmt.Errorf("invalid TunnelingSlot address: %d", ts.Addr)
 	}
 	return n, nil
@@ -486,6 +898,10 @@ func (tdib TunnellingInfoDIB) Size() uint {
 
 // Pack assembles the tunnelling information structure in the given buffer.
 func (tdib *TunnellingInfoDIB) Pack(buffer []byte) {
+	if tdib == nil {
+		return
+	}
+
 	util.PackSome(
 		buffer,
 		uint8(tdib.Size()), uint8(tdib.Type),
@@ -518,7 +934,7 @@ func (tdib *TunnellingInfoDIB) Unpack(data []byte) (n uint, err error) {
 		}
 
 		n += nn
-		if s.Addr == 0 {
+		if !cemi.IsValidIndividualAddr(s.Addr) {
 			return n, fmt.Errorf("invalid tunneling slot address: %d", s.Addr)
 		}
 		tdib.Slots = append(tdib.Slots, s)
@@ -538,15 +954,30 @@ type ExtendedDeviceInfoDIB struct {
 	Reserved         uint8
 	APDUSize         uint16
 	DeviceDescriptor uint16
+
+	// RFDomainAddress is the 6 byte domain address of a KNX RF device. RF interfaces append it to
+	// the structure, it is empty for all other media.
+	RFDomainAddress []byte
 }
 
+// rfDomainAddressSize is the size of a KNX RF domain address.
+const rfDomainAddressSize = 6
+
 // Size returns the packed size.
-func (ExtendedDeviceInfoDIB) Size() uint {
+func (edib ExtendedDeviceInfoDIB) Size() uint {
+	if len(edib.RFDomainAddress) > 0 {
+		return 8 + rfDomainAddressSize
+	}
+
 	return 8
 }
 
 // Pack assembles the extended device information structure in the given buffer.
 func (edib *ExtendedDeviceInfoDIB) Pack(buffer []byte) {
+	if edib == nil {
+		return
+	}
+
 	util.PackSome(
 		buffer,
 		uint8(edib.Size()), uint8(edib.Type),
@@ -554,6 +985,10 @@ func (edib *ExtendedDeviceInfoDIB) Pack(buffer []byte) {
 		edib.APDUSize,
 		edib.DeviceDescriptor,
 	)
+
+	if len(edib.RFDomainAddress) > 0 {
+		copy(buffer[8:8+rfDomainAddressSize], edib.RFDomainAddress)
+	}
 }
 
 // Unpack parses the given data in order to initialize the structure.
@@ -569,13 +1004,84 @@ func (edib *ExtendedDeviceInfoDIB) Unpack(data []byte) (n uint, err error) {
 		return
 	}
 
-	if length != uint8(edib.Size()) {
+	edib.RFDomainAddress = nil
+
+	switch length {
+	case 8:
+	case 8 + rfDomainAddressSize:
+		if len(data) < int(length) {
+			return n, io.ErrUnexpectedEOF
+		}
+
+		edib.RFDomainAddress = make([]byte, rfDomainAddressSize)
+		n += uint(copy(edib.RFDomainAddress, data[n:length]))
+
+	default:
 		return n, errors.New("invalid length for Extended Device Info structure")
 	}
 
 	return
 }
 
+// Descriptor decodes the device descriptor of the device.
+func (edib *ExtendedDeviceInfoDIB) Descriptor() DeviceDescriptor0 {
+	return DeviceDescriptor0(edib.DeviceDescriptor)
+}
+
+// CommunicationPossible reports whether the device can communicate on its KNX medium. The lowest
+// bit of the medium status indicates a medium fault.
+func (edib *ExtendedDeviceInfoDIB) CommunicationPossible() bool {
+	return edib.MediumStatus&1 == 0
+}
+
+// DeviceDescriptor0 is a device descriptor type 0, also known as mask version. It consists of the
+// medium type, the firmware type, the firmware version and its subcode, each 4 bits wide.
+type DeviceDescriptor0 uint16
+
+// dd0Media maps the medium types of device descriptors to KNX media.
+var dd0Media = map[uint8]KNXMedium{
+	0: KNXMediumTP1,
+	1: KNXMediumPL110,
+	2: KNXMediumRF,
+	5: KNXMediumIP,
+}
+
+// MediumType returns the raw medium type of the descriptor.
+func (dd DeviceDescriptor0) MediumType() uint8 {
+	return uint8(dd>>12) & 0xF
+}
+
+// Medium returns the KNX medium of the descriptor. Unknown medium types yield zero.
+func (dd DeviceDescriptor0) Medium() KNXMedium {
+	return dd0Media[dd.MediumType()]
+}
+
+// FirmwareType returns the firmware type of the descriptor.
+func (dd DeviceDescriptor0) FirmwareType() uint8 {
+	return uint8(dd>>8) & 0xF
+}
+
+// Version returns the firmware version of the descriptor.
+func (dd DeviceDescriptor0) Version() uint8 {
+	return uint8(dd>>4) & 0xF
+}
+
+// Subcode returns the firmware subcode of the descriptor.
+func (dd DeviceDescriptor0) Subcode() uint8 {
+	return uint8(dd) & 0xF
+}
+
+// String formats the descriptor.
+func (dd DeviceDescriptor0) String() string {
+	medium := dd.Medium().String()
+	if !dd.Medium().IsKnown() {
+		medium = fmt.Sprintf("medium type %d", dd.MediumType())
+	}
+
+	return fmt.Sprintf("%04X (%s, firmware type %d, version %d.%d)",
+		uint16(dd), medium, dd.FirmwareType(), dd.Version(), dd.Subcode())
+}
+
 // ServiceFamilyType describes a KNXnet service family type.
 type ServiceFamilyType uint8
 
@@ -598,6 +1104,8 @@ const (
 	ServiceFamilyTypeIPSecure = 0x09
 )
 
+var serviceFamilySize = ServiceFamily{}.Size()
+
 // ServiceFamily describes a KNXnet service supported by a device.
 type ServiceFamily struct {
 	Type    ServiceFamilyType
@@ -622,6 +1130,73 @@ func (f *ServiceFamily) Unpack(data []byte) (n uint, err error) {
 	return util.UnpackSome(data, (*uint8)(&f.Type), &f.Version)
 }
 
+// dibFactory creates a DIB of a registered description type.
+type dibFactory struct {
+	create  func() DIB
+	builtin bool
+}
+
+// builtinDIBs maps the description types to factories for the DIBs this package implements.
+var builtinDIBs = map[DescriptionType]func() DIB{
+	DescriptionTypeDeviceInfo:               func() DIB { return &DeviceInformationBlock{} },
+	DescriptionTypeSupportedServiceFamilies: func() DIB { return &SupportedServicesDIB{} },
+	DescriptionTypeIPConfig:                 func() DIB { return &IPConfigDIB{} },
+	DescriptionTypeIPCurrentConfig:          func() DIB { return &IPCurrentConfigDIB{} },
+	DescriptionTypeKNXAddresses:             func() DIB { return &KNXAddrsDIB{} },
+	DescriptionTypeSecuredServiceFamilies:   func() DIB { return &SecuredServicesDIB{} },
+	DescriptionTypeTunnellingInfo:           func() DIB { return &TunnellingInfoDIB{} },
+	DescriptionTypeExtendedDeviceInfo:       func() DIB { return &ExtendedDeviceInfoDIB{} },
+	DescriptionTypeManufacturerData:         func() DIB { return &ManufacturerDataDIB{} },
+}
+
+// dibs maps the description types to factories for their DIBs.
+var dibs = func() map[DescriptionType]dibFactory {
+	factories := make(map[DescriptionType]dibFactory, len(builtinDIBs))
+	for t, create := range builtinDIBs {
+		factories[t] = dibFactory{create, true}
+	}
+
+	return factories
+}()
+
+var dibsMu sync.RWMutex
+
+// RegisterDIB registers a factory for DIBs of the given description type. DescriptionBlock and
+// SearchResExt use it to decode DIBs this package does not implement, e.g. vendor-specific ones.
+// DIBs decoded by registered factories end up in DescriptionBlock.ExtraBlocks instead of
+// DescriptionBlock.UnknownBlocks. Optional built-in DIBs may be replaced as well, whereas the
+// mandatory Device Information DIB and Supported Service Families DIB are refused, since
+// DescriptionBlock could not unpack any description without them. A nil factory removes the
+// registration and restores the built-in DIB, if there is one.
+func RegisterDIB(t DescriptionType, factory func() DIB) error {
+	if t == DescriptionTypeDeviceInfo || t == DescriptionTypeSupportedServiceFamilies {
+		return fmt.Errorf("mandatory DIB %v cannot be replaced", t)
+	}
+
+	dibsMu.Lock()
+	defer dibsMu.Unlock()
+
+	if factory != nil {
+		dibs[t] = dibFactory{create: factory}
+	} else if create, ok := builtinDIBs[t]; ok {
+		dibs[t] = dibFactory{create, true}
+	} else {
+		delete(dibs, t)
+	}
+
+	return nil
+}
+
+// lookupDIB returns the registered factory for the given description type and whether it is the
+// built-in one.
+func lookupDIB(t DescriptionType) (factory func() DIB, builtin bool, ok bool) {
+	dibsMu.RLock()
+	defer dibsMu.RUnlock()
+
+	f, ok := dibs[t]
+	return f.create, f.builtin, ok
+}
+
 // DescriptionBlock is returned by a Search Request, a Search Request Extended,
 // a Description Request or a Diagnostic Request. DIBs other than the Device
 // Information DIB and the Supported Service Families DIB are optional.
@@ -635,111 +1210,390 @@ type DescriptionBlock struct {
 	TunnellingInfo     TunnellingInfoDIB
 	ExtendedDeviceInfo ExtendedDeviceInfoDIB
 	ManufacturerData   ManufacturerDataDIB
+	ExtraBlocks        []DIB
+	DuplicateBlocks    []DIB // Further built-in DIBs of a type which has already been unpacked
 	UnknownBlocks      []UnknownDescriptionBlock
+	DIBErrors          []error // DIBs which have been skipped, see SetRecoverDIBPanics
+}
+
+// builtinDIB returns the field which holds the built-in DIB of the given type.
+func (di *DescriptionBlock) builtinDIB(ty DescriptionType) DIB {
+	switch ty {
+	case DescriptionTypeDeviceInfo:
+		return &di.DeviceHardware
+	case DescriptionTypeSupportedServiceFamilies:
+		return &di.SupportedServices
+	case DescriptionTypeIPConfig:
+		return &di.IPConfig
+	case DescriptionTypeIPCurrentConfig:
+		return &di.IPCurrentConfig
+	case DescriptionTypeKNXAddresses:
+		return &di.KNXAddrs
+	case DescriptionTypeSecuredServiceFamilies:
+		return &di.SecuredServices
+	case DescriptionTypeTunnellingInfo:
+		return &di.TunnellingInfo
+	case DescriptionTypeExtendedDeviceInfo:
+		return &di.ExtendedDeviceInfo
+	case DescriptionTypeManufacturerData:
+		return &di.ManufacturerData
+	}
+
+	return nil
+}
+
+// presentDIBs returns the DIBs which are part of the description. Built-in DIBs, including the
+// mandatory ones, are only included if their type is set. A description which lacks a mandatory
+// DIB is rejected when it is unpacked again, see checkMandatoryDIBs.
+func (di *DescriptionBlock) presentDIBs() []DIB {
+	var dibs []DIB
+
+	for _, ty := range standardDescriptionTypes {
+		if dib := di.builtinDIB(ty); dibType(dib) != 0 {
+			dibs = append(dibs, dib)
+		}
+	}
+
+	for _, dib := range di.ExtraBlocks {
+		if !isNilDIB(dib) {
+			dibs = append(dibs, dib)
+		}
+	}
+
+	for _, dib := range di.DuplicateBlocks {
+		if !isNilDIB(dib) {
+			dibs = append(dibs, dib)
+		}
+	}
+
+	return dibs
+}
+
+// Has reports whether the description contains a DIB of the given type. Optional DIBs which the
+// device has not sent are absent.
+func (di *DescriptionBlock) Has(ty DescriptionType) bool {
+	for _, present := range di.DescriptionTypes() {
+		if present == ty {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DescriptionTypes returns the types of all DIBs in the description, in the order in which Pack
+// assembles them.
+func (di *DescriptionBlock) DescriptionTypes() []DescriptionType {
+	dibs := di.presentDIBs()
+
+	types := make([]DescriptionType, 0, len(dibs)+len(di.UnknownBlocks))
+	for _, dib := range dibs {
+		types = append(types, dibType(dib))
+	}
+
+	for _, u := range di.UnknownBlocks {
+		types = append(types, u.Type)
+	}
+
+	return types
+}
+
+// Size returns the packed size of all DIBs in the description.
+func (di DescriptionBlock) Size() uint {
+	var size uint
+	for _, dib := range di.presentDIBs() {
+		size += dib.Size()
+	}
+
+	for _, u := range di.UnknownBlocks {
+		size += u.Size()
+	}
+
+	return size
+}
+
+// Pack assembles all DIBs in the description in the given buffer. The mandatory DIBs come first,
+// followed by the optional built-in DIBs whose type is set, the extra blocks, the duplicate blocks
+// and finally the unknown blocks. The result can be unpacked again, e.g. to emulate a KNXnet/IP
+// server in tests.
+func (di *DescriptionBlock) Pack(buffer []byte) {
+	var offset uint
+	for _, dib := range di.presentDIBs() {
+		dib.Pack(buffer[offset:])
+		offset += dib.Size()
+	}
+
+	for i := range di.UnknownBlocks {
+		di.UnknownBlocks[i].Pack(buffer[offset:])
+		offset += di.UnknownBlocks[i].Size()
+	}
+}
+
+// AdditionalAddresses returns the additional individual addresses of the device. The first
+// address of the KNX Addresses DIB is the individual address of the device itself, all following
+// addresses are the ones a tunnelling interface can assign to its connections.
+func (di *DescriptionBlock) AdditionalAddresses() []cemi.IndividualAddr {
+	if len(di.KNXAddrs.KNXAddrs) < 2 {
+		return nil
+	}
+
+	addrs := make([]cemi.IndividualAddr, len(di.KNXAddrs.KNXAddrs)-1)
+	copy(addrs, di.KNXAddrs.KNXAddrs[1:])
+
+	return addrs
+}
+
+// FriendlyName returns the friendly name of the device.
+func (di *DescriptionBlock) FriendlyName() string {
+	return di.DeviceHardware.FriendlyName
+}
+
+// Medium returns the KNX medium the device is attached to.
+func (di *DescriptionBlock) Medium() KNXMedium {
+	return di.DeviceHardware.Medium
+}
+
+// IsRF reports whether the device is attached to a KNX RF medium.
+func (di *DescriptionBlock) IsRF() bool {
+	return di.DeviceHardware.Medium == KNXMediumRF
+}
+
+// RFDomainAddress returns the domain address of a KNX RF device, if it has reported one in its
+// extended device information.
+func (di *DescriptionBlock) RFDomainAddress() ([]byte, bool) {
+	if !di.IsRF() || len(di.ExtendedDeviceInfo.RFDomainAddress) == 0 {
+		return nil, false
+	}
+
+	addr := make([]byte, len(di.ExtendedDeviceInfo.RFDomainAddress))
+	copy(addr, di.ExtendedDeviceInfo.RFDomainAddress)
+
+	return addr, true
+}
+
+// SupportedFamilies returns the service families supported by the device.
+func (di *DescriptionBlock) SupportedFamilies() []ServiceFamily {
+	if len(di.SupportedServices.Families) == 0 {
+		return nil
+	}
+
+	families := make([]ServiceFamily, len(di.SupportedServices.Families))
+	copy(families, di.SupportedServices.Families)
+
+	return families
+}
+
+// TunnellingSlots returns the tunnelling slots of the device. Devices which do not include a
+// Tunnelling Information DIB in their description have no slots.
+func (di *DescriptionBlock) TunnellingSlots() []TunnellingSlot {
+	if len(di.TunnellingInfo.Slots) == 0 {
+		return nil
+	}
+
+	slots := make([]TunnellingSlot, len(di.TunnellingInfo.Slots))
+	copy(slots, di.TunnellingInfo.Slots)
+
+	return slots
+}
+
+// SecureStatus summarizes the KNX IP Secure capabilities of a device.
+type SecureStatus struct {
+	// Tunnelling indicates that secure tunnelling is supported.
+	Tunnelling bool
+
+	// Routing indicates that secure routing is supported.
+	Routing bool
+
+	// DeviceManagement indicates that secure device management is supported.
+	DeviceManagement bool
+
+	// Required indicates that at least one service family can only be used securely.
+	Required bool
+}
+
+// String formats the secure status.
+func (status SecureStatus) String() string {
+	var parts []string
+
+	if status.Tunnelling {
+		parts = append(parts, "tunnelling")
+	}
+
+	if status.Routing {
+		parts = append(parts, "routing")
+	}
+
+	if status.DeviceManagement {
+		parts = append(parts, "device management")
+	}
+
+	if len(parts) == 0 {
+		return "not secure"
+	}
+
+	str := "secure " + strings.Join(parts, ", ")
+	if status.Required {
+		str += " (required)"
+	}
+
+	return str
+}
+
+// hasServiceFamily checks whether the given service family type is in the list.
+func hasServiceFamily(families []ServiceFamily, ty ServiceFamilyType) bool {
+	for _, family := range families {
+		if family.Type == ty {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SecureStatus combines the Supported and Secured Service Families DIBs into a summary of the
+// device's KNX IP Secure capabilities. A service family is considered securable if it is listed
+// in the Secured Service Families DIB, or if both the family and the Secure family are supported.
+func (di *DescriptionBlock) SecureStatus() SecureStatus {
+	supported := di.SupportedServices.Families
+	secured := di.SecuredServices.Families
+	secure := hasServiceFamily(supported, ServiceFamilyTypeIPSecure)
+
+	securable := func(ty ServiceFamilyType) bool {
+		return hasServiceFamily(secured, ty) || (secure && hasServiceFamily(supported, ty))
+	}
+
+	return SecureStatus{
+		Tunnelling:       securable(ServiceFamilyTypeIPTunnelling),
+		Routing:          securable(ServiceFamilyTypeIPRouting),
+		DeviceManagement: securable(ServiceFamilyTypeIPDeviceManagement),
+		Required:         len(secured) > 0,
+	}
+}
+
+// ErrDIBPanic is wrapped by the errors which describe a recovered panic while unpacking a DIB.
+var ErrDIBPanic = errors.New("unpacking DIB panicked")
+
+var recoverDIBPanics int32
+
+// SetRecoverDIBPanics controls whether DescriptionBlock.Unpack recovers from a panic in the Unpack
+// method of an individual DIB, which usually stems from a registered vendor DIB. If enabled, such a
+// DIB is skipped and recorded in DIBErrors, and the remaining DIBs are unpacked nonetheless. This
+// suits discovery services which must not fail because of a single malformed DIB. It is disabled
+// by default.
+func SetRecoverDIBPanics(enabled bool) {
+	var value int32
+	if enabled {
+		value = 1
+	}
+
+	atomic.StoreInt32(&recoverDIBPanics, value)
+}
+
+// unpackDIB unpacks a single DIB. If recovering is enabled, a panic is returned as an error which
+// wraps ErrDIBPanic.
+func unpackDIB(dib DIB, data []byte, recovering bool) (err error) {
+	if recovering {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("%w: DIB %v: %v", ErrDIBPanic, DescriptionType(data[1]), r)
+			}
+		}()
+	}
+
+	_, err = dib.Unpack(data)
+	return
 }
 
 // Unpack parses the given service payload in order to initialize the Description Block.
-// It can cope with not in sequence and unknown Device Information Blocks (DIB).
+// It can cope with not in sequence and unknown Device Information Blocks (DIB). If a built-in DIB
+// occurs more than once, the first one is unpacked into its field and the others are collected in
+// DuplicateBlocks. The Device Information DIB and the Supported Service Families DIB are
+// mandatory, an error wrapping ErrMissingMandatoryDIB is returned if one of them is absent.
 func (di *DescriptionBlock) Unpack(data []byte) (n uint, err error) {
-	var length uint8
-	var ty DescriptionType
+	if err = checkDescriptionSize(data); err != nil {
+		return 0, err
+	}
+
+	recovering := atomic.LoadInt32(&recoverDIBPanics) != 0
+
+	var seen [256]bool
 
 	n = 0
-	for n < uint(len(data)) {
-		// DIBs should always have a length and a type.
-		_, err := util.UnpackSome(data[n:], &length, (*uint8)(&ty))
+	for count := 0; n < uint(len(data)); count++ {
+		length, ty, err := nextDIB(data, n, count)
 		if err != nil {
 			return 0, err
 		}
 
-		switch ty {
-		case DescriptionTypeDeviceInfo:
-			_, err = di.DeviceHardware.Unpack(data[n : n+uint(length)])
-			if err != nil {
-				return 0, err
-			}
-			n += uint(length)
+		var dib DIB
+		var extra, duplicate bool
 
-		case DescriptionTypeSupportedServiceFamilies:
-			_, err = di.SupportedServices.Unpack(data[n : n+uint(length)])
-			if err != nil {
-				return 0, err
+		// Built-in DIBs are unpacked into their fields, registered ones are collected.
+		if factory, builtin, ok := lookupDIB(ty); ok {
+			if builtin && !seen[ty] {
+				dib = di.builtinDIB(ty)
+			} else {
+				dib, extra, duplicate = factory(), true, builtin
 			}
-			n += uint(length)
+			seen[ty] = true
+		}
 
-		case DescriptionTypeIPConfig:
-			_, err = di.IPConfig.Unpack(data[n : n+uint(length)])
-			if err != nil {
-				return 0, err
-			}
-			n += uint(length)
+		if dib == nil {
+			util.Log(di, "Found unsupported DIB %v", ty)
 
-		case DescriptionTypeIPCurrentConfig:
-			_, err = di.IPCurrentConfig.Unpack(data[n : n+uint(length)])
-			if err != nil {
+			var u UnknownDescriptionBlock
+			if _, err = u.Unpack(data[n : n+uint(length)]); err != nil {
 				return 0, err
 			}
+			di.UnknownBlocks = append(di.UnknownBlocks, u)
 			n += uint(length)
 
-		case DescriptionTypeKNXAddresses:
-			_, err = di.KNXAddrs.Unpack(data[n : n+uint(length)])
-			if err != nil {
-				return 0, err
-			}
-			n += uint(length)
+			continue
+		}
 
-		case DescriptionTypeSecuredServiceFamilies:
-			_, err = di.SecuredServices.Unpack(data[n : n+uint(length)])
-			if err != nil {
-				return 0, err
+		err = unpackDIB(dib, data[n:n+uint(length)], recovering)
+		if errors.Is(err, ErrDIBPanic) {
+			// Discard what the built-in DIB has unpacked before it panicked.
+			if !extra {
+				field := reflect.ValueOf(dib).Elem()
+				field.Set(reflect.Zero(field.Type()))
 			}
-			n += uint(length)
 
-		case DescriptionTypeTunnellingInfo:
-			_, err = di.TunnellingInfo.Unpack(data[n : n+uint(length)])
-			if err != nil {
-				return 0, err
-			}
+			di.DIBErrors = append(di.DIBErrors, err)
 			n += uint(length)
 
-		case DescriptionTypeExtendedDeviceInfo:
-			_, err = di.ExtendedDeviceInfo.Unpack(data[n : n+uint(length)])
-			if err != nil {
-				return 0, err
-			}
-			n += uint(length)
+			continue
+		} else if err != nil {
+			return 0, err
+		}
+		if duplicate {
+			di.DuplicateBlocks = append(di.DuplicateBlocks, dib)
+		} else if extra {
+			di.ExtraBlocks = append(di.ExtraBlocks, dib)
+		}
+		n += uint(length)
+	}
 
-		case DescriptionTypeManufacturerData:
-			_, err = di.ManufacturerData.Unpack(data[n : n+uint(length)])
-			if err != nil {
-				return 0, err
-			}
-			n += uint(length)
+	return n, di.checkMandatoryDIBs()
+}
 
-			// Original implementation did not handle these DIBs.
-		// case DescriptionTypeIPConfig, DescriptionTypeIPCurrentConfig,
-		// 	DescriptionTypeKNXAddresses, DescriptionTypeManufacturerData:
-		// 	u := UnknownDescriptionBlock{Type: ty}
-		//
-		// 	// known DIBs without data will be silently ignored.
-		// 	if length > 2 {
-		// 		// _, err = u.Unpack(data[n+2 : n+uint(length)-2]) // wrong end index in original code
-		// 		_, err = u.Unpack(data[n+2 : n+uint(length)])
-		// 		if err != nil {
-		// 			return 0, err
-		// 		}
-		// 		di.UnknownBlocks = append(di.UnknownBlocks, u)
-		// 		util.Log(di, "DIB not parsed: 0x%02x", ty)
-		// 	}
-		// 	n += uint(length)
-
-		default:
-			util.Log(di, "Found unsupported DIB with code: 0x%02x", ty)
-			n += uint(length)
-		}
+// ErrMissingMandatoryDIB indicates that a description lacks the Device Information DIB or the
+// Supported Service Families DIB.
+var ErrMissingMandatoryDIB = errors.New("description lacks a mandatory DIB")
+
+// checkMandatoryDIBs verifies that the Device Information DIB and the Supported Service Families
+// DIB have been unpacked. Otherwise their zero values could not be told apart from a device which
+// supports no service families.
+func (di *DescriptionBlock) checkMandatoryDIBs() error {
+	if di.DeviceHardware.Type != DescriptionTypeDeviceInfo {
+		return fmt.Errorf("%w: device information is missing", ErrMissingMandatoryDIB)
+	}
+
+	if di.SupportedServices.Type != DescriptionTypeSupportedServiceFamilies {
+		return fmt.Errorf("%w: supported service families are missing", ErrMissingMandatoryDIB)
 	}
 
-	return n, err
+	return nil
 }
 
 // UnknownDescriptionBlock is a placeholder for unknown DIBs.
@@ -748,10 +1602,36 @@ type UnknownDescriptionBlock struct {
 	Data []byte
 }
 
-// Unpack Unknown Description Blocks into a buffer.
+// Size returns the packed size.
+func (u UnknownDescriptionBlock) Size() uint {
+	return 2 + uint(len(u.Data))
+}
+
+// Pack assembles the unknown DIB, i.e. its length, its type and the stored data, in the given
+// buffer. This allows forwarding DIBs which this package does not understand.
+func (u *UnknownDescriptionBlock) Pack(buffer []byte) {
+	util.PackSome(buffer, uint8(u.Size()), uint8(u.Type), u.Data)
+}
+
+// Unpack parses an entire unknown DIB, including its length and type. The data is only allocated
+// if the DIB carries any.
 func (u *UnknownDescriptionBlock) Unpack(data []byte) (n uint, err error) {
-	u.Data = make([]byte, len(data))
-	return util.UnpackSome(data, u.Data)
+	var length uint8
+	if n, err = util.UnpackSome(data, &length, (*uint8)(&u.Type)); err != nil {
+		return
+	}
+
+	if length < 2 || int(length) > len(data) {
+		return n, fmt.Errorf("invalid length %d for unknown DIB %v", length, u.Type)
+	}
+
+	u.Data = nil
+	if length > 2 {
+		u.Data = make([]byte, length-2)
+		n += uint(copy(u.Data, data[n:length]))
+	}
+
+	return
 }
 
 type DIB interface {
@@ -763,7 +1643,50 @@ type DIB interface {
 
 	// Unpack parses the given data in order to initialize the DIB structure.
 	Unpack(data []byte) (n uint, err error)
+}
+
+// isNilDIB reports whether the DIB is nil or a nil pointer to a concrete DIB.
+func isNilDIB(dib DIB) bool {
+	if dib == nil {
+		return true
+	}
+
+	value := reflect.ValueOf(dib)
+	return value.Kind() == reflect.Ptr && value.IsNil()
+}
+
+// dibType determines the description type of the DIB. Built-in DIBs report their Type field, all
+// others are packed in order to read the type from the header.
+func dibType(dib DIB) DescriptionType {
+	switch dib := dib.(type) {
+	case *DeviceInformationBlock:
+		return dib.Type
+	case *SupportedServicesDIB:
+		return dib.Type
+	case *IPConfigDIB:
+		return dib.Type
+	case *IPCurrentConfigDIB:
+		return dib.Type
+	case *KNXAddrsDIB:
+		return dib.Type
+	case *SecuredServicesDIB:
+		return dib.Type
+	case *TunnellingInfoDIB:
+		return dib.Type
+	case *ExtendedDeviceInfoDIB:
+		return dib.Type
+	case *ManufacturerDataDIB:
+		return dib.Type
+	case *UnknownDescriptionBlock:
+		return dib.Type
+	}
+
+	if dib.Size() < 2 {
+		return 0
+	}
+
+	buffer := make([]byte, dib.Size())
+	dib.Pack(buffer)
 
-	// Type returns the type of the DIB.
-	// Type() DescriptionType
+	return DescriptionType(buffer[1])
 }
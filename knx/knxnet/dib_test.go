@@ -0,0 +1,1076 @@
+// Licensed under the MIT license which can be found in the LICENSE file.
+
+package knxnet
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/LB-00/knx-go/knx/cemi"
+	"github.com/LB-00/knx-go/knx/util"
+)
+
+// makeMandatoryDIBs packs the DIBs which every description must contain.
+func makeMandatoryDIBs() []byte {
+	return util.AllocAndPack(
+		&DeviceInformationBlock{Type: DescriptionTypeDeviceInfo, HardwareAddr: make([]byte, 6)},
+		&SupportedServicesDIB{
+			Type:     DescriptionTypeSupportedServiceFamilies,
+			Families: []ServiceFamily{{Type: ServiceFamilyTypeIPCore, Version: 2}},
+		},
+	)
+}
+
+func TestKNXAddrsDIB_Unpack(t *testing.T) {
+	data := append(makeMandatoryDIBs(),
+		0x08, byte(DescriptionTypeKNXAddresses),
+		0x11, 0x01,
+		0x11, 0x02,
+		0x11, 0x03,
+	)
+
+	var di DescriptionBlock
+	n, err := di.Unpack(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != uint(len(data)) {
+		t.Errorf("Unexpected length: %d != %d", n, len(data))
+	}
+
+	if len(di.KNXAddrs.KNXAddrs) != 3 {
+		t.Fatalf("Unexpected number of addresses: %d", len(di.KNXAddrs.KNXAddrs))
+	}
+
+	expected := []cemi.IndividualAddr{
+		cemi.NewIndividualAddr3(1, 1, 2),
+		cemi.NewIndividualAddr3(1, 1, 3),
+	}
+
+	additional := di.AdditionalAddresses()
+	if len(additional) != len(expected) {
+		t.Fatalf("Unexpected number of additional addresses: %d", len(additional))
+	}
+
+	for i := range expected {
+		if additional[i] != expected[i] {
+			t.Errorf("Unexpected additional address %d: %v != %v", i, additional[i], expected[i])
+		}
+	}
+
+	if str := di.KNXAddrs.String(); str != "1.1.1, 1.1.2, 1.1.3" {
+		t.Errorf("Unexpected string representation: %s", str)
+	}
+}
+
+func TestSupportedServicesDIB_Unpack(t *testing.T) {
+	data := []byte{
+		0x07, byte(DescriptionTypeSupportedServiceFamilies),
+		ServiceFamilyTypeIPCore, 0x01,
+		ServiceFamilyTypeIPTunnelling, 0x01,
+		0xff,
+	}
+
+	t.Run("Strict", func(t *testing.T) {
+		var sdib SupportedServicesDIB
+		if _, err := sdib.Unpack(data); err == nil {
+			t.Fatal("Should not succeed")
+		}
+	})
+
+	t.Run("Tolerant", func(t *testing.T) {
+		SetTolerantDIBUnpack(true)
+		defer SetTolerantDIBUnpack(false)
+
+		var sdib SupportedServicesDIB
+		n, err := sdib.Unpack(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if n != uint(len(data)) {
+			t.Errorf("Unexpected length: %d != %d", n, len(data))
+		}
+
+		if len(sdib.Families) != 2 {
+			t.Errorf("Unexpected number of service families: %d", len(sdib.Families))
+		}
+
+		if !bytes.Equal(sdib.Trailing, []byte{0xff}) {
+			t.Errorf("Unexpected trailing bytes: %v", sdib.Trailing)
+		}
+
+		if packed := util.AllocAndPack(&sdib); !bytes.Equal(packed, data) {
+			t.Errorf("Unexpected packed result: %v != %v", packed, data)
+		}
+	})
+}
+
+func TestKNXAddrsDIB_UnpackTrailing(t *testing.T) {
+	data := []byte{
+		0x05, byte(DescriptionTypeKNXAddresses),
+		0x11, 0x01,
+		0x00,
+	}
+
+	t.Run("Strict", func(t *testing.T) {
+		var kdib KNXAddrsDIB
+		if _, err := kdib.Unpack(data); err == nil {
+			t.Fatal("Should not succeed")
+		}
+	})
+
+	t.Run("Tolerant", func(t *testing.T) {
+		SetTolerantDIBUnpack(true)
+		defer SetTolerantDIBUnpack(false)
+
+		var kdib KNXAddrsDIB
+		n, err := kdib.Unpack(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if n != uint(len(data)) {
+			t.Errorf("Unexpected length: %d != %d", n, len(data))
+		}
+
+		if len(kdib.KNXAddrs) != 1 {
+			t.Errorf("Unexpected number of addresses: %d", len(kdib.KNXAddrs))
+		}
+
+		if !bytes.Equal(kdib.Trailing, []byte{0x00}) {
+			t.Errorf("Unexpected trailing bytes: %v", kdib.Trailing)
+		}
+	})
+}
+
+func BenchmarkDeviceInformationBlock_Pack(b *testing.B) {
+	b.ReportAllocs()
+
+	dib := DeviceInformationBlock{
+		Type:         DescriptionTypeDeviceInfo,
+		Medium:       KNXMediumTP1,
+		HardwareAddr: make([]byte, 6),
+		FriendlyName: "KNX IP Interface",
+	}
+	buffer := make([]byte, dib.Size())
+
+	for i := 0; i < b.N; i++ {
+		dib.Pack(buffer)
+	}
+}
+
+func TestDIB_PackNil(t *testing.T) {
+	buffer := make([]byte, 64)
+
+	for _, dib := range []DIB{
+		(*DeviceInformationBlock)(nil),
+		(*SupportedServicesDIB)(nil),
+		(*IPConfigDIB)(nil),
+		(*IPCurrentConfigDIB)(nil),
+		(*KNXAddrsDIB)(nil),
+		(*ManufacturerDataDIB)(nil),
+		(*SecuredServicesDIB)(nil),
+		(*TunnellingInfoDIB)(nil),
+		(*ExtendedDeviceInfoDIB)(nil),
+	} {
+		dib.Pack(buffer)
+	}
+
+	if !bytes.Equal(buffer, make([]byte, 64)) {
+		t.Errorf("Nil DIBs wrote %x", buffer)
+	}
+}
+
+func TestDeviceInformationBlock_Pack(t *testing.T) {
+	dib := DeviceInformationBlock{
+		Type:         DescriptionTypeDeviceInfo,
+		Medium:       KNXMediumTP1,
+		Source:       cemi.NewIndividualAddr3(1, 1, 0),
+		HardwareAddr: []byte{0x00, 0x24, 0x6d, 0x01, 0x02, 0x03},
+		FriendlyName: "KNX",
+	}
+
+	// A dirty buffer must not leak into the padding of the friendly name.
+	buffer := make([]byte, dib.Size())
+	for i := range buffer {
+		buffer[i] = 0xff
+	}
+
+	dib.Pack(buffer)
+
+	name := buffer[dib.Size()-friendlyNameMaxLen:]
+	if !bytes.Equal(name[:3], []byte("KNX")) {
+		t.Errorf("Unexpected friendly name: %v", name[:3])
+	}
+
+	if !bytes.Equal(name[3:], make([]byte, friendlyNameMaxLen-3)) {
+		t.Errorf("Friendly name is not zero-padded: %v", name[3:])
+	}
+
+	var unpacked DeviceInformationBlock
+	if _, err := unpacked.Unpack(buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	if unpacked.FriendlyName != dib.FriendlyName {
+		t.Errorf("Unexpected friendly name: %q != %q", unpacked.FriendlyName, dib.FriendlyName)
+	}
+}
+
+// makeDescriptionPayload assembles a realistic description of a tunnelling interface.
+func makeDescriptionPayload() []byte {
+	return util.AllocAndPack(
+		&DeviceInformationBlock{
+			Type:         DescriptionTypeDeviceInfo,
+			Medium:       KNXMediumTP1,
+			Source:       cemi.NewIndividualAddr3(1, 1, 0),
+			HardwareAddr: []byte{0x00, 0x24, 0x6d, 0x01, 0x02, 0x03},
+			FriendlyName: "KNX IP Interface",
+		},
+		&SupportedServicesDIB{
+			Type: DescriptionTypeSupportedServiceFamilies,
+			Families: []ServiceFamily{
+				{Type: ServiceFamilyTypeIPCore, Version: 2},
+				{Type: ServiceFamilyTypeIPDeviceManagement, Version: 2},
+				{Type: ServiceFamilyTypeIPTunnelling, Version: 2},
+			},
+		},
+		&IPConfigDIB{
+			Type: DescriptionTypeIPConfig,
+			IP:   Address{192, 168, 1, 10},
+			Mask: Address{255, 255, 255, 0},
+		},
+		&IPCurrentConfigDIB{
+			Type: DescriptionTypeIPCurrentConfig,
+			IP:   Address{192, 168, 1, 10},
+			Mask: Address{255, 255, 255, 0},
+		},
+		&KNXAddrsDIB{
+			Type: DescriptionTypeKNXAddresses,
+			KNXAddrs: []cemi.IndividualAddr{
+				cemi.NewIndividualAddr3(1, 1, 0),
+				cemi.NewIndividualAddr3(1, 1, 1),
+				cemi.NewIndividualAddr3(1, 1, 2),
+			},
+		},
+		&TunnellingInfoDIB{
+			Type:     DescriptionTypeTunnellingInfo,
+			APDUSize: 254,
+			Slots: []TunnellingSlot{
+				{Addr: cemi.NewIndividualAddr3(1, 1, 1), Status: 0x07},
+				{Addr: cemi.NewIndividualAddr3(1, 1, 2), Status: 0x07},
+			},
+		},
+		&ExtendedDeviceInfoDIB{
+			Type:             DescriptionTypeExtendedDeviceInfo,
+			APDUSize:         254,
+			DeviceDescriptor: 0x091a,
+		},
+	)
+}
+
+func TestDescriptionBlock_PackRoundTrip(t *testing.T) {
+	di := DescriptionBlock{
+		KNXAddrs: KNXAddrsDIB{
+			Type:     DescriptionTypeKNXAddresses,
+			KNXAddrs: []cemi.IndividualAddr{cemi.NewIndividualAddr3(1, 1, 0), cemi.NewIndividualAddr3(1, 1, 1)},
+		},
+		TunnellingInfo: TunnellingInfoDIB{
+			Type:     DescriptionTypeTunnellingInfo,
+			APDUSize: 254,
+			Slots:    []TunnellingSlot{{Addr: cemi.NewIndividualAddr3(1, 1, 1), Status: 0x05}},
+		},
+		ManufacturerData: ManufacturerDataDIB{
+			Type: DescriptionTypeManufacturerData,
+			ID:   0x00c5,
+			Data: []byte{0x13, 0x37},
+		},
+		UnknownBlocks: []UnknownDescriptionBlock{{Type: 0x42, Data: []byte{0x01, 0x02}}},
+	}
+
+	if _, err := di.DeviceHardware.Unpack(makeDescriptionPayload()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := di.SupportedServices.Unpack(makeDescriptionPayload()[di.DeviceHardware.Size():]); err != nil {
+		t.Fatal(err)
+	}
+
+	data := util.AllocAndPack(&di)
+	if uint(len(data)) != di.Size() {
+		t.Fatalf("Packed %d bytes, expected %d", len(data), di.Size())
+	}
+
+	// The mandatory DIBs come first, the optional ones follow in the order of their types.
+	expected := util.AllocAndPack(&di.DeviceHardware, &di.SupportedServices, &di.KNXAddrs, &di.TunnellingInfo, &di.ManufacturerData)
+	if !bytes.Equal(data[:len(expected)], expected) {
+		t.Errorf("Unexpected order of DIBs: % x", data)
+	}
+
+	var unpacked DescriptionBlock
+	if _, err := unpacked.Unpack(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(unpacked, di) {
+		t.Errorf("Round trip yielded %+v, expected %+v", unpacked, di)
+	}
+}
+
+func TestDescriptionBlock_UnpackAllocs(t *testing.T) {
+	data := makeDescriptionPayload()
+
+	allocs := testing.AllocsPerRun(100, func() {
+		var di DescriptionBlock
+		if _, err := di.Unpack(data); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	// Guards the hot discovery path against allocation regressions.
+	if allocs > 32 {
+		t.Errorf("Unexpected number of allocations: %v", allocs)
+	}
+}
+
+func BenchmarkDescriptionBlockUnpack(b *testing.B) {
+	b.ReportAllocs()
+
+	data := makeDescriptionPayload()
+
+	for i := 0; i < b.N; i++ {
+		var di DescriptionBlock
+		di.Unpack(data)
+	}
+}
+
+func TestDescriptionBlock_UnpackKnownOnly(t *testing.T) {
+	data := makeDescriptionPayload()
+
+	var di DescriptionBlock
+	if _, err := di.Unpack(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if di.UnknownBlocks != nil {
+		t.Fatalf("Unexpected unknown blocks: %v", di.UnknownBlocks)
+	}
+
+	// Sum up the allocations of the individual DIBs.
+	var dibAllocs float64
+	for offset := uint(0); offset < uint(len(data)); offset += uint(data[offset]) {
+		block := data[offset : offset+uint(data[offset])]
+
+		var dib DIB
+		switch DescriptionType(block[1]) {
+		case DescriptionTypeDeviceInfo:
+			dib = &DeviceInformationBlock{}
+		case DescriptionTypeSupportedServiceFamilies:
+			dib = &SupportedServicesDIB{}
+		case DescriptionTypeIPConfig:
+			dib = &IPConfigDIB{}
+		case DescriptionTypeIPCurrentConfig:
+			dib = &IPCurrentConfigDIB{}
+		case DescriptionTypeKNXAddresses:
+			dib = &KNXAddrsDIB{}
+		case DescriptionTypeTunnellingInfo:
+			dib = &TunnellingInfoDIB{}
+		case DescriptionTypeExtendedDeviceInfo:
+			dib = &ExtendedDeviceInfoDIB{}
+		default:
+			t.Fatalf("Unexpected DIB type: %#x", block[1])
+		}
+
+		dibAllocs += testing.AllocsPerRun(100, func() {
+			dib.Unpack(block)
+		})
+	}
+
+	// Besides the DIB fields themselves, nothing must be allocated.
+	allocs := testing.AllocsPerRun(100, func() {
+		di = DescriptionBlock{}
+		di.Unpack(data)
+	})
+
+	if allocs > dibAllocs {
+		t.Errorf("Unexpected number of allocations: %v > %v", allocs, dibAllocs)
+	}
+}
+
+func TestDescriptionBlock_UnpackUnknown(t *testing.T) {
+	data := append(makeDescriptionPayload(), 0x04, 0x42, 0x13, 0x37)
+
+	var di DescriptionBlock
+	if _, err := di.Unpack(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(di.UnknownBlocks) != 1 {
+		t.Fatalf("Unexpected number of unknown blocks: %d", len(di.UnknownBlocks))
+	}
+
+	if di.UnknownBlocks[0].Type != 0x42 || !bytes.Equal(di.UnknownBlocks[0].Data, []byte{0x13, 0x37}) {
+		t.Errorf("Unexpected unknown block: %+v", di.UnknownBlocks[0])
+	}
+}
+
+func TestDescriptionBlock_UnpackLimits(t *testing.T) {
+	defer SetMaxDescriptionSize(DefaultMaxDescriptionSize)
+	defer SetMaxDescriptionBlocks(DefaultMaxDescriptionBlocks)
+
+	// Many small blocks
+	many := makeMandatoryDIBs()
+	for i := 0; i < DefaultMaxDescriptionBlocks+1; i++ {
+		many = append(many, 0x04, 0x42, 0x13, 0x37)
+	}
+
+	// Few large blocks
+	large := makeMandatoryDIBs()
+	for len(large) <= DefaultMaxDescriptionSize {
+		block := make([]byte, 255)
+		block[0], block[1] = 255, 0x42
+		large = append(large, block...)
+	}
+
+	for _, data := range [][]byte{many, large} {
+		var di DescriptionBlock
+		if _, err := di.Unpack(data); !errors.Is(err, ErrDescriptionTooLarge) {
+			t.Errorf("Unpacking %d bytes yielded %v", len(data), err)
+		}
+	}
+
+	SetMaxDescriptionSize(0)
+	SetMaxDescriptionBlocks(0)
+
+	for _, data := range [][]byte{many, large} {
+		var di DescriptionBlock
+		if _, err := di.Unpack(data); err != nil {
+			t.Errorf("Unpacking %d bytes without limits yielded %v", len(data), err)
+		}
+	}
+
+	// Malformed lengths must neither loop forever nor panic.
+	for _, data := range [][]byte{{0x00, 0x42}, {0x01, 0x42}, {0x08, 0x42, 0x13}} {
+		var di DescriptionBlock
+		if _, err := di.Unpack(data); err == nil {
+			t.Errorf("Unpacking %x should fail", data)
+		}
+	}
+}
+
+// vendorDIB is a DIB which is not implemented by this package.
+type vendorDIB struct {
+	Value uint16
+}
+
+const vendorDescriptionType DescriptionType = 0xfd
+
+func (vendorDIB) Size() uint {
+	return 4
+}
+
+func (vdib *vendorDIB) Pack(buffer []byte) {
+	util.PackSome(buffer, uint8(vdib.Size()), uint8(vendorDescriptionType), vdib.Value)
+}
+
+func (vdib *vendorDIB) Unpack(data []byte) (uint, error) {
+	var length, ty uint8
+	return util.UnpackSome(data, &length, &ty, &vdib.Value)
+}
+
+func TestRegisterDIB(t *testing.T) {
+	RegisterDIB(vendorDescriptionType, func() DIB { return &vendorDIB{} })
+	defer RegisterDIB(vendorDescriptionType, nil)
+
+	vendor := util.AllocAndPack(&vendorDIB{Value: 0x1337})
+
+	t.Run("DescriptionBlock", func(t *testing.T) {
+		var di DescriptionBlock
+		if _, err := di.Unpack(append(makeDescriptionPayload(), vendor...)); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(di.UnknownBlocks) != 0 {
+			t.Errorf("Unexpected unknown blocks: %v", di.UnknownBlocks)
+		}
+
+		if len(di.ExtraBlocks) != 1 {
+			t.Fatalf("Unexpected number of extra blocks: %d", len(di.ExtraBlocks))
+		}
+
+		if dib, ok := di.ExtraBlocks[0].(*vendorDIB); !ok || dib.Value != 0x1337 {
+			t.Errorf("Unexpected extra block: %#v", di.ExtraBlocks[0])
+		}
+	})
+
+	t.Run("SearchResExt", func(t *testing.T) {
+		var res SearchResExt
+		if _, err := res.Unpack(append(makeSearchResExtPayload(), vendor...)); err != nil {
+			t.Fatal(err)
+		}
+
+		last := res.DIBs[len(res.DIBs)-1]
+		if dib, ok := last.(*vendorDIB); !ok || dib.Value != 0x1337 {
+			t.Errorf("Unexpected DIB: %#v", last)
+		}
+	})
+}
+
+func TestRegisterDIB_Override(t *testing.T) {
+	raw := func() DIB { return &UnknownDescriptionBlock{} }
+
+	// The mandatory DIBs cannot be replaced, descriptions can still be unpacked afterwards.
+	for _, ty := range []DescriptionType{
+		DescriptionTypeDeviceInfo,
+		DescriptionTypeSupportedServiceFamilies,
+	} {
+		if err := RegisterDIB(ty, raw); err == nil {
+			t.Errorf("Replacing %v should not succeed", ty)
+		}
+
+		if err := RegisterDIB(ty, nil); err == nil {
+			t.Errorf("Removing %v should not succeed", ty)
+		}
+	}
+
+	var di DescriptionBlock
+	if _, err := di.Unpack(makeDescriptionPayload()); err != nil {
+		t.Fatal(err)
+	}
+
+	// An optional built-in DIB is replaced by the registered one.
+	if err := RegisterDIB(DescriptionTypeIPConfig, raw); err != nil {
+		t.Fatal(err)
+	}
+
+	di = DescriptionBlock{}
+	if _, err := di.Unpack(makeDescriptionPayload()); err != nil {
+		t.Fatal(err)
+	}
+
+	if di.IPConfig.Type != 0 {
+		t.Errorf("Unexpected IP config %+v", di.IPConfig)
+	}
+
+	if len(di.ExtraBlocks) != 1 || dibType(di.ExtraBlocks[0]) != DescriptionTypeIPConfig {
+		t.Errorf("Unexpected extra blocks: %v", di.ExtraBlocks)
+	}
+
+	// Removing the registration restores the built-in DIB.
+	if err := RegisterDIB(DescriptionTypeIPConfig, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	di = DescriptionBlock{}
+	if _, err := di.Unpack(makeDescriptionPayload()); err != nil {
+		t.Fatal(err)
+	}
+
+	if di.IPConfig.Type != DescriptionTypeIPConfig || len(di.ExtraBlocks) != 0 {
+		t.Errorf("Built-in IP config DIB has not been restored: %+v", di)
+	}
+}
+
+// panickingDIB is a vendor DIB whose Unpack panics on any data.
+type panickingDIB struct{}
+
+const panickingDescriptionType DescriptionType = 0xfc
+
+func (panickingDIB) Size() uint {
+	return 2
+}
+
+func (panickingDIB) Pack(buffer []byte) {
+	util.PackSome(buffer, uint8(2), uint8(panickingDescriptionType))
+}
+
+func (panickingDIB) Unpack(data []byte) (uint, error) {
+	return uint(data[len(data)+1]), nil
+}
+
+func TestDescriptionBlock_UnpackRecoverPanic(t *testing.T) {
+	RegisterDIB(panickingDescriptionType, func() DIB { return panickingDIB{} })
+	defer RegisterDIB(panickingDescriptionType, nil)
+
+	vendor := util.AllocAndPack(&vendorDIB{Value: 0x1337})
+
+	RegisterDIB(vendorDescriptionType, func() DIB { return &vendorDIB{} })
+	defer RegisterDIB(vendorDescriptionType, nil)
+
+	// The malformed DIB sits between valid ones.
+	data := append(makeDescriptionPayload(), util.AllocAndPack(panickingDIB{})...)
+	data = append(data, vendor...)
+
+	t.Run("Disabled", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("Unpack should panic")
+			}
+		}()
+
+		var di DescriptionBlock
+		di.Unpack(data)
+	})
+
+	t.Run("Enabled", func(t *testing.T) {
+		SetRecoverDIBPanics(true)
+		defer SetRecoverDIBPanics(false)
+
+		var di DescriptionBlock
+		n, err := di.Unpack(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if n != uint(len(data)) {
+			t.Errorf("Unpacked %d of %d bytes", n, len(data))
+		}
+
+		if len(di.DIBErrors) != 1 || !errors.Is(di.DIBErrors[0], ErrDIBPanic) {
+			t.Fatalf("Unexpected DIB errors: %v", di.DIBErrors)
+		}
+
+		if err := di.checkMandatoryDIBs(); err != nil {
+			t.Error(err)
+		}
+
+		if len(di.ExtraBlocks) != 1 {
+			t.Fatalf("Unexpected number of extra blocks: %d", len(di.ExtraBlocks))
+		}
+
+		if dib, ok := di.ExtraBlocks[0].(*vendorDIB); !ok || dib.Value != 0x1337 {
+			t.Errorf("Unexpected extra block: %#v", di.ExtraBlocks[0])
+		}
+	})
+}
+
+func TestDeviceStatus_ProgrammingMode(t *testing.T) {
+	status := DeviceStatus(0xf0)
+	if status.ProgrammingMode() {
+		t.Error("Programming mode should not be set")
+	}
+
+	status.SetProgrammingMode(true)
+	if status != 0xf1 || !status.ProgrammingMode() {
+		t.Errorf("Unexpected status %#02x", uint8(status))
+	}
+
+	status.SetProgrammingMode(false)
+	if status != 0xf0 || status.ProgrammingMode() {
+		t.Errorf("Unexpected status %#02x", uint8(status))
+	}
+}
+
+func TestKNXMedium(t *testing.T) {
+	testCases := []struct {
+		Medium KNXMedium
+		Known  bool
+		String string
+	}{
+		{KNXMediumTP1, true, "TP1"},
+		{KNXMediumPL110, true, "PL110"},
+		{KNXMediumRF, true, "RF"},
+		{KNXMediumIP, true, "IP"},
+		{KNXMedium(0x40), false, "unknown(0x40)"},
+		{KNXMedium(0x01), false, "unknown(0x01)"},
+	}
+
+	for _, testCase := range testCases {
+		if testCase.Medium.IsKnown() != testCase.Known {
+			t.Errorf("Unexpected result of IsKnown for %v", testCase.Medium)
+		}
+
+		if testCase.Medium.String() != testCase.String {
+			t.Errorf("Unexpected string representation: %s != %s", testCase.Medium, testCase.String)
+		}
+	}
+}
+
+func TestProjectInstallationIdentifier(t *testing.T) {
+	testCases := []struct {
+		Project      uint16
+		Installation uint8
+		ID           ProjectInstallationIdentifier
+	}{
+		{0, 0, 0x0000},
+		{1, 0, 0x0010},
+		{0, 1, 0x0001},
+		{0x123, 0x4, 0x1234},
+		{0xfff, 0xf, 0xffff},
+	}
+
+	for _, testCase := range testCases {
+		id, err := NewProjectInstallationIdentifier(testCase.Project, testCase.Installation)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if id != testCase.ID {
+			t.Errorf("Unexpected identifier: %#04x != %#04x", uint16(id), uint16(testCase.ID))
+		}
+
+		if id.ProjectNumber() != testCase.Project || id.InstallationNumber() != testCase.Installation {
+			t.Errorf("Unexpected numbers of %#04x: %d, %d", uint16(id), id.ProjectNumber(), id.InstallationNumber())
+		}
+	}
+
+	if _, err := NewProjectInstallationIdentifier(0x1000, 0); err == nil {
+		t.Error("Project number exceeding 12 bits should not succeed")
+	}
+
+	if _, err := NewProjectInstallationIdentifier(0, 0x10); err == nil {
+		t.Error("Installation number exceeding 4 bits should not succeed")
+	}
+}
+
+func TestExtendedDeviceInfoDIB_Descriptor(t *testing.T) {
+	for _, test := range []struct {
+		descriptor uint16
+		medium     KNXMedium
+		firmware   uint8
+		version    uint8
+		subcode    uint8
+		str        string
+	}{
+		{0x07B0, KNXMediumTP1, 7, 11, 0, "07B0 (TP1, firmware type 7, version 11.0)"},
+		{0x57B0, KNXMediumIP, 7, 11, 0, "57B0 (IP, firmware type 7, version 11.0)"},
+		{0x091A, KNXMediumTP1, 9, 1, 10, "091A (TP1, firmware type 9, version 1.10)"},
+		{0x2311, KNXMediumRF, 3, 1, 1, "2311 (RF, firmware type 3, version 1.1)"},
+		{0x1012, KNXMediumPL110, 0, 1, 2, "1012 (PL110, firmware type 0, version 1.2)"},
+		{0x3012, 0, 0, 1, 2, "3012 (medium type 3, firmware type 0, version 1.2)"},
+	} {
+		edib := ExtendedDeviceInfoDIB{DeviceDescriptor: test.descriptor}
+		dd := edib.Descriptor()
+
+		if dd.Medium() != test.medium || dd.FirmwareType() != test.firmware ||
+			dd.Version() != test.version || dd.Subcode() != test.subcode {
+			t.Errorf("%04X decoded as %v, %d, %d, %d", test.descriptor,
+				dd.Medium(), dd.FirmwareType(), dd.Version(), dd.Subcode())
+		}
+
+		if str := dd.String(); str != test.str {
+			t.Errorf("%04X formatted as %q, expected %q", test.descriptor, str, test.str)
+		}
+	}
+
+	if !(&ExtendedDeviceInfoDIB{MediumStatus: 0}).CommunicationPossible() {
+		t.Error("Medium status 0 indicates that communication is possible")
+	}
+
+	if (&ExtendedDeviceInfoDIB{MediumStatus: 1}).CommunicationPossible() {
+		t.Error("Medium status 1 indicates a medium fault")
+	}
+}
+
+func TestDescriptionBlock_SecureStatus(t *testing.T) {
+	families := func(types ...ServiceFamilyType) []ServiceFamily {
+		result := make([]ServiceFamily, len(types))
+		for i, ty := range types {
+			result[i] = ServiceFamily{Type: ty, Version: 1}
+		}
+		return result
+	}
+
+	t.Run("NotSecure", func(t *testing.T) {
+		di := DescriptionBlock{}
+		di.SupportedServices.Families = families(ServiceFamilyTypeIPCore, ServiceFamilyTypeIPTunnelling)
+
+		status := di.SecureStatus()
+		if status != (SecureStatus{}) {
+			t.Errorf("Unexpected secure status: %+v", status)
+		}
+
+		if str := status.String(); str != "not secure" {
+			t.Errorf("Unexpected string representation: %s", str)
+		}
+	})
+
+	t.Run("Optional", func(t *testing.T) {
+		di := DescriptionBlock{}
+		di.SupportedServices.Families = families(
+			ServiceFamilyTypeIPCore, ServiceFamilyTypeIPTunnelling, ServiceFamilyTypeIPSecure,
+		)
+
+		status := di.SecureStatus()
+		if status != (SecureStatus{Tunnelling: true}) {
+			t.Errorf("Unexpected secure status: %+v", status)
+		}
+
+		if str := status.String(); str != "secure tunnelling" {
+			t.Errorf("Unexpected string representation: %s", str)
+		}
+	})
+
+	t.Run("Required", func(t *testing.T) {
+		di := DescriptionBlock{}
+		di.SupportedServices.Families = families(
+			ServiceFamilyTypeIPCore, ServiceFamilyTypeIPDeviceManagement,
+			ServiceFamilyTypeIPTunnelling, ServiceFamilyTypeIPRouting, ServiceFamilyTypeIPSecure,
+		)
+		di.SecuredServices.Families = families(ServiceFamilyTypeIPRouting)
+
+		status := di.SecureStatus()
+		expected := SecureStatus{Tunnelling: true, Routing: true, DeviceManagement: true, Required: true}
+		if status != expected {
+			t.Errorf("Unexpected secure status: %+v", status)
+		}
+
+		if str := status.String(); str != "secure tunnelling, routing, device management (required)" {
+			t.Errorf("Unexpected string representation: %s", str)
+		}
+	})
+}
+
+func TestDescriptionBlock_RF(t *testing.T) {
+	domain := []byte{0x00, 0xfa, 0x12, 0x34, 0x56, 0x78}
+
+	data := util.AllocAndPack(
+		&DeviceInformationBlock{
+			Type:         DescriptionTypeDeviceInfo,
+			Medium:       KNXMediumRF,
+			Source:       cemi.NewIndividualAddr3(1, 1, 0),
+			HardwareAddr: []byte{0x00, 0x24, 0x6d, 0x01, 0x02, 0x03},
+			FriendlyName: "KNX RF Interface",
+		},
+		&SupportedServicesDIB{
+			Type:     DescriptionTypeSupportedServiceFamilies,
+			Families: []ServiceFamily{{Type: ServiceFamilyTypeIPCore, Version: 2}},
+		},
+		&ExtendedDeviceInfoDIB{
+			Type:             DescriptionTypeExtendedDeviceInfo,
+			APDUSize:         55,
+			DeviceDescriptor: 0x2311,
+			RFDomainAddress:  domain,
+		},
+	)
+
+	var di DescriptionBlock
+	if _, err := di.Unpack(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if !di.IsRF() {
+		t.Error("Device should be attached to RF")
+	}
+
+	addr, ok := di.RFDomainAddress()
+	if !ok || !bytes.Equal(addr, domain) {
+		t.Errorf("Unexpected domain address % x, %v", addr, ok)
+	}
+
+	if !bytes.Equal(util.AllocAndPack(&di), data) {
+		t.Errorf("Packed % x, expected % x", util.AllocAndPack(&di), data)
+	}
+
+	// A TP1 device has no domain address, even if the structure carries one.
+	di.DeviceHardware.Medium = KNXMediumTP1
+	if di.IsRF() {
+		t.Error("Device should not be attached to RF")
+	}
+
+	if _, ok := di.RFDomainAddress(); ok {
+		t.Error("Domain address should only be reported for RF devices")
+	}
+
+	// Only the plain structure and the one with a domain address are valid.
+	invalid := []byte{0x0a, byte(DescriptionTypeExtendedDeviceInfo), 0, 0, 0, 55, 0x23, 0x11, 0, 0}
+	if _, err := new(ExtendedDeviceInfoDIB).Unpack(invalid); err == nil {
+		t.Error("Unpacking an invalid length should fail")
+	}
+}
+
+func TestIPConfigDIB_Capabilities(t *testing.T) {
+	idib := IPConfigDIB{
+		IPCapabilities: IPCapabilityDHCP | IPCapabilityAutoIP,
+		IPAssignment:   IPAssignmentManual | IPAssignmentDHCP,
+	}
+
+	if idib.SupportsBootP() || !idib.SupportsDHCP() || !idib.SupportsAutoIP() {
+		t.Errorf("Capabilities %#x decoded as BootP %v, DHCP %v, AutoIP %v", idib.IPCapabilities,
+			idib.SupportsBootP(), idib.SupportsDHCP(), idib.SupportsAutoIP())
+	}
+
+	if methods := idib.AssignmentMethods(); !reflect.DeepEqual(methods, []string{"manual", "DHCP"}) {
+		t.Errorf("Unexpected assignment methods %v", methods)
+	}
+
+	cdib := IPCurrentConfigDIB{IPAssignment: IPAssignmentAutoIP}
+	if methods := cdib.AssignmentMethods(); !reflect.DeepEqual(methods, []string{"AutoIP"}) {
+		t.Errorf("Unexpected assignment methods %v", methods)
+	}
+
+	if methods := new(IPConfigDIB).AssignmentMethods(); len(methods) != 0 {
+		t.Errorf("Unexpected assignment methods %v", methods)
+	}
+}
+
+func TestDeviceSerialNumber_String(t *testing.T) {
+	sn := DeviceSerialNumber{0x00, 0xfa, 0x12, 0x34, 0x56, 0x78}
+	if str := sn.String(); str != "00:fa:12:34:56:78" {
+		t.Errorf("Unexpected string %q", str)
+	}
+
+	if str := fmt.Sprint(sn); str != "00:fa:12:34:56:78" {
+		t.Errorf("Unexpected formatted string %q", str)
+	}
+}
+
+func TestDescriptionType_String(t *testing.T) {
+	names := []string{
+		"DeviceInfo",
+		"SupportedServiceFamilies",
+		"IPConfig",
+		"IPCurrentConfig",
+		"KNXAddresses",
+		"SecuredServiceFamilies",
+		"TunnellingInfo",
+		"ExtendedDeviceInfo",
+		"ManufacturerData",
+	}
+
+	for i, ty := range standardDescriptionTypes {
+		if str := ty.String(); str != names[i] {
+			t.Errorf("Description type %#02x is named %q, expected %q", uint8(ty), str, names[i])
+		}
+	}
+
+	if str := DescriptionType(0x42).String(); str != "unknown(0x42)" {
+		t.Errorf("Unknown description type is named %q", str)
+	}
+}
+
+func TestUnknownDescriptionBlock_RoundTrip(t *testing.T) {
+	data := []byte{0x05, 0x42, 0x01, 0x02, 0x03}
+
+	var u UnknownDescriptionBlock
+	if n, err := u.Unpack(data); err != nil || n != uint(len(data)) {
+		t.Fatalf("Unpacked %d bytes: %v", n, err)
+	}
+
+	if u.Type != 0x42 || !bytes.Equal(u.Data, data[2:]) {
+		t.Errorf("Unexpected unknown block: %+v", u)
+	}
+
+	if packed := util.AllocAndPack(&u); !bytes.Equal(packed, data) {
+		t.Errorf("Packed % x, expected % x", packed, data)
+	}
+
+	if _, err := new(UnknownDescriptionBlock).Unpack([]byte{0x06, 0x42, 0x01}); err == nil {
+		t.Error("Unpacking a truncated block should fail")
+	}
+
+	// A search response keeps the vendor DIB when it is forwarded.
+	frame := append(makeSearchResExtPayload(), data...)
+
+	var res SearchRes
+	if _, err := res.Unpack(frame); err != nil {
+		t.Fatal(err)
+	}
+
+	if packed := util.AllocAndPack(&res); !bytes.Equal(packed, frame) {
+		t.Errorf("Packed % x, expected % x", packed, frame)
+	}
+}
+
+func TestDeviceInformationBlock_PackLongName(t *testing.T) {
+	dib := DeviceInformationBlock{
+		Type:         DescriptionTypeDeviceInfo,
+		HardwareAddr: make([]byte, 6),
+		FriendlyName: "Schaltaktor Küche Erdgeschoss Süd",
+	}
+
+	if err := CheckFriendlyName(dib.FriendlyName); !errors.Is(err, util.ErrStringTooLong) {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	var unpacked DeviceInformationBlock
+	if _, err := unpacked.Unpack(util.AllocAndPack(&dib)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The name is truncated after 30 characters, each of which takes a single byte.
+	if unpacked.FriendlyName != "Schaltaktor Küche Erdgeschoss " {
+		t.Errorf("Unexpected friendly name %q", unpacked.FriendlyName)
+	}
+
+	// A name of exactly 30 bytes is not terminated.
+	if err := CheckFriendlyName(unpacked.FriendlyName); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	if err := CheckFriendlyName("KNX ☃"); err == nil {
+		t.Error("Characters outside of ISO 8859-1 should be rejected")
+	}
+}
+
+func TestDescriptionBlock_UnpackDuplicates(t *testing.T) {
+	first := &KNXAddrsDIB{Type: DescriptionTypeKNXAddresses, KNXAddrs: []cemi.IndividualAddr{0x1101}}
+	second := &KNXAddrsDIB{Type: DescriptionTypeKNXAddresses, KNXAddrs: []cemi.IndividualAddr{0x1201, 0x1202}}
+
+	data := append(makeMandatoryDIBs(), util.AllocAndPack(first, second)...)
+
+	var di DescriptionBlock
+	if _, err := di.Unpack(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(&di.KNXAddrs, first) {
+		t.Errorf("The first block has been replaced: %+v", di.KNXAddrs)
+	}
+
+	if len(di.DuplicateBlocks) != 1 || !reflect.DeepEqual(di.DuplicateBlocks[0], second) {
+		t.Errorf("Unexpected duplicate blocks %+v", di.DuplicateBlocks)
+	}
+
+	if len(di.ExtraBlocks) != 0 {
+		t.Errorf("Unexpected extra blocks %+v", di.ExtraBlocks)
+	}
+
+	if packed := util.AllocAndPack(&di); !bytes.Equal(packed, data) {
+		t.Errorf("Packed % x, expected % x", packed, data)
+	}
+
+	// Duplicates do not make up for a missing mandatory DIB.
+	mandatory := makeMandatoryDIBs()
+	device := mandatory[:DeviceInformationBlock{}.Size()]
+
+	twice := append(append([]byte{}, device...), device...)
+	if _, err := new(DescriptionBlock).Unpack(twice); !errors.Is(err, ErrMissingMandatoryDIB) {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
+
+func TestDescriptionBlock_UnpackTruncated(t *testing.T) {
+	// A DIB which claims more bytes than are present.
+	data := append(makeMandatoryDIBs(), 0x10, byte(DescriptionTypeTunnellingInfo), 0x00, 0xfe)
+
+	var di DescriptionBlock
+	if _, err := di.Unpack(data); !errors.Is(err, ErrDIBTruncated) || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	// No built-in DIB panics, no matter which length it declares.
+	for _, ty := range standardDescriptionTypes {
+		for length := 2; length < 256; length++ {
+			block := make([]byte, length)
+			block[0], block[1] = byte(length), byte(ty)
+
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						t.Errorf("DIB %v with length %d panicked: %v", ty, length, r)
+					}
+				}()
+
+				var di DescriptionBlock
+				di.Unpack(block)
+			}()
+		}
+	}
+}
@@ -23,6 +23,18 @@ const (
 	TCP4 Protocol = 2
 )
 
+// String returns the URL scheme of the protocol.
+func (p Protocol) String() string {
+	switch p {
+	case UDP4:
+		return "udp"
+	case TCP4:
+		return "tcp"
+	}
+
+	return fmt.Sprintf("unknown(0x%02x)", uint8(p))
+}
+
 // Address is an IPv4 address.
 type Address [4]byte
 
@@ -31,7 +43,21 @@ func (addr Address) String() string {
 	return fmt.Sprintf("%d.%d.%d.%d", addr[0], addr[1], addr[2], addr[3])
 }
 
-// Port is a port number.
+// DefaultRoutingMulticastAddress is the multicast group which KNXnet/IP routers use by default.
+var DefaultRoutingMulticastAddress = Address{224, 0, 23, 12}
+
+// IsMulticast reports whether the address belongs to the IPv4 multicast range 224.0.0.0/4.
+func (addr Address) IsMulticast() bool {
+	return addr[0]&0xf0 == 0xe0
+}
+
+// IsUnspecified reports whether the address is 0.0.0.0.
+func (addr Address) IsUnspecified() bool {
+	return addr == Address{}
+}
+
+// Port is a port number. Like all multi-byte values of KNXnet/IP, it is transmitted in network
+// byte order, i.e. big-endian.
 type Port uint16
 
 // HostInfo contains information about a host.
@@ -87,6 +113,17 @@ func (info HostInfo) Equals(other HostInfo) bool {
 		info.Port == other.Port
 }
 
+// Equal checks whether both structures are equal. It is the same as Equals, but follows the naming
+// convention which tools like go-cmp expect.
+func (info HostInfo) Equal(other HostInfo) bool {
+	return info.Equals(other)
+}
+
+// String formats the host info as URL, e.g. "udp://192.168.1.10:3671".
+func (info HostInfo) String() string {
+	return fmt.Sprintf("%v://%v:%d", info.Protocol, info.Address, info.Port)
+}
+
 // Size returns the packed size.
 func (HostInfo) Size() uint {
 	return 8
@@ -44,6 +44,65 @@ func TestAddress_String(t *testing.T) {
 	})
 }
 
+func TestAddress_IsMulticast(t *testing.T) {
+	testCases := []struct {
+		Addr      Address
+		Multicast bool
+	}{
+		{DefaultRoutingMulticastAddress, true},
+		{Address{224, 0, 0, 0}, true},
+		{Address{239, 255, 255, 255}, true},
+		{Address{223, 255, 255, 255}, false},
+		{Address{240, 0, 0, 0}, false},
+		{Address{192, 168, 1, 10}, false},
+		{Address{}, false},
+	}
+
+	for _, testCase := range testCases {
+		if testCase.Addr.IsMulticast() != testCase.Multicast {
+			t.Errorf("Unexpected result of IsMulticast for %v", testCase.Addr)
+		}
+
+		if ip := net.IP(testCase.Addr[:]); ip.IsMulticast() != testCase.Multicast {
+			t.Errorf("IsMulticast disagrees with the net package for %v", testCase.Addr)
+		}
+	}
+}
+
+// logRecorder collects the messages of util.Log.
+type logRecorder []string
+
+func (rec *logRecorder) Printf(format string, args ...interface{}) {
+	*rec = append(*rec, fmt.Sprintf(format, args...))
+}
+
+func TestDeviceInformationBlock_RoutingMulticastAddress(t *testing.T) {
+	var rec logRecorder
+	util.Logger = &rec
+	defer func() { util.Logger = nil }()
+
+	for _, addr := range []Address{DefaultRoutingMulticastAddress, {}} {
+		dib := DeviceInformationBlock{RoutingMulticastAddress: addr, HardwareAddr: make([]byte, 6)}
+		if _, err := new(DeviceInformationBlock).Unpack(util.AllocAndPack(&dib)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(rec) != 0 {
+		t.Errorf("Unexpected warnings %q", rec)
+	}
+
+	// Both packing and unpacking warn about a unicast address.
+	dib := DeviceInformationBlock{RoutingMulticastAddress: Address{192, 168, 1, 10}, HardwareAddr: make([]byte, 6)}
+	if _, err := new(DeviceInformationBlock).Unpack(util.AllocAndPack(&dib)); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rec) != 2 {
+		t.Errorf("Expected 2 warnings, got %q", rec)
+	}
+}
+
 func makeRandBuffer(size int) []byte {
 	buffer := make([]byte, size)
 	rand.Read(buffer)
@@ -106,6 +165,29 @@ func TestHostInfo_Pack(t *testing.T) {
 	}
 }
 
+func TestHostInfo_PackByteOrder(t *testing.T) {
+	hi := HostInfo{Protocol: UDP4, Address: Address{192, 168, 1, 82}, Port: 0x1234}
+
+	if data := util.AllocAndPack(&hi); !bytes.Equal(data, []byte{0x08, 0x01, 0xc0, 0xa8, 0x01, 0x52, 0x12, 0x34}) {
+		t.Errorf("Port is not packed in network byte order: % x", data)
+	}
+
+	// A Search Request from 192.168.1.82:3671 as captured on the wire.
+	req, err := NewSearchReq(&net.UDPAddr{IP: net.IPv4(192, 168, 1, 82), Port: 3671})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []byte{
+		0x06, 0x10, 0x02, 0x01, 0x00, 0x0e, // Header
+		0x08, 0x01, 0xc0, 0xa8, 0x01, 0x52, 0x0e, 0x57, // HPAI
+	}
+
+	if data := AllocAndPack(req); !bytes.Equal(data, expected) {
+		t.Errorf("Unexpected search request % x, expected % x", data, expected)
+	}
+}
+
 func TestHostInfoFromAddress(t *testing.T) {
 	t.Run("valid UDP4 address", func(t *testing.T) {
 		address := net.UDPAddr{
@@ -140,3 +222,38 @@ func TestHostInfoFromAddress(t *testing.T) {
 	})
 
 }
+
+func TestHostInfo_String(t *testing.T) {
+	testCases := []struct {
+		Info   HostInfo
+		String string
+	}{
+		{HostInfo{UDP4, Address{192, 168, 1, 10}, 3671}, "udp://192.168.1.10:3671"},
+		{HostInfo{TCP4, Address{10, 0, 0, 1}, 3671}, "tcp://10.0.0.1:3671"},
+		{HostInfo{Protocol: UDP4}, "udp://0.0.0.0:0"}, // Route back
+		{HostInfo{}, "unknown(0x00)://0.0.0.0:0"},
+	}
+
+	for _, testCase := range testCases {
+		if str := testCase.Info.String(); str != testCase.String {
+			t.Errorf("Unexpected string representation: %s != %s", str, testCase.String)
+		}
+	}
+}
+
+func TestHostInfo_Equal(t *testing.T) {
+	info := HostInfo{UDP4, Address{192, 168, 1, 10}, 3671}
+
+	if !info.Equal(HostInfo{UDP4, Address{192, 168, 1, 10}, 3671}) {
+		t.Error("Identical host infos should be equal")
+	}
+
+	if info.Equal(HostInfo{TCP4, Address{192, 168, 1, 10}, 3671}) {
+		t.Error("Host infos with different protocols should not be equal")
+	}
+
+	routeBack := HostInfo{Protocol: UDP4}
+	if !routeBack.Equal(HostInfo{Protocol: UDP4}) || routeBack.Equal(info) {
+		t.Error("Unexpected equality of route back host info")
+	}
+}
@@ -6,8 +6,10 @@ package knxnet
 
 import (
 	"errors"
-
 	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
 
 	"github.com/LB-00/knx-go/knx/util"
 )
@@ -15,8 +17,12 @@ import (
 // ServiceID identifies the service that is contained in a packet.
 type ServiceID uint16
 
-// String generates a string representation of the service.
+// String returns the name of the service, or its hexadecimal value if the service is unknown.
 func (srv ServiceID) String() string {
+	if name, ok := serviceNames[srv]; ok {
+		return name
+	}
+
 	return fmt.Sprintf("%#04x", uint16(srv))
 }
 
@@ -41,6 +47,66 @@ const (
 	RoutingBusyService  ServiceID = 0x0532
 )
 
+// These services are known by name, but their payloads are not implemented. Packets carrying them
+// unpack as UnknownService unless a payload is registered with RegisterService.
+const (
+	DeviceConfigReqService        ServiceID = 0x0310
+	DeviceConfigAckService        ServiceID = 0x0311
+	TunnelFeatureGetService       ServiceID = 0x0422
+	TunnelFeatureResService       ServiceID = 0x0423
+	TunnelFeatureSetService       ServiceID = 0x0424
+	TunnelFeatureInfoService      ServiceID = 0x0425
+	RoutingSystemBroadcastService ServiceID = 0x0533
+	RemoteDiagReqService          ServiceID = 0x0740
+	RemoteDiagResService          ServiceID = 0x0741
+	RemoteBasicConfigReqService   ServiceID = 0x0742
+	RemoteResetReqService         ServiceID = 0x0743
+	SecureWrapperService          ServiceID = 0x0950
+	SessionReqService             ServiceID = 0x0951
+	SessionResService             ServiceID = 0x0952
+	SessionAuthService            ServiceID = 0x0953
+	SessionStatusService          ServiceID = 0x0954
+	TimerNotifyService            ServiceID = 0x0955
+)
+
+// serviceNames maps the known services to their names.
+var serviceNames = map[ServiceID]string{
+	SearchReqService:              "SearchReq",
+	SearchResService:              "SearchRes",
+	DescrReqService:               "DescrReq",
+	DescrResService:               "DescrRes",
+	ConnReqService:                "ConnReq",
+	ConnResService:                "ConnRes",
+	ConnStateReqService:           "ConnStateReq",
+	ConnStateResService:           "ConnStateRes",
+	DiscReqService:                "DiscReq",
+	DiscResService:                "DiscRes",
+	SearchReqExtService:           "SearchReqExt",
+	SearchResExtService:           "SearchResExt",
+	DeviceConfigReqService:        "DeviceConfigReq",
+	DeviceConfigAckService:        "DeviceConfigAck",
+	TunnelReqService:              "TunnelReq",
+	TunnelResService:              "TunnelRes",
+	TunnelFeatureGetService:       "TunnelFeatureGet",
+	TunnelFeatureResService:       "TunnelFeatureRes",
+	TunnelFeatureSetService:       "TunnelFeatureSet",
+	TunnelFeatureInfoService:      "TunnelFeatureInfo",
+	RoutingIndService:             "RoutingInd",
+	RoutingLostService:            "RoutingLost",
+	RoutingBusyService:            "RoutingBusy",
+	RoutingSystemBroadcastService: "RoutingSystemBroadcast",
+	RemoteDiagReqService:          "RemoteDiagReq",
+	RemoteDiagResService:          "RemoteDiagRes",
+	RemoteBasicConfigReqService:   "RemoteBasicConfigReq",
+	RemoteResetReqService:         "RemoteResetReq",
+	SecureWrapperService:          "SecureWrapper",
+	SessionReqService:             "SessionReq",
+	SessionResService:             "SessionRes",
+	SessionAuthService:            "SessionAuth",
+	SessionStatusService:          "SessionStatus",
+	TimerNotifyService:            "TimerNotify",
+}
+
 // Service describes a KNXnet/IP service.
 type Service interface {
 	Service() ServiceID
@@ -79,9 +145,24 @@ func (us *UnknownService) Unpack(data []byte) (uint, error) {
 	return uint(copy(us.Data, data)), nil
 }
 
+// headerSize is the size of the KNXnet/IP packet header.
+const headerSize = 6
+
 // Size returns the size of a KNXnet/IP packet.
 func Size(service ServicePackable) uint {
-	return 6 + service.Size()
+	return headerSize + service.Size()
+}
+
+// FrameSize returns the total size of a KNXnet/IP frame carrying the given service, i.e. the
+// header size plus the packed size of the service. Use it to pre-size buffers for Pack. The
+// service must implement a Size method, like every ServicePackable does; for services without
+// one only the header size is returned.
+func FrameSize(s Service) uint {
+	if sized, ok := s.(interface{ Size() uint }); ok {
+		return headerSize + sized.Size()
+	}
+
+	return headerSize
 }
 
 // Pack generates a KNXnet/IP packet. Utilize Size() to determine the required size of the buffer.
@@ -100,19 +181,146 @@ func AllocAndPack(srv ServicePackable) []byte {
 	return buffer
 }
 
-// These are errors that might occur during unpacking of the header.
+// DefaultMaxFrameSize is the default limit for the size of outgoing frames. Datagrams of this size
+// fit into a typical Ethernet MTU and are therefore not fragmented, which some interfaces do not
+// handle.
+const DefaultMaxFrameSize = 1400
+
+// ErrFrameTooLarge is wrapped by the errors which reject frames that exceed the maximum frame size.
+var ErrFrameTooLarge = errors.New("frame exceeds the maximum frame size")
+
+var maxFrameSize uint32 = DefaultMaxFrameSize
+
+// SetMaxFrameSize sets the limit for the size of frames which are packed by PackFrame or sent
+// through a socket, including the KNXnet/IP header. Zero restores DefaultMaxFrameSize. The limit
+// never exceeds what the 16-bit total length of the header can describe.
+func SetMaxFrameSize(size uint) {
+	if size == 0 {
+		size = DefaultMaxFrameSize
+	} else if size > math.MaxUint16 {
+		size = math.MaxUint16
+	}
+
+	atomic.StoreUint32(&maxFrameSize, uint32(size))
+}
+
+// MaxFrameSize returns the current limit for the size of outgoing frames.
+func MaxFrameSize() uint {
+	return uint(atomic.LoadUint32(&maxFrameSize))
+}
+
+// CheckFrameSize returns an error wrapping ErrFrameTooLarge if a frame of the given size exceeds
+// the maximum frame size.
+func CheckFrameSize(size uint) error {
+	if max := MaxFrameSize(); size > max {
+		return fmt.Errorf("%w: %d bytes, at most %d bytes are allowed", ErrFrameTooLarge, size, max)
+	}
+
+	return nil
+}
+
+// PackFrame allocates a buffer and packs the KNXnet/IP packet into it, unless the packet exceeds
+// the maximum frame size.
+func PackFrame(srv ServicePackable) ([]byte, error) {
+	if err := CheckFrameSize(Size(srv)); err != nil {
+		return nil, fmt.Errorf("service %v: %w", srv.Service(), err)
+	}
+
+	return AllocAndPack(srv), nil
+}
+
+// services maps the service identifiers to factories for their payload.
+var services = map[ServiceID]func() Service{
+	SearchReqService:    func() Service { return &SearchReq{} },
+	SearchResService:    func() Service { return &SearchRes{} },
+	DescrReqService:     func() Service { return &DescriptionReq{} },
+	DescrResService:     func() Service { return &DescriptionRes{} },
+	ConnReqService:      func() Service { return &ConnReq{} },
+	ConnResService:      func() Service { return &ConnRes{} },
+	ConnStateReqService: func() Service { return &ConnStateReq{} },
+	ConnStateResService: func() Service { return &ConnStateRes{} },
+	DiscReqService:      func() Service { return &DiscReq{} },
+	DiscResService:      func() Service { return &DiscRes{} },
+	SearchReqExtService: func() Service { return &SearchReqExt{} },
+	SearchResExtService: func() Service { return &SearchResExt{} },
+	TunnelReqService:    func() Service { return &TunnelReq{} },
+	TunnelResService:    func() Service { return &TunnelRes{} },
+	RoutingIndService:   func() Service { return &RoutingInd{} },
+	RoutingLostService:  func() Service { return &RoutingLost{} },
+	RoutingBusyService:  func() Service { return &RoutingBusy{} },
+}
+
+var servicesMu sync.RWMutex
+
+// RegisterService registers a factory for the payload of the given service. Unpack uses it to
+// create the payload of packets carrying that service, which allows handling services this package
+// does not implement, e.g. vendor extensions. The services created by the factory must implement
+// util.Unpackable in order to be unpacked. Built-in services may be replaced, a nil factory removes
+// the registration.
+func RegisterService(id ServiceID, factory func() Service) {
+	servicesMu.Lock()
+	defer servicesMu.Unlock()
+
+	if factory == nil {
+		delete(services, id)
+		return
+	}
+
+	services[id] = factory
+}
+
+// lookupService returns the registered factory for the given service.
+func lookupService(id ServiceID) func() Service {
+	servicesMu.RLock()
+	defer servicesMu.RUnlock()
+
+	return services[id]
+}
+
+// ErrNotKNXnetIP indicates that a datagram does not start with a KNXnet/IP header. Sockets
+// shared with other traffic will see this for every foreign datagram.
+var ErrNotKNXnetIP = errors.New("not a KNXnet/IP frame")
+
+// These are errors that might occur during unpacking of the header. Both wrap ErrNotKNXnetIP.
 var (
-	ErrHeaderLength  = errors.New("header length is not 6")
-	ErrHeaderVersion = errors.New("protocol version is not 16")
+	ErrHeaderLength  = fmt.Errorf("%w: header length is not 6", ErrNotKNXnetIP)
+	ErrHeaderVersion = fmt.Errorf("%w: protocol version is not 16", ErrNotKNXnetIP)
 )
 
-type serviceUnpackable interface {
+// ServiceMismatchError indicates that a frame carries another service than the one it is unpacked
+// as, e.g. a SearchRes from a noncompliant server where a SearchResExt is expected.
+type ServiceMismatchError struct {
+	Expected ServiceID
+	Actual   ServiceID
+}
+
+// Error describes the mismatch and names the type which the frame must be unpacked as.
+func (err *ServiceMismatchError) Error() string {
+	name := "UnknownService"
+	if factory := lookupService(err.Actual); factory != nil {
+		name = fmt.Sprintf("%T", factory())
+	}
+
+	return fmt.Sprintf("expected service %v, got service %v which unpacks as %s", err.Expected, err.Actual, name)
+}
+
+// ServiceUnpackable is a service which can be unpacked from its payload.
+type ServiceUnpackable interface {
 	util.Unpackable
 	Service
 }
 
 // UnpackHeader extracts information from the KNXnet/IP packet header.
 func UnpackHeader(data []byte, serviceID *ServiceID, totalLen *uint16) (uint, error) {
+	// Reject foreign datagrams before attempting to parse anything else.
+	if len(data) < 1 || data[0] != 6 {
+		return 0, ErrHeaderLength
+	}
+
+	if len(data) > 1 && data[1] != 16 {
+		return 0, ErrHeaderVersion
+	}
+
 	var headerLen, version uint8
 
 	n, err := util.UnpackSome(data, &headerLen, &version, (*uint16)(serviceID), totalLen)
@@ -161,68 +369,72 @@ func Unpack(data []byte, srv *Service) (uint, error) {
 		return n, err
 	}
 
-	var body serviceUnpackable
-	switch srvID {
-	case SearchReqService:
-		body = &SearchReq{}
-
-	case SearchResService:
-		body = &SearchRes{}
-
-	case DescrReqService:
-		body = &DescriptionReq{}
-
-	case DescrResService:
-		body = &DescriptionRes{}
-
-	case ConnReqService:
-		body = &ConnReq{}
-
-	case ConnResService:
-		body = &ConnRes{}
-
-	case ConnStateReqService:
-		body = &ConnStateReq{}
-
-	case ConnStateResService:
-		body = &ConnStateRes{}
-
-	case DiscReqService:
-		body = &DiscReq{}
+	var body ServiceUnpackable
+	if factory := lookupService(srvID); factory != nil {
+		srv, ok := factory().(ServiceUnpackable)
+		if !ok {
+			return n, fmt.Errorf("registered service %v cannot be unpacked", srvID)
+		}
+		body = srv
+	} else {
+		body = &UnknownService{service: srvID}
+	}
 
-	case DiscResService:
-		body = &DiscRes{}
+	m, err := body.Unpack(data[n:])
 
-	case SearchReqExtService:
-		body = &SearchReqExt{}
+	if err == nil {
+		*srv = body
+	}
 
-	case SearchResExtService:
-		body = &SearchResExt{}
+	return n + m, err
+}
 
-	case TunnelReqService:
-		body = &TunnelReq{}
+// UnpackAs parses a KNXnet/IP packet into the given service. The service announced by the header
+// must match, otherwise a ServiceMismatchError is returned, which names the type the packet unpacks
+// as. This suits receive paths which expect a particular service, where a noncompliant server may
+// answer with a similar one, e.g. with a SearchRes instead of a SearchResExt. The payloads of both
+// look alike, so the mismatch could not be detected from the payload alone.
+func UnpackAs(data []byte, srv ServiceUnpackable) (uint, error) {
+	var srvID ServiceID
+	var totalLen uint16
 
-	case TunnelResService:
-		body = &TunnelRes{}
+	n, err := UnpackHeader(data, &srvID, &totalLen)
+	if err != nil {
+		return n, err
+	}
 
-	case RoutingIndService:
-		body = &RoutingInd{}
+	if srvID != srv.Service() {
+		return n, &ServiceMismatchError{Expected: srv.Service(), Actual: srvID}
+	}
 
-	case RoutingLostService:
-		body = &RoutingLost{}
+	m, err := srv.Unpack(data[n:])
+	return n + m, err
+}
 
-	case RoutingBusyService:
-		body = &RoutingBusy{}
+// Receive parses a single KNXnet/IP datagram and returns its service payload, which is one of
+// the service types of this package, a registered service or an UnknownService. It is the
+// counterpart to sending a raw datagram and suits custom receive loops or captured traffic. The
+// datagram must be at least as long as the total length given in its header; bytes beyond it
+// are ignored.
+func Receive(data []byte) (Service, error) {
+	var srvID ServiceID
+	var totalLen uint16
 
-	default:
-		body = &UnknownService{service: srvID}
+	if _, err := UnpackHeader(data, &srvID, &totalLen); err != nil {
+		return nil, err
 	}
 
-	m, err := body.Unpack(data[n:])
+	if int(totalLen) < headerSize || int(totalLen) > len(data) {
+		return nil, fmt.Errorf(
+			"total length %d of service %v does not fit the datagram of %d bytes",
+			totalLen, srvID, len(data),
+		)
+	}
 
-	if err == nil {
-		*srv = body
+	var srv Service
+	if _, err := Unpack(data[:totalLen], &srv); err != nil {
+		return nil, err
 	}
 
-	return n + m, err
+	return srv, nil
 }
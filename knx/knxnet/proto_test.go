@@ -4,7 +4,10 @@
 package knxnet
 
 import (
+	"errors"
+	"math/rand"
 	"testing"
+	"time"
 
 	"github.com/LB-00/knx-go/knx/cemi"
 	"github.com/LB-00/knx-go/knx/util"
@@ -35,3 +38,304 @@ func BenchmarkPack(b *testing.B) {
 		util.AllocAndPack(req)
 	}
 }
+
+func TestFrameSize(t *testing.T) {
+	t.Run("SearchReq", func(t *testing.T) {
+		req := &SearchReq{HostInfo: HostInfo{Protocol: UDP4, Port: 3671}}
+
+		if size := FrameSize(req); size != 14 {
+			t.Errorf("Unexpected frame size: %d", size)
+		}
+
+		if size := FrameSize(req); size != uint(len(AllocAndPack(req))) {
+			t.Errorf("Frame size does not match packed size: %d", size)
+		}
+	})
+
+	t.Run("SearchResExt", func(t *testing.T) {
+		res := &SearchResExt{
+			Control: HostInfo{Protocol: UDP4, Port: 3671},
+			DIBs: []DIB{
+				&DeviceInformationBlock{
+					Type:         DescriptionTypeDeviceInfo,
+					HardwareAddr: make([]byte, 6),
+				},
+				&SupportedServicesDIB{
+					Type:     DescriptionTypeSupportedServiceFamilies,
+					Families: []ServiceFamily{{Type: ServiceFamilyTypeIPCore, Version: 1}},
+				},
+			},
+		}
+
+		if size := FrameSize(res); size != 6+8+54+4 {
+			t.Errorf("Unexpected frame size: %d", size)
+		}
+
+		if size := FrameSize(res); size != uint(len(AllocAndPack(res))) {
+			t.Errorf("Frame size does not match packed size: %d", size)
+		}
+	})
+}
+
+// vendorService is a service which is not implemented by this package.
+type vendorService struct {
+	Data []byte
+}
+
+const vendorServiceID ServiceID = 0x0f01
+
+func (vendorService) Service() ServiceID {
+	return vendorServiceID
+}
+
+func (srv *vendorService) Size() uint {
+	return uint(len(srv.Data))
+}
+
+func (srv *vendorService) Pack(buffer []byte) {
+	copy(buffer, srv.Data)
+}
+
+func (srv *vendorService) Unpack(data []byte) (uint, error) {
+	srv.Data = make([]byte, len(data))
+	return uint(copy(srv.Data, data)), nil
+}
+
+func TestRegisterService(t *testing.T) {
+	RegisterService(vendorServiceID, func() Service { return &vendorService{} })
+	defer RegisterService(vendorServiceID, nil)
+
+	data := AllocAndPack(&vendorService{Data: []byte{0x13, 0x37}})
+
+	var srv Service
+	if _, err := Unpack(data, &srv); err != nil {
+		t.Fatal(err)
+	}
+
+	vendor, ok := srv.(*vendorService)
+	if !ok {
+		t.Fatalf("Unexpected service type: %T", srv)
+	}
+
+	if len(vendor.Data) != 2 || vendor.Data[0] != 0x13 || vendor.Data[1] != 0x37 {
+		t.Errorf("Unexpected service data: %v", vendor.Data)
+	}
+
+	// Without a registration, the service is unknown.
+	RegisterService(vendorServiceID, nil)
+
+	if _, err := Unpack(data, &srv); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := srv.(*UnknownService); !ok {
+		t.Fatalf("Unexpected service type: %T", srv)
+	}
+}
+
+func TestUnpack_NotKNXnetIP(t *testing.T) {
+	rand.Seed(time.Now().UnixNano())
+
+	for i := 0; i < 100; i++ {
+		data := make([]byte, 1+rand.Intn(64))
+		rand.Read(data)
+
+		// Make sure the datagram does not accidentally look like KNXnet/IP.
+		if data[0] == 6 {
+			data[0] = 0x42
+		}
+
+		var srv Service
+		if _, err := Unpack(data, &srv); !errors.Is(err, ErrNotKNXnetIP) {
+			t.Fatalf("Unpacking %x yielded %v, expected ErrNotKNXnetIP", data, err)
+		}
+	}
+
+	// Correct header length, wrong version
+	var srv Service
+	if _, err := Unpack([]byte{6, 0x20, 0x02, 0x01, 0, 6}, &srv); !errors.Is(err, ErrHeaderVersion) ||
+		!errors.Is(err, ErrNotKNXnetIP) {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	if _, err := Unpack(nil, &srv); !errors.Is(err, ErrNotKNXnetIP) {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	// Valid header should not be rejected as foreign.
+	data := AllocAndPack(&DiscRes{Channel: 1})
+	if _, err := Unpack(data, &srv); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
+
+func TestReceive(t *testing.T) {
+	// A tunnelling acknowledgement for channel 1 and sequence number 2.
+	srv, err := Receive([]byte{0x06, 0x10, 0x04, 0x21, 0x00, 0x0a, 0x04, 0x01, 0x02, 0x00})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res, ok := srv.(*TunnelRes); !ok || res.Channel != 1 || res.SeqNumber != 2 || res.Status != 0 {
+		t.Errorf("Unexpected service: %#v", srv)
+	}
+
+	// A connection state request for channel 7 with a trailing byte.
+	srv, err = Receive([]byte{
+		0x06, 0x10, 0x02, 0x07, 0x00, 0x10, 0x07, 0x00,
+		0x08, 0x01, 0xc0, 0xa8, 0x01, 0x52, 0x0e, 0x57, 0xff,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if req, ok := srv.(*ConnStateReq); !ok || req.Channel != 7 || req.Control.Port != 3671 {
+		t.Errorf("Unexpected service: %#v", srv)
+	}
+
+	// A disconnect response with status E_CONNECTION_ID.
+	srv, err = Receive([]byte{0x06, 0x10, 0x02, 0x0a, 0x00, 0x08, 0x03, 0x21})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res, ok := srv.(*DiscRes); !ok || res.Channel != 3 || res.Status != 0x21 {
+		t.Errorf("Unexpected service: %#v", srv)
+	}
+
+	// Unsupported services are returned as is.
+	srv, err = Receive([]byte{0x06, 0x10, 0x0f, 0x02, 0x00, 0x07, 0x42})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if unknown, ok := srv.(*UnknownService); !ok || unknown.Service() != 0x0f02 ||
+		len(unknown.Data) != 1 || unknown.Data[0] != 0x42 {
+		t.Errorf("Unexpected service: %#v", srv)
+	}
+
+	// The datagram is shorter than announced.
+	if _, err := Receive([]byte{0x06, 0x10, 0x02, 0x0a, 0x00, 0x08, 0x03}); err == nil {
+		t.Error("Should not succeed")
+	}
+
+	if _, err := Receive([]byte{0x42}); !errors.Is(err, ErrNotKNXnetIP) {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
+
+func TestPackFrame_MaxFrameSize(t *testing.T) {
+	// Manufacturer DIBs are at most 255 bytes each, but a server may send several of them.
+	res := &SearchResExt{Control: HostInfo{Protocol: UDP4, Address: Address{192, 168, 1, 10}, Port: 3671}}
+	for i := 0; i < 6; i++ {
+		res.DIBs = append(res.DIBs, &ManufacturerDataDIB{
+			Type: DescriptionTypeManufacturerData,
+			ID:   0x00c5,
+			Data: make([]byte, 250),
+		})
+	}
+
+	if Size(res) <= DefaultMaxFrameSize {
+		t.Fatalf("Frame of %d bytes does not exceed the limit", Size(res))
+	}
+
+	if _, err := PackFrame(res); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	res.DIBs = res.DIBs[:5]
+
+	data, err := PackFrame(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if uint(len(data)) != Size(res) {
+		t.Errorf("Packed %d bytes, expected %d", len(data), Size(res))
+	}
+
+	// The limit is configurable.
+	SetMaxFrameSize(Size(res) - 1)
+	defer SetMaxFrameSize(0)
+
+	if _, err := PackFrame(res); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	sock, err := DialTunnelUDP("127.0.0.1:3671")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sock.Close()
+
+	if err := sock.Send(res); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	if err := sock.SendRaw(data); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	SetMaxFrameSize(0)
+	if MaxFrameSize() != DefaultMaxFrameSize {
+		t.Errorf("Limit %d has not been restored", MaxFrameSize())
+	}
+}
+
+func TestServiceID_String(t *testing.T) {
+	names := map[ServiceID]string{
+		SearchReqService:              "SearchReq",
+		SearchResService:              "SearchRes",
+		DescrReqService:               "DescrReq",
+		DescrResService:               "DescrRes",
+		ConnReqService:                "ConnReq",
+		ConnResService:                "ConnRes",
+		ConnStateReqService:           "ConnStateReq",
+		ConnStateResService:           "ConnStateRes",
+		DiscReqService:                "DiscReq",
+		DiscResService:                "DiscRes",
+		SearchReqExtService:           "SearchReqExt",
+		SearchResExtService:           "SearchResExt",
+		DeviceConfigReqService:        "DeviceConfigReq",
+		DeviceConfigAckService:        "DeviceConfigAck",
+		TunnelReqService:              "TunnelReq",
+		TunnelResService:              "TunnelRes",
+		TunnelFeatureGetService:       "TunnelFeatureGet",
+		TunnelFeatureResService:       "TunnelFeatureRes",
+		TunnelFeatureSetService:       "TunnelFeatureSet",
+		TunnelFeatureInfoService:      "TunnelFeatureInfo",
+		RoutingIndService:             "RoutingInd",
+		RoutingLostService:            "RoutingLost",
+		RoutingBusyService:            "RoutingBusy",
+		RoutingSystemBroadcastService: "RoutingSystemBroadcast",
+		RemoteDiagReqService:          "RemoteDiagReq",
+		RemoteDiagResService:          "RemoteDiagRes",
+		RemoteBasicConfigReqService:   "RemoteBasicConfigReq",
+		RemoteResetReqService:         "RemoteResetReq",
+		SecureWrapperService:          "SecureWrapper",
+		SessionReqService:             "SessionReq",
+		SessionResService:             "SessionRes",
+		SessionAuthService:            "SessionAuth",
+		SessionStatusService:          "SessionStatus",
+		TimerNotifyService:            "TimerNotify",
+	}
+
+	for srv, name := range names {
+		if str := srv.String(); str != name {
+			t.Errorf("Service %#04x is named %q, expected %q", uint16(srv), str, name)
+		}
+	}
+
+	// Every service which can be unpacked has a name.
+	servicesMu.RLock()
+	for srv := range services {
+		if _, ok := names[srv]; !ok {
+			t.Errorf("Service %v is missing in the test", srv)
+		}
+	}
+	servicesMu.RUnlock()
+
+	if str := ServiceID(0x0fff).String(); str != "0x0fff" {
+		t.Errorf("Unknown service is named %q", str)
+	}
+}
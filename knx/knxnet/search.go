@@ -5,6 +5,7 @@ package knxnet
 import (
 	"errors"
 	"fmt"
+	"io"
 	"net"
 
 	"github.com/LB-00/knx-go/knx/util"
@@ -37,6 +38,10 @@ func (SearchReq) Service() ServiceID {
 type SearchRes struct {
 	Control      HostInfo
 	DescriptionB DescriptionBlock
+
+	// Sender is the address the response has been received from. It is not part of the packet,
+	// but needed to reach servers that ask for route back in their control endpoint.
+	Sender *net.UDPAddr
 }
 
 // Service returns the service identifier for the Search Response.
@@ -46,17 +51,75 @@ func (SearchRes) Service() ServiceID {
 
 // Size returns the packed size.
 func (res SearchRes) Size() uint {
-	return res.Control.Size() + res.DescriptionB.DeviceHardware.Size() + res.DescriptionB.SupportedServices.Size()
+	return res.Control.Size() + res.DescriptionB.Size()
 }
 
 // Pack assembles the Search Response structure in the given buffer.
 func (res *SearchRes) Pack(buffer []byte) {
-	util.PackSome(buffer, res.Control, res.DescriptionB.DeviceHardware, res.DescriptionB.SupportedServices)
+	res.Control.Pack(buffer)
+	res.DescriptionB.Pack(buffer[res.Control.Size():])
 }
 
 // Unpack parses the given service payload in order to initialize the Search Response structure.
 func (res *SearchRes) Unpack(data []byte) (n uint, err error) {
-	return util.UnpackSome(data, &res.Control, &res.DescriptionB.DeviceHardware, &res.DescriptionB.SupportedServices)
+	if n, err = res.Control.Unpack(data); err != nil {
+		return
+	}
+
+	m, err := res.DescriptionB.Unpack(data[n:])
+	return n + m, err
+}
+
+// serialNumberOffset is the offset of the serial number in the Device Information DIB.
+const serialNumberOffset = 8
+
+// SearchResSerialNumber extracts the serial number of the responding server from a Search Response
+// datagram. Unlike unpacking the response, it only reads the header, the length of the control
+// endpoint and the headers of the DIBs up to the Device Information DIB, which makes telling
+// responders apart cheap. A datagram of another service yields a ServiceMismatchError.
+func SearchResSerialNumber(datagram []byte) (DeviceSerialNumber, error) {
+	var sn DeviceSerialNumber
+	var srvID ServiceID
+	var totalLen uint16
+
+	n, err := UnpackHeader(datagram, &srvID, &totalLen)
+	if err != nil {
+		return sn, err
+	}
+
+	if srvID != SearchResService {
+		return sn, &ServiceMismatchError{Expected: SearchResService, Actual: srvID}
+	}
+
+	if uint(totalLen) < uint(len(datagram)) {
+		datagram = datagram[:totalLen]
+	}
+
+	// Skip the control endpoint.
+	if n >= uint(len(datagram)) {
+		return sn, io.ErrUnexpectedEOF
+	}
+	n += uint(datagram[n])
+
+	for count := 0; n < uint(len(datagram)); count++ {
+		length, ty, err := nextDIB(datagram, n, count)
+		if err != nil {
+			return sn, err
+		}
+
+		if ty == DescriptionTypeDeviceInfo {
+			if length < serialNumberOffset+uint8(len(sn)) {
+				return sn, fmt.Errorf("device information DIB is too short: %w", io.ErrUnexpectedEOF)
+			}
+
+			copy(sn[:], datagram[n+serialNumberOffset:])
+			return sn, nil
+		}
+
+		n += uint(length)
+	}
+
+	return sn, fmt.Errorf("%w: device information is missing", ErrMissingMandatoryDIB)
 }
 
 // NewSearchReqExt creates a new SearchReqExt, addr defines where KNXnet/IP server should send the response to, and params are the optional SRP blocks.
@@ -122,31 +185,29 @@ func (req *SearchReqExt) Pack(buffer []byte) {
 
 // Unpack parses the given service payload in order to initialize the Search Request Extended structure.
 func (req *SearchReqExt) Unpack(data []byte) (n uint, err error) {
-	var length uint8
-	if n, err = util.UnpackSome(
-		data,
-		&length, &req.Control,
-	); err != nil {
+	if n, err = req.Control.Unpack(data); err != nil {
 		return
 	}
 
-	if length < 6 {
-		return n, errors.New("invalid length for SearchReqExt structure")
-	}
-
+	// The remainder of the payload consists of SRP blocks. A request without any is a plain
+	// search which all servers answer.
 	req.Parameters = make([]SRPBlock, 0)
-	for n < uint(length) {
-		var param SRPBlock
-		var ty ParameterType
-		var paramLength uint8
+	for n < uint(len(data)) {
+		if uint(len(data))-n < 2 {
+			return n, io.ErrUnexpectedEOF
+		}
 
-		nn, err := util.UnpackSome(data[n:], &paramLength, &ty)
-		if err != nil {
-			return n, err
+		// The length includes the header. Lengths which are too short would never advance n, those
+		// which are too long exceed the payload.
+		paramLength := uint(data[n])
+		if paramLength < 2 {
+			return n, fmt.Errorf("invalid length %d for SRP block", paramLength)
+		} else if n+paramLength > uint(len(data)) {
+			return n, fmt.Errorf("SRP block length %d exceeds the remaining %d bytes", paramLength, uint(len(data))-n)
 		}
-		n += nn
 
-		switch ty {
+		var param SRPBlock
+		switch ParameterType(data[n+1] & 0x7F) {
 		case ParameterTypeSelectProgMode:
 			param = &SelectProgMode{}
 		case ParameterTypeSelectMACAddr:
@@ -156,20 +217,20 @@ func (req *SearchReqExt) Unpack(data []byte) (n uint, err error) {
 		case ParameterTypeRequestDIBs:
 			param = &RequestDIBs{}
 		default:
-			fmt.Printf("Found unsupported parameter type: %d\n", ty)
+			// Skip parameters we don't know about.
+			n += paramLength
 			continue
 		}
 
-		nn, err = param.Unpack(data[n : n+uint(paramLength)])
-		if err != nil {
-			return 0, err
+		if _, err = param.Unpack(data[n : n+paramLength]); err != nil {
+			return n, err
 		}
-		n += nn
+		n += paramLength
 
 		req.Parameters = append(req.Parameters, param)
 	}
 
-	return uint(length), nil
+	return n, nil
 }
 
 // SRPBlock represents a Search Request Parameter (SRP) Block used to transfer
@@ -374,6 +435,14 @@ func NewRequestDIBs(mandatory bool, descTypes ...DescriptionType) *RequestDIBs {
 	}
 }
 
+// NewRequestAllDIBs creates a new Request DIBs SRP which requests all standard DIBs.
+func NewRequestAllDIBs(mandatory bool) *RequestDIBs {
+	descTypes := make([]DescriptionType, len(standardDescriptionTypes))
+	copy(descTypes, standardDescriptionTypes)
+
+	return NewRequestDIBs(mandatory, descTypes...)
+}
+
 // Size returns the packed size.
 func (srp RequestDIBs) Size() uint {
 	lenDeskTypes := uint(len(srp.DescTypes))
@@ -423,8 +492,17 @@ func (srp *RequestDIBs) Unpack(data []byte) (n uint, err error) {
 	srp.Mandatory = (pld & 0x80) != 0    // MSB indicates if the SRP is mandatory.
 	srp.Type = ParameterType(pld & 0x7F) // Lower 7 bits indicate the type.
 
+	if uint(len(data)) < uint(length) || uint(length) < n {
+		return n, io.ErrUnexpectedEOF
+	}
+
 	var descTypes []DescriptionType
-	for _, b := range data[n:length] {
+	for i, b := range data[n:length] {
+		// Skip the padding which is added to an odd number of description types.
+		if b == 0 && n+uint(i) == uint(length)-1 {
+			continue
+		}
+
 		descType := DescriptionType(b)
 		descTypes = append(descTypes, descType)
 	}
@@ -437,6 +515,16 @@ func (srp *RequestDIBs) Unpack(data []byte) (n uint, err error) {
 	return uint(length), nil
 }
 
+// NewSearchResExt creates a Search Response Extended which contains the present DIBs of the block
+// in canonical order, as a KNXnet/IP server would send them. The DIBs refer to the fields of the
+// block. Unknown blocks are not included.
+func NewSearchResExt(control HostInfo, block *DescriptionBlock) *SearchResExt {
+	return &SearchResExt{
+		Control: control,
+		DIBs:    block.presentDIBs(),
+	}
+}
+
 // A SearchResExt is a Search Response Extended from a KNXnet/IP server.
 type SearchResExt struct {
 	Control HostInfo
@@ -452,7 +540,9 @@ func (SearchResExt) Service() ServiceID {
 func (res SearchResExt) Size() uint {
 	size := res.Control.Size()
 	for _, dib := range res.DIBs {
-		size += dib.Size()
+		if !isNilDIB(dib) {
+			size += dib.Size()
+		}
 	}
 	return size
 }
@@ -462,16 +552,47 @@ func (res *SearchResExt) Pack(buffer []byte) {
 	offset := res.Control.Size()
 	res.Control.Pack(buffer[:offset])
 
-	// Pack each DIB.
+	// Pack each DIB. Nil entries are skipped, as they have no representation.
 	for _, dib := range res.DIBs {
+		if isNilDIB(dib) {
+			continue
+		}
+
 		dib.Pack(buffer[offset:])
 		offset += dib.Size()
 	}
 }
 
+// Description collects the DIBs of the response in a DescriptionBlock.
+func (res *SearchResExt) Description() (*DescriptionBlock, error) {
+	dibs := make([]util.Packable, 0, len(res.DIBs))
+	for _, dib := range res.DIBs {
+		if !isNilDIB(dib) {
+			dibs = append(dibs, dib)
+		}
+	}
+
+	block := &DescriptionBlock{}
+	if _, err := block.Unpack(util.AllocAndPack(dibs...)); err != nil {
+		return nil, err
+	}
+
+	return block, nil
+}
+
+// DIB returns the first DIB of the given type, regardless of its position in the response.
+func (res *SearchResExt) DIB(t DescriptionType) (DIB, bool) {
+	for _, dib := range res.DIBs {
+		if !isNilDIB(dib) && dibType(dib) == t {
+			return dib, true
+		}
+	}
+
+	return nil, false
+}
+
 // Unpack parses the given service payload in order to initialize the Search Response Extended structure.
 func (res *SearchResExt) Unpack(data []byte) (n uint, err error) {
-
 	if n, err = util.UnpackSome(
 		data,
 		&res.Control,
@@ -479,58 +600,83 @@ func (res *SearchResExt) Unpack(data []byte) (n uint, err error) {
 		return
 	}
 
-	var length uint8
-	var ty DescriptionType
+	if err = checkDescriptionSize(data[n:]); err != nil {
+		return n, err
+	}
 
-	// Unpack each DIB.
-	for n < uint(len(data)) {
-		_, err := util.UnpackSome(data[n:], &length, (*uint8)(&ty))
+	// Unpack each DIB. DIBs of a previous response are dropped.
+	res.DIBs = nil
+	for count := 0; n < uint(len(data)); count++ {
+		length, ty, err := nextDIB(data, n, count)
 		if err != nil {
 			return n, err
 		}
 
 		var dib DIB
+		if factory, _, ok := lookupDIB(ty); ok {
+			dib = factory()
+		} else {
+			// Keep unsupported DIBs as they are, so that they survive a round trip.
+			util.Log(res, "Found unsupported DIB %v", ty)
+			dib = &UnknownDescriptionBlock{}
+		}
 
-		switch ty {
-		case DescriptionTypeDeviceInfo:
-			dib = &DeviceInformationBlock{}
-
-		case DescriptionTypeSupportedServiceFamilies:
-			dib = &SupportedServicesDIB{}
-
-		case DescriptionTypeIPConfig:
-			dib = &IPConfigDIB{}
-
-		case DescriptionTypeIPCurrentConfig:
-			dib = &IPCurrentConfigDIB{}
-
-		case DescriptionTypeKNXAddresses:
-			dib = &KNXAddrsDIB{}
+		_, err = dib.Unpack(data[n : n+uint(length)])
+		if err != nil {
+			return n, err
+		}
+		n += uint(length)
+		res.DIBs = append(res.DIBs, dib)
+	}
 
-		case DescriptionTypeSecuredServiceFamilies:
-			dib = &SecuredServicesDIB{}
+	return
+}
 
-		case DescriptionTypeTunnellingInfo:
-			dib = &TunnellingInfoDIB{}
+// MergeSearchResExt combines the DIBs of Search Responses Extended which a single device has
+// spread over several responses. All responses must share the control endpoint and, where they
+// include a Device Information DIB, the serial number. DIBs are de-duplicated by their type, the
+// first occurrence wins.
+func MergeSearchResExt(responses ...SearchResExt) (SearchResExt, error) {
+	if len(responses) == 0 {
+		return SearchResExt{}, errors.New("no responses to merge")
+	}
 
-		case DescriptionTypeExtendedDeviceInfo:
-			dib = &ExtendedDeviceInfoDIB{}
+	merged := SearchResExt{Control: responses[0].Control}
 
-		case DescriptionTypeManufacturerData:
-			dib = &ManufacturerDataDIB{}
+	var serial *DeviceSerialNumber
+	seen := make(map[DescriptionType]bool)
 
-		default:
-			fmt.Printf("Found unsupported DIB with code: 0x%02x", uint8(ty))
-			continue
+	for _, res := range responses {
+		if res.Control != merged.Control {
+			return SearchResExt{}, fmt.Errorf(
+				"control endpoint %v differs from %v", res.Control, merged.Control,
+			)
 		}
 
-		_, err = dib.Unpack(data[n : n+uint(length)])
-		if err != nil {
-			return 0, err
+		for _, dib := range res.DIBs {
+			if isNilDIB(dib) {
+				continue
+			}
+
+			if info, ok := dib.(*DeviceInformationBlock); ok {
+				if serial == nil {
+					serial = &info.SerialNumber
+				} else if info.SerialNumber != *serial {
+					return SearchResExt{}, fmt.Errorf(
+						"serial number %x differs from %x", info.SerialNumber, *serial,
+					)
+				}
+			}
+
+			ty := dibType(dib)
+			if seen[ty] {
+				continue
+			}
+
+			seen[ty] = true
+			merged.DIBs = append(merged.DIBs, dib)
 		}
-		n += uint(length)
-		res.DIBs = append(res.DIBs, dib)
 	}
 
-	return
+	return merged, nil
 }
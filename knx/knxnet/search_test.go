@@ -0,0 +1,580 @@
+// Licensed under the MIT license which can be found in the LICENSE file.
+
+package knxnet
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/LB-00/knx-go/knx/util"
+)
+
+// makeSearchResExtPayload assembles the service payload of a Search Response Extended.
+func makeSearchResExtPayload() []byte {
+	control := HostInfo{Protocol: UDP4, Address: Address{192, 168, 1, 10}, Port: 3671}
+	return append(util.AllocAndPack(&control), makeDescriptionPayload()...)
+}
+
+func BenchmarkSearchResExtUnpack(b *testing.B) {
+	b.ReportAllocs()
+
+	data := makeSearchResExtPayload()
+
+	for i := 0; i < b.N; i++ {
+		var res SearchResExt
+		res.Unpack(data)
+	}
+}
+
+func TestSearchResExt_DIB(t *testing.T) {
+	control := HostInfo{Protocol: UDP4, Address: Address{192, 168, 1, 10}, Port: 3671}
+
+	// DIBs in an order which differs from the one of Description Responses.
+	data := util.AllocAndPack(
+		&control,
+		&TunnellingInfoDIB{
+			Type:     DescriptionTypeTunnellingInfo,
+			APDUSize: 254,
+		},
+		&SupportedServicesDIB{
+			Type:     DescriptionTypeSupportedServiceFamilies,
+			Families: []ServiceFamily{{Type: ServiceFamilyTypeIPTunnelling, Version: 2}},
+		},
+		&DeviceInformationBlock{
+			Type:         DescriptionTypeDeviceInfo,
+			Medium:       KNXMediumTP1,
+			HardwareAddr: make([]byte, 6),
+			FriendlyName: "KNX IP Interface",
+		},
+	)
+
+	var res SearchResExt
+	if _, err := res.Unpack(data); err != nil {
+		t.Fatal(err)
+	}
+
+	dib, ok := res.DIB(DescriptionTypeDeviceInfo)
+	if !ok {
+		t.Fatal("Device information DIB not found")
+	}
+
+	if device, ok := dib.(*DeviceInformationBlock); !ok || device.FriendlyName != "KNX IP Interface" {
+		t.Errorf("Unexpected DIB: %#v", dib)
+	}
+
+	dib, ok = res.DIB(DescriptionTypeTunnellingInfo)
+	if !ok {
+		t.Fatal("Tunnelling information DIB not found")
+	}
+
+	if info, ok := dib.(*TunnellingInfoDIB); !ok || info.APDUSize != 254 {
+		t.Errorf("Unexpected DIB: %#v", dib)
+	}
+
+	if _, ok := res.DIB(DescriptionTypeIPConfig); ok {
+		t.Error("IP config DIB should not be found")
+	}
+
+	// DIBs not implemented by this package are found as well.
+	res.DIBs = append(res.DIBs, &vendorDIB{Value: 0x1337})
+
+	if dib, ok := res.DIB(vendorDescriptionType); !ok || dib.(*vendorDIB).Value != 0x1337 {
+		t.Errorf("Unexpected DIB: %#v", dib)
+	}
+}
+
+func TestSearchReqExt_NoParameters(t *testing.T) {
+	req, err := NewSearchReqExt(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if req.Size() != req.Control.Size() {
+		t.Errorf("Size is %d, expected only the host info (%d)", req.Size(), req.Control.Size())
+	}
+
+	data := AllocAndPack(req)
+	if len(data) != 6+8 {
+		t.Fatalf("Packed %d bytes, expected 14", len(data))
+	}
+
+	var srv Service
+	n, err := Unpack(data, &srv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != uint(len(data)) {
+		t.Errorf("Unpacked %d of %d bytes", n, len(data))
+	}
+
+	res, ok := srv.(*SearchReqExt)
+	if !ok {
+		t.Fatalf("Unexpected service %T", srv)
+	}
+
+	if !res.Control.Equal(req.Control) {
+		t.Errorf("Control is %v, expected %v", res.Control, req.Control)
+	}
+
+	if len(res.Parameters) != 0 {
+		t.Errorf("Unexpected parameters %v", res.Parameters)
+	}
+}
+
+func TestSearchReqExt_Unpack(t *testing.T) {
+	control := HostInfo{Protocol: UDP4}
+
+	// Unknown parameters are skipped.
+	data := append(util.AllocAndPack(&control), 4, 0x7F, 0, 0)
+	data = append(data, util.AllocAndPack(&RequestDIBs{
+		Type:      ParameterTypeRequestDIBs,
+		DescTypes: []DescriptionType{DescriptionTypeDeviceInfo},
+	})...)
+
+	var req SearchReqExt
+	n, err := req.Unpack(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != uint(len(data)) {
+		t.Errorf("Unpacked %d of %d bytes", n, len(data))
+	}
+
+	if len(req.Parameters) != 1 {
+		t.Fatalf("Unexpected parameters %v", req.Parameters)
+	}
+
+	if _, ok := req.Parameters[0].(*RequestDIBs); !ok {
+		t.Errorf("Unexpected parameter %T", req.Parameters[0])
+	}
+
+	for _, invalid := range [][]byte{
+		data[:7],
+		append(util.AllocAndPack(&control), 0, 0x7F),
+		append(util.AllocAndPack(&control), 1, 0x7F),
+		append(util.AllocAndPack(&control), 8, 0x7F),
+		append(util.AllocAndPack(&control), 0xFF, byte(ParameterTypeRequestDIBs), 0x01, 0x02),
+		append(util.AllocAndPack(&control), 0, byte(ParameterTypeSelectProgMode)),
+		append(util.AllocAndPack(&control), 4),
+	} {
+		if _, err := req.Unpack(invalid); err == nil {
+			t.Errorf("Unpacking %x should fail", invalid)
+		}
+	}
+}
+
+func TestSearchResExt_UnpackLimits(t *testing.T) {
+	control := HostInfo{Protocol: UDP4, Address: Address{192, 168, 1, 10}, Port: 3671}
+
+	data := util.AllocAndPack(&control)
+	for i := 0; i <= DefaultMaxDescriptionBlocks; i++ {
+		data = append(data, util.AllocAndPack(&TunnellingInfoDIB{
+			Type:     DescriptionTypeTunnellingInfo,
+			APDUSize: 254,
+		})...)
+	}
+
+	var res SearchResExt
+	if _, err := res.Unpack(data); !errors.Is(err, ErrDescriptionTooLarge) {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	data = append(util.AllocAndPack(&control), make([]byte, DefaultMaxDescriptionSize+1)...)
+	if _, err := res.Unpack(data); !errors.Is(err, ErrDescriptionTooLarge) {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
+
+func TestSearchResExt_PackNilDIB(t *testing.T) {
+	services := &SupportedServicesDIB{
+		Type:     DescriptionTypeSupportedServiceFamilies,
+		Families: []ServiceFamily{{Type: ServiceFamilyTypeIPTunnelling, Version: 2}},
+	}
+
+	res := &SearchResExt{
+		Control: HostInfo{Protocol: UDP4, Address: Address{192, 168, 1, 10}, Port: 3671},
+		DIBs:    []DIB{nil, (*TunnellingInfoDIB)(nil), services},
+	}
+
+	if _, ok := res.DIB(DescriptionTypeTunnellingInfo); ok {
+		t.Error("Nil DIB should not be found")
+	}
+
+	if res.Size() != res.Control.Size()+services.Size() {
+		t.Errorf("Size %d includes nil DIBs", res.Size())
+	}
+
+	data := util.AllocAndPack(res)
+
+	var unpacked SearchResExt
+	if _, err := unpacked.Unpack(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(unpacked.DIBs) != 1 {
+		t.Fatalf("Unexpected DIBs %v", unpacked.DIBs)
+	}
+
+	if _, ok := unpacked.DIB(DescriptionTypeSupportedServiceFamilies); !ok {
+		t.Error("Supported services DIB is missing")
+	}
+}
+
+func TestSearchRes_PackOptionalDIBs(t *testing.T) {
+	res := &SearchRes{
+		Control: HostInfo{Protocol: UDP4, Address: Address{192, 168, 1, 10}, Port: 3671},
+	}
+
+	if _, err := res.DescriptionB.Unpack(append(makeDescriptionPayload(), 0x04, 0x42, 0x13, 0x37)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Pack into a larger buffer to detect writes beyond Size.
+	size := res.Size()
+	buffer := make([]byte, size+16)
+	res.Pack(buffer)
+
+	if !bytes.Equal(buffer[size:], make([]byte, 16)) {
+		t.Errorf("Pack wrote beyond Size %d", size)
+	}
+
+	var unpacked SearchRes
+	n, err := unpacked.Unpack(buffer[:size])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != size {
+		t.Errorf("Unpacked %d of %d bytes", n, size)
+	}
+
+	if !reflect.DeepEqual(&unpacked.DescriptionB, &res.DescriptionB) {
+		t.Errorf("Round trip yielded %+v, expected %+v", unpacked.DescriptionB, res.DescriptionB)
+	}
+
+	// DIBs without a type are not packed, not even the mandatory ones. Unpacking reports them as
+	// missing instead.
+	res.DescriptionB = DescriptionBlock{}
+	if size := res.Size(); size != 8 {
+		t.Errorf("Unexpected size %d of empty search response", size)
+	}
+
+	var empty SearchRes
+	if _, err := empty.Unpack(util.AllocAndPack(res)); !errors.Is(err, ErrMissingMandatoryDIB) {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
+
+func TestSearchRes_UnpackMissingMandatoryDIB(t *testing.T) {
+	control := HostInfo{Protocol: UDP4, Address: Address{192, 168, 1, 10}, Port: 3671}
+
+	// The response carries the device information, but no supported service families.
+	payload := append(util.AllocAndPack(&control), util.AllocAndPack(&DeviceInformationBlock{
+		Type:         DescriptionTypeDeviceInfo,
+		HardwareAddr: make([]byte, 6),
+	})...)
+
+	var res SearchRes
+	if _, err := res.Unpack(payload); !errors.Is(err, ErrMissingMandatoryDIB) {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	// Both mandatory DIBs are present.
+	payload = append(util.AllocAndPack(&control), makeDescriptionPayload()...)
+
+	res = SearchRes{}
+	if _, err := res.Unpack(payload); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUnpackAs_SearchRes(t *testing.T) {
+	var res SearchRes
+	if _, err := res.Unpack(makeSearchResExtPayload()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The payloads of both responses look alike, hence unpacking one as the other succeeds.
+	var resExt SearchResExt
+	if _, err := resExt.Unpack(makeSearchResExtPayload()); err != nil {
+		t.Fatal(err)
+	}
+
+	// A noncompliant server answers a SearchReqExt with a plain SearchRes.
+	_, err := UnpackAs(AllocAndPack(&res), &resExt)
+
+	var mismatch *ServiceMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if mismatch.Expected != SearchResExtService || mismatch.Actual != SearchResService {
+		t.Errorf("Unexpected services %v and %v", mismatch.Expected, mismatch.Actual)
+	}
+
+	if msg := err.Error(); msg != "expected service SearchResExt, got service SearchRes which unpacks as *knxnet.SearchRes" {
+		t.Errorf("Unexpected message: %s", msg)
+	}
+
+	// The other way around.
+	frame := AllocAndPack(&resExt)
+
+	if _, err := UnpackAs(frame, &res); !errors.As(err, &mismatch) || mismatch.Actual != SearchResExtService {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	// The matching service is unpacked.
+	resExt = SearchResExt{}
+	if n, err := UnpackAs(frame, &resExt); err != nil || n != uint(len(frame)) {
+		t.Fatalf("Unpacked %d of %d bytes: %v", n, len(frame), err)
+	}
+
+	if _, ok := resExt.DIB(DescriptionTypeDeviceInfo); !ok {
+		t.Error("Device information DIB not found")
+	}
+}
+
+func TestNewSearchResExt(t *testing.T) {
+	control := HostInfo{Protocol: UDP4, Address: Address{192, 168, 1, 10}, Port: 3671}
+
+	var block DescriptionBlock
+	if _, err := block.Unpack(makeDescriptionPayload()); err != nil {
+		t.Fatal(err)
+	}
+
+	res := NewSearchResExt(control, &block)
+
+	// The mandatory DIBs come first.
+	if len(res.DIBs) < 2 || dibType(res.DIBs[0]) != DescriptionTypeDeviceInfo ||
+		dibType(res.DIBs[1]) != DescriptionTypeSupportedServiceFamilies {
+		t.Fatalf("Unexpected DIBs %v", res.DIBs)
+	}
+
+	var srv Service
+	if _, err := Unpack(AllocAndPack(res), &srv); err != nil {
+		t.Fatal(err)
+	}
+
+	unpacked, ok := srv.(*SearchResExt)
+	if !ok {
+		t.Fatalf("Unexpected service %T", srv)
+	}
+
+	if !unpacked.Control.Equal(control) {
+		t.Errorf("Unexpected control endpoint %v", unpacked.Control)
+	}
+
+	roundTrip, err := unpacked.Description()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(roundTrip, &block) {
+		t.Errorf("Round trip yielded %+v, expected %+v", roundTrip, block)
+	}
+}
+
+func TestNewRequestAllDIBs(t *testing.T) {
+	srp := NewRequestAllDIBs(true)
+
+	// Nine description types are padded to an even number.
+	expected := []byte{0x0C, 0x84, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0xFE, 0x00}
+
+	data := util.AllocAndPack(srp)
+	if !bytes.Equal(data, expected) {
+		t.Errorf("Packed % x, expected % x", data, expected)
+	}
+
+	var unpacked RequestDIBs
+	if _, err := unpacked.Unpack(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(&unpacked, srp) {
+		t.Errorf("Round trip yielded %+v, expected %+v", unpacked, *srp)
+	}
+}
+
+func TestSearchResExt_UnpackTruncated(t *testing.T) {
+	data := makeSearchResExtPayload()
+
+	// The last DIB claims more bytes than are present.
+	for _, truncated := range [][]byte{data[:len(data)-1], append(data, 0x10, 0x42, 0x00)} {
+		var res SearchResExt
+		if _, err := res.Unpack(truncated); !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("Unexpected error %v", err)
+		}
+	}
+}
+
+func TestMergeSearchResExt(t *testing.T) {
+	control := HostInfo{Protocol: UDP4, Address: Address{192, 168, 1, 10}, Port: 3671}
+
+	info := &DeviceInformationBlock{
+		Type:         DescriptionTypeDeviceInfo,
+		SerialNumber: DeviceSerialNumber{0x00, 0xfa, 0x12, 0x34, 0x56, 0x78},
+		HardwareAddr: make([]byte, 6),
+	}
+
+	services := &SupportedServicesDIB{
+		Type:     DescriptionTypeSupportedServiceFamilies,
+		Families: []ServiceFamily{{Type: ServiceFamilyTypeIPCore, Version: 2}},
+	}
+
+	tunnelling := &TunnellingInfoDIB{Type: DescriptionTypeTunnellingInfo, APDUSize: 254}
+
+	// The second part repeats the device information, which must not be duplicated.
+	first := SearchResExt{Control: control, DIBs: []DIB{info, services}}
+	second := SearchResExt{Control: control, DIBs: []DIB{info, tunnelling}}
+
+	merged, err := MergeSearchResExt(first, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if merged.Control != control {
+		t.Errorf("Unexpected control endpoint %v", merged.Control)
+	}
+
+	if !reflect.DeepEqual(merged.DIBs, []DIB{info, services, tunnelling}) {
+		t.Errorf("Unexpected DIBs %v", merged.DIBs)
+	}
+
+	// Responses of different devices cannot be merged.
+	other := *info
+	other.SerialNumber[5]++
+
+	if _, err := MergeSearchResExt(first, SearchResExt{Control: control, DIBs: []DIB{&other}}); err == nil {
+		t.Error("Should not succeed for different serial numbers")
+	}
+
+	otherControl := control
+	otherControl.Port++
+
+	if _, err := MergeSearchResExt(first, SearchResExt{Control: otherControl}); err == nil {
+		t.Error("Should not succeed for different control endpoints")
+	}
+
+	if _, err := MergeSearchResExt(); err == nil {
+		t.Error("Should not succeed without responses")
+	}
+}
+
+func TestSearchResExt_UnpackUnknownDIB(t *testing.T) {
+	unknown := &UnknownDescriptionBlock{Type: 0x42, Data: []byte{0x01, 0x02}}
+	data := append(makeSearchResExtPayload(), util.AllocAndPack(unknown)...)
+
+	done := make(chan struct{})
+
+	var res SearchResExt
+	var n uint
+	var err error
+	go func() {
+		defer close(done)
+		n, err = res.Unpack(data)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Unpack does not terminate")
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != uint(len(data)) {
+		t.Errorf("Unpacked %d of %d bytes", n, len(data))
+	}
+
+	dib, ok := res.DIB(0x42)
+	if !ok {
+		t.Fatal("Unknown DIB not found")
+	}
+
+	if !reflect.DeepEqual(dib, unknown) {
+		t.Errorf("Unexpected DIB %+v", dib)
+	}
+
+	if packed := util.AllocAndPack(&res); !bytes.Equal(packed, data) {
+		t.Errorf("Round trip mismatch:\n%x\n%x", packed, data)
+	}
+}
+
+func TestSearchResSerialNumber(t *testing.T) {
+	serial := DeviceSerialNumber{0x00, 0xfa, 0x12, 0x34, 0x56, 0x78}
+
+	res := &SearchRes{Control: HostInfo{Protocol: UDP4, Address: Address{192, 168, 1, 10}, Port: 3671}}
+	res.DescriptionB.DeviceHardware.Type = DescriptionTypeDeviceInfo
+	res.DescriptionB.DeviceHardware.HardwareAddr = make([]byte, 6)
+	res.DescriptionB.DeviceHardware.SerialNumber = serial
+	res.DescriptionB.SupportedServices.Type = DescriptionTypeSupportedServiceFamilies
+
+	// A malformed optional DIB, which prevents unpacking the response, is not looked at.
+	datagram := append(AllocAndPack(res), 0x03, byte(DescriptionTypeIPConfig), 0x00)
+	datagram[4], datagram[5] = 0, byte(len(datagram))
+
+	if _, err := Receive(datagram); err == nil {
+		t.Fatal("Unpacking the malformed response should not succeed")
+	}
+
+	if sn, err := SearchResSerialNumber(datagram); err != nil || sn != serial {
+		t.Errorf("Unexpected serial number %v: %v", sn, err)
+	}
+
+	// Other services are rejected.
+	req, _ := NewSearchReq(&net.UDPAddr{IP: net.IPv4(224, 0, 23, 12), Port: 3671})
+
+	var mismatch *ServiceMismatchError
+	if _, err := SearchResSerialNumber(AllocAndPack(req)); !errors.As(err, &mismatch) {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	// The device information must be complete up to the serial number.
+	for _, length := range []int{0, 6, 14, 14 + 8 + 2} {
+		if _, err := SearchResSerialNumber(datagram[:length]); err == nil {
+			t.Errorf("Truncating the response to %d bytes should not succeed", length)
+		}
+	}
+}
+
+func TestSearchResExt_UnpackAgain(t *testing.T) {
+	data := makeSearchResExtPayload()
+
+	var res SearchResExt
+	if _, err := res.Unpack(data); err != nil {
+		t.Fatal(err)
+	}
+
+	count := len(res.DIBs)
+
+	// Reusing the structure does not accumulate the DIBs of both responses.
+	if _, err := res.Unpack(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res.DIBs) != count {
+		t.Errorf("Unpacked %d DIBs, expected %d", len(res.DIBs), count)
+	}
+
+	// A broken DIB reports how far unpacking got.
+	valid := len(data)
+	data = append(data, 0x04, byte(DescriptionTypeDeviceInfo), 0x00, 0x00)
+
+	n, err := res.Unpack(data)
+	if err == nil {
+		t.Fatal("Unpacking a broken DIB should fail")
+	}
+
+	if n != uint(valid) {
+		t.Errorf("Unpacked %d bytes, expected %d", n, valid)
+	}
+}
@@ -5,6 +5,7 @@ package knxnet
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -23,12 +24,26 @@ type Socket interface {
 	LocalAddr() net.Addr
 }
 
+// A RawSocket is a Socket which can also transmit datagrams that have already been encoded.
+type RawSocket interface {
+	Socket
+	SendRaw(data []byte) error
+}
+
 // TunnelSocket is a UDP socket for KNXnet/IP packet exchange.
 type TunnelSocket struct {
-	conn    net.Conn
+	conn    tunnelConn
 	inbound <-chan Service
 }
 
+// tunnelConn is the part of a connection which a TunnelSocket uses, as the receiving is done by
+// its worker.
+type tunnelConn interface {
+	Write(buffer []byte) (int, error)
+	Close() error
+	LocalAddr() net.Addr
+}
+
 // DialTunnelUDP creates a new Socket which can used to exchange KNXnet/IP packets with a single
 // endpoint through UDP.
 func DialTunnelUDP(address string) (*TunnelSocket, error) {
@@ -114,9 +129,68 @@ func NewTunnelSocketFromConn(conn net.Conn) (*TunnelSocket, error) {
 	return &TunnelSocket{conn, inbound}, nil
 }
 
-// Send transmits a KNXnet/IP packet.
+// NewTunnelSocketFromPacketConn creates a new Socket which exchanges KNXnet/IP packets with the
+// remote address over an existing net.PacketConn, e.g. a UDP socket bound to a specific local port
+// or one with custom socket options. Datagrams from other senders are ignored. The socket takes
+// ownership of the connection and closes it when it is closed itself.
+func NewTunnelSocketFromPacketConn(conn net.PacketConn, remote net.Addr) (*TunnelSocket, error) {
+	if conn == nil {
+		return nil, fmt.Errorf("connection cannot be nil")
+	}
+
+	if remote == nil {
+		return nil, fmt.Errorf("remote address cannot be nil")
+	}
+
+	if addr, ok := remote.(*net.UDPAddr); ok && addr.IP.IsMulticast() {
+		return nil, fmt.Errorf("cannot tunnel to multicast address")
+	}
+
+	inbound := make(chan Service)
+	go servePacketConn(conn, remote, inbound)
+
+	return &TunnelSocket{&packetConnAdapter{conn, remote}, inbound}, nil
+}
+
+// packetConnAdapter sends the datagrams of a TunnelSocket through a net.PacketConn to a single
+// remote address. Receiving is left to servePacketConn.
+type packetConnAdapter struct {
+	conn   net.PacketConn
+	remote net.Addr
+}
+
+// Write sends the datagram to the remote address.
+func (adapter *packetConnAdapter) Write(buffer []byte) (int, error) {
+	return adapter.conn.WriteTo(buffer, adapter.remote)
+}
+
+// Close closes the connection.
+func (adapter *packetConnAdapter) Close() error {
+	return adapter.conn.Close()
+}
+
+// LocalAddr returns the local address of the connection.
+func (adapter *packetConnAdapter) LocalAddr() net.Addr {
+	return adapter.conn.LocalAddr()
+}
+
+// sameAddr reports whether both addresses refer to the same endpoint.
+func sameAddr(a, b net.Addr) bool {
+	if a == nil || b == nil {
+		return false
+	}
+
+	return a.Network() == b.Network() && a.String() == b.String()
+}
+
+// Send transmits a KNXnet/IP packet. Packets exceeding the maximum frame size are rejected.
 func (sock *TunnelSocket) Send(payload ServicePackable) error {
-	buffer := make([]byte, Size(payload))
+	if err := CheckFrameSize(Size(payload)); err != nil {
+		return fmt.Errorf("service %v: %w", payload.Service(), err)
+	}
+
+	buffer := util.AcquireBuffer(Size(payload))
+	defer util.ReleaseBuffer(buffer)
 	Pack(buffer, payload)
 
 	// Transmission of the buffer contents.
@@ -124,6 +198,17 @@ func (sock *TunnelSocket) Send(payload ServicePackable) error {
 	return err
 }
 
+// SendRaw transmits the given datagram as is. It is not validated, except that it must not exceed
+// the maximum frame size.
+func (sock *TunnelSocket) SendRaw(data []byte) error {
+	if err := CheckFrameSize(uint(len(data))); err != nil {
+		return err
+	}
+
+	_, err := sock.conn.Write(data)
+	return err
+}
+
 // Inbound provides a channel from which you can retrieve incoming packets.
 func (sock *TunnelSocket) Inbound() <-chan Service {
 	return sock.inbound
@@ -156,19 +241,40 @@ func ListenRouter(multicastAddress string) (*RouterSocket, error) {
 // multiple endpoints. The interface is used to send or listen for KNXnet/IP packets. If the
 // interface is nil, the system-assigned multicast interface is used.
 func ListenRouterOnInterface(ifi *net.Interface, multicastAddress string, multicastLoopbackEnabled bool) (*RouterSocket, error) {
-	addr, err := net.ResolveUDPAddr("udp4", multicastAddress)
+	conn, addr, err := ListenMulticastUDP(ifi, multicastAddress, multicastLoopbackEnabled)
 	if err != nil {
 		return nil, err
 	}
 
+	inbound := make(chan Service)
+	go serveUDPSocket(conn, nil, inbound)
+
+	return &RouterSocket{conn, addr, inbound}, nil
+}
+
+// ListenMulticastUDP joins the multicast group on the given interface and returns the plain UDP
+// connection together with the group address. Unlike a RouterSocket, the connection does not
+// unpack any datagrams, which suits callers that only need to inspect them. If the interface is
+// nil, the system-assigned multicast interface is used.
+func ListenMulticastUDP(
+	ifi *net.Interface,
+	multicastAddress string,
+	multicastLoopbackEnabled bool,
+) (*net.UDPConn, *net.UDPAddr, error) {
+	addr, err := net.ResolveUDPAddr("udp4", multicastAddress)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	conn, err := net.ListenUDP("udp4", addr)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	pc := ipv4.NewPacketConn(conn)
 
 	if err := pc.JoinGroup(ifi, addr); err != nil {
-		return nil, err
+		conn.Close()
+		return nil, nil, err
 	}
 
 	// Just for logging purposes.
@@ -184,10 +290,7 @@ func ListenRouterOnInterface(ifi *net.Interface, multicastAddress string, multic
 
 	conn.SetDeadline(time.Time{})
 
-	inbound := make(chan Service)
-	go serveUDPSocket(conn, nil, inbound)
-
-	return &RouterSocket{conn, addr, inbound}, nil
+	return conn, addr, nil
 }
 
 // Addr returns the multicast destination address.
@@ -195,9 +298,14 @@ func (sock *RouterSocket) Addr() *net.UDPAddr {
 	return sock.addr
 }
 
-// Send transmits a KNXnet/IP packet.
+// Send transmits a KNXnet/IP packet. Packets exceeding the maximum frame size are rejected.
 func (sock *RouterSocket) Send(payload ServicePackable) error {
-	buffer := make([]byte, Size(payload))
+	if err := CheckFrameSize(Size(payload)); err != nil {
+		return fmt.Errorf("service %v: %w", payload.Service(), err)
+	}
+
+	buffer := util.AcquireBuffer(Size(payload))
+	defer util.ReleaseBuffer(buffer)
 	Pack(buffer, payload)
 
 	// Transmission of the buffer contents
@@ -205,6 +313,17 @@ func (sock *RouterSocket) Send(payload ServicePackable) error {
 	return err
 }
 
+// SendRaw transmits the given datagram as is. It is not validated, except that it must not exceed
+// the maximum frame size.
+func (sock *RouterSocket) SendRaw(data []byte) error {
+	if err := CheckFrameSize(uint(len(data))); err != nil {
+		return err
+	}
+
+	_, err := sock.conn.WriteToUDP(data, sock.addr)
+	return err
+}
+
 // Inbound provides a channel from which you can retrieve incoming packets.
 func (sock *RouterSocket) Inbound() <-chan Service {
 	return sock.inbound
@@ -251,11 +370,67 @@ func serveUDPSocket(conn *net.UDPConn, addr *net.UDPAddr, inbound chan<- Service
 
 		var payload Service
 		_, err = Unpack(buffer[:len], &payload)
+		if errors.Is(err, ErrNotKNXnetIP) {
+			util.Log(conn, "Ignoring datagram: %v", err)
+			continue
+		} else if err != nil {
+			util.Log(conn, "Error during Unpack: %v", err)
+			continue
+		}
+
+		// Search responses need to know where they came from.
+		if res, ok := payload.(*SearchRes); ok {
+			res.Sender = sender
+		}
+
+		inbound <- payload
+	}
+}
+
+// servePacketConn is the receiver worker for a packet connection. Only datagrams from the remote
+// address are processed.
+func servePacketConn(conn net.PacketConn, remote net.Addr, inbound chan<- Service) {
+	util.Log(conn, "Started worker")
+	defer util.Log(conn, "Worker exited")
+
+	// A closed inbound channel indicates to its readers that the worker has terminated.
+	defer close(inbound)
+
+	buffer := [1024]byte{}
+
+	for {
+		len, sender, err := conn.ReadFrom(buffer[:])
 		if err != nil {
+			util.Log(conn, "Error during ReadFrom: %v", err)
+			return
+		}
+
+		// Discard empty frames
+		if len == 0 {
+			util.Log(conn, "Empty frame discarded")
+			continue
+		}
+
+		if !sameAddr(sender, remote) {
+			util.Log(conn, "Origin validation failed: %v != %v", remote, sender)
+			continue
+		}
+
+		var payload Service
+		_, err = Unpack(buffer[:len], &payload)
+		if errors.Is(err, ErrNotKNXnetIP) {
+			util.Log(conn, "Ignoring datagram: %v", err)
+			continue
+		} else if err != nil {
 			util.Log(conn, "Error during Unpack: %v", err)
 			continue
 		}
 
+		// Search responses need to know where they came from.
+		if res, ok := payload.(*SearchRes); ok {
+			res.Sender, _ = sender.(*net.UDPAddr)
+		}
+
 		inbound <- payload
 	}
 }
@@ -301,7 +476,10 @@ func serveTCPSocket(conn *net.TCPConn, addr *net.TCPAddr, inbound chan<- Service
 
 		var payload Service
 		_, err = Unpack(buffer[:len], &payload)
-		if err != nil {
+		if errors.Is(err, ErrNotKNXnetIP) {
+			util.Log(conn, "Ignoring datagram: %v", err)
+			continue
+		} else if err != nil {
 			util.Log(conn, "Error during Unpack: %v", err)
 			continue
 		}
@@ -335,7 +513,10 @@ func serveSocket(conn *net.Conn, inbound chan<- Service) {
 
 		var payload Service
 		_, err = Unpack(buffer[:len], &payload)
-		if err != nil {
+		if errors.Is(err, ErrNotKNXnetIP) {
+			util.Log(conn, "Ignoring datagram: %v", err)
+			continue
+		} else if err != nil {
 			util.Log(conn, "Error during Unpack: %v", err)
 			continue
 		}
@@ -10,30 +10,100 @@ import (
 	"time"
 
 	"github.com/LB-00/knx-go/knx/cemi"
+	"github.com/LB-00/knx-go/knx/dpt"
 )
 
+var (
+	// ErrNotConnected is returned when using a connection that has been closed by the caller.
+	ErrNotConnected = errors.New("not connected to device")
+
+	// ErrPeerDisconnected is returned when using a connection that has been closed by the device.
+	ErrPeerDisconnected = errors.New("connection was closed by the device")
+
+	// ErrTunnelClosed is returned when using a connection whose underlying tunnel has been closed.
+	ErrTunnelClosed = errors.New("tunnel was closed")
+
+	// ErrManagementClosed is returned when connecting through a Management that has been closed.
+	ErrManagementClosed = errors.New("management was closed")
+
+	// ErrConnectionReset is returned when a connection has been reset because the device kept
+	// rejecting a telegram with T_NAK.
+	ErrConnectionReset = errors.New("connection was reset after repeated T_NAK")
+
+	// ErrBudgetExhausted is returned when a procedure run with WithBudget has not completed in time.
+	ErrBudgetExhausted = errors.New("procedure exceeded its time budget")
+
+	// ErrShuttingDown is returned when sending over a connection or tunnel which is being shut down.
+	ErrShuttingDown = errors.New("shutting down")
+)
+
+// errNak signals that the device has rejected a telegram with a T_NAK.
+var errNak = errors.New("telegram was rejected with T_NAK")
+
+// errAckTimeout signals that the device has not acknowledged a telegram in time.
+var errAckTimeout = errors.New("timed out while waiting for ACK")
+
+// errSeqMismatch signals that the device has rejected a telegram with a T_NAK for another sequence
+// number, which means that the sequence numbers of both sides have drifted apart.
+var errSeqMismatch = errors.New("sequence numbers are out of sync")
+
+// defaultMaxAPDULength is the maximum APDU length of a standard frame. It is assumed as long as
+// the actual maximum APDU length of a device is unknown.
+const defaultMaxAPDULength = 15
+
+// defaultApplicationTimeout is how long a connection waits for the response of the device by
+// default.
+const defaultApplicationTimeout = 6 * time.Second
+
+// maxRepetitions is how often a telegram is repeated after the device rejected it with a T_NAK,
+// before the connection is reset.
+const maxRepetitions = 3
+
 // P2PConnection represents a point-to-point connection to a bus device.
 type P2PConnection struct {
-	tunnel     *Tunnel             // Underlying tunneling connection
-	inbound    chan cemi.Message   // Filtered messages for this connection
-	targetAddr cemi.IndividualAddr // Individual Address of the target bus device
-	seqNumber  uint8               // Sequence number (4 bits)
-	rateLimit  uint                // Rate limit for sending messages
-	lastSend   time.Time           // Time of last sent message
-	connected  bool                // Whether the connection is established
+	tunnel     *Tunnel                           // Underlying tunneling connection
+	inbound    chan cemi.Message                 // Filtered messages for this connection
+	targetAddr cemi.IndividualAddr               // Individual Address of the target bus device
+	seqNumber  uint8                             // Sequence number (4 bits)
+	rateLimit  uint                              // Rate limit for sending messages
+	maxAPDU    uint                              // Maximum APDU length supported by the device
+	resTimeout time.Duration                     // How long to wait for the confirmation of a T_CONNECT or a T_ACK
+	appTimeout time.Duration                     // How long to wait for the response of the device
+	lastSend   time.Time                         // Time of last sent message
+	connected  bool                              // Whether the connection is established
+	peerClosed bool                              // Whether the device has closed the connection
+	err        error                             // Reason why the connection has ended
+	onReset    func()                            // Called after the connection has been reset
+	onClose    func(err error)                   // Called once the connection has ended
+	onExchange func(sent, received cemi.Message) // Called after each completed Send
+	deadline   time.Time                         // End of the time budget of the running procedure, if any
+	stats      Stats                             // Statistics about the connection
+	draining   bool                              // Whether new operations are rejected
+	dryRun     bool                              // Whether write procedures only record their telegrams
+	dryFrames  [][]byte                          // Telegrams recorded in dry run mode
+	active     sync.WaitGroup                    // Operations in progress
 	done       chan struct{}
+	closeOnce  sync.Once // Closes done
+	discOnce   sync.Once // Runs the teardown of Disconnect
 	wait       sync.WaitGroup
 	mu         sync.Mutex
 }
 
 // NewP2PConnection creates a new point-to-point connection to a device.
 func NewP2PConnection(tunnel *Tunnel, addr cemi.IndividualAddr) (*P2PConnection, error) {
+	if !cemi.IsValidIndividualAddr(addr) {
+		return nil, fmt.Errorf("invalid target address %s", addr)
+	}
+
 	// Initialize the point-to-point connection structure.
 	conn := &P2PConnection{
 		tunnel:     tunnel,
 		targetAddr: addr,
 		seqNumber:  15, // Start with the maximum so the first increment will be 0.
 		rateLimit:  20,
+		maxAPDU:    defaultMaxAPDULength,
+		resTimeout: defaultResponseTimeout(tunnel),
+		appTimeout: defaultApplicationTimeout,
 		lastSend:   time.Now().Add(-time.Second),
 		done:       make(chan struct{}),
 		inbound:    make(chan cemi.Message, 10),
@@ -53,51 +123,411 @@ func NewP2PConnection(tunnel *Tunnel, addr cemi.IndividualAddr) (*P2PConnection,
 }
 
 // Send sends a cEMI telegram over the point-to-point connection to the device
-// and waits for a response matching the expected command.
+// and waits for a response matching the expected command. It waits up to t for the response, or
+// up to the ApplicationTimeout of the connection if t is zero.
 func (conn *P2PConnection) Send(req cemi.Message, exp cemi.APCI, t time.Duration) (cemi.Message, error) {
-	if !conn.connected {
-		return nil, errors.New("not connected to device")
+	if err := conn.begin(); err != nil {
+		return nil, err
+	}
+	defer conn.active.Done()
+
+	return conn.send(req, exp, t)
+}
+
+// send sends the request and waits for the response without tracking the operation.
+func (conn *P2PConnection) send(req cemi.Message, exp cemi.APCI, t time.Duration) (*cemi.LDataInd, error) {
+	err := conn.sendAcked(req, conn.ResponseTimeout())
+	if err != nil {
+		return nil, err
+	}
+
+	// Wait for a response from the device.
+	res, err := conn.awaitResponse(exp, t)
+	if err != nil {
+		return nil, err
+	}
+
+	conn.mu.Lock()
+	onExchange := conn.onExchange
+	conn.mu.Unlock()
+
+	if onExchange != nil {
+		go onExchange(req, res)
+	}
+
+	return res, nil
+}
+
+// SendNoResponse sends a cEMI telegram over the point-to-point connection to the device and waits
+// only for its acknowledgement. This suits services like A_Memory_Write for which the device does
+// not respond.
+func (conn *P2PConnection) SendNoResponse(req cemi.Message) error {
+	return conn.sendTracked(req, conn.ResponseTimeout())
+}
+
+// sendTracked works like sendAcked, but counts as an operation in progress while draining.
+func (conn *P2PConnection) sendTracked(req cemi.Message, t time.Duration) error {
+	if err := conn.begin(); err != nil {
+		return err
+	}
+	defer conn.active.Done()
+
+	return conn.sendAcked(req, t)
+}
+
+// begin registers an operation in progress, unless the connection is being drained.
+func (conn *P2PConnection) begin() error {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	if conn.draining {
+		return ErrShuttingDown
 	}
 
+	conn.active.Add(1)
+	return nil
+}
+
+// drain rejects further operations and waits up to timeout for those in progress. It reports
+// whether all of them have completed in time.
+func (conn *P2PConnection) drain(timeout time.Duration) bool {
+	conn.mu.Lock()
+	conn.draining = true
+	conn.mu.Unlock()
+
+	return waitTimeout(&conn.active, timeout)
+}
+
+// sendAcked sends the request and waits up to t for its acknowledgement.
+func (conn *P2PConnection) sendAcked(req cemi.Message, t time.Duration) error {
+	if !conn.isConnected() {
+		return conn.closedErr()
+	}
+
+	err := conn.sendNumbered(req, t)
+	if !errors.Is(err, errSeqMismatch) {
+		return err
+	}
+
+	// Reconnecting resets the sequence numbers of both sides, after which the telegram is repeated
+	// once.
+	if err := conn.resync(); err != nil {
+		return fmt.Errorf("failed to resynchronize sequence numbers: %w", err)
+	}
+
+	return conn.sendNumbered(req, t)
+}
+
+// sendNumbered sends the request with the next sequence number and waits up to t for its
+// acknowledgement.
+func (conn *P2PConnection) sendNumbered(req cemi.Message, t time.Duration) error {
 	// Set the sequence number in the request.
 	seq := conn.nextSeqNum()
 	err := conn.setSeqNum(req, seq)
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	err = conn.checkAPDULength(req)
+	if err != nil {
+		return err
+	}
+
+	// A telegram rejected with T_NAK is repeated with the same sequence number.
+	for i := 0; i <= maxRepetitions; i++ {
+		if _, err := conn.limitTimeout(t); err != nil {
+			return err
+		}
+
+		conn.applyRateLimit()
+
+		// Send the cEMI frame through the tunnel.
+		err = conn.tunnel.Send(req)
+		if err != nil {
+			return fmt.Errorf("failed to send request: %w", err)
+		}
+
+		err = conn.awaitAck(t)
+		if err != errNak {
+			return err
+		}
 	}
 
+	conn.reset()
+	return ErrConnectionReset
+}
+
+// resync reconnects to the device without closing the connection, so that the sequence numbers of
+// both sides start over at zero.
+func (conn *P2PConnection) resync() error {
 	conn.applyRateLimit()
 
-	// Send the cEMI frame through the tunnel.
-	err = conn.tunnel.Send(req)
+	err := conn.tunnel.Send(cemi.NewDiscReq(conn.tunnel.SourceAddr(), conn.targetAddr))
+	if err != nil {
+		return err
+	}
+
+	conn.applyRateLimit()
+
+	err = conn.tunnel.Send(cemi.NewConnReq(conn.tunnel.SourceAddr(), conn.targetAddr))
+	if err != nil {
+		return err
+	}
+
+	err = conn.awaitConnCon(conn.ResponseTimeout())
+	if err != nil {
+		return err
+	}
+
+	conn.mu.Lock()
+	conn.seqNumber = 15 // The next increment will be 0.
+	conn.stats.Resyncs++
+	conn.mu.Unlock()
+
+	return nil
+}
+
+// awaitConnCon waits for the L_Data.con of a T_CONNECT while the connection is being served.
+func (conn *P2PConnection) awaitConnCon(t time.Duration) error {
+	timeout := time.After(t)
+
+	for {
+		select {
+		case <-timeout:
+			return errResponseTimeout
+
+		case <-conn.done:
+			return conn.closedErr()
+
+		case msg := <-conn.inbound:
+			if con, ok := msg.(*cemi.LDataCon); ok {
+				if _, ok := con.LData.Data.(*cemi.ControlConn); ok {
+					return nil
+				}
+			}
+		}
+	}
+}
+
+// Stats contains statistics about a point-to-point connection.
+type Stats struct {
+	// Resyncs counts how often the connection has been re-established, because the sequence
+	// numbers of the device and the connection had drifted apart.
+	Resyncs uint
+}
+
+// Stats returns statistics about the connection.
+func (conn *P2PConnection) Stats() Stats {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	return conn.stats
+}
+
+// reset closes the connection after the device kept rejecting telegrams.
+func (conn *P2PConnection) reset() {
+	conn.mu.Lock()
+	conn.err = ErrConnectionReset
+	onReset := conn.onReset
+	conn.mu.Unlock()
+
+	conn.Disconnect()
+
+	if onReset != nil {
+		onReset()
+	}
+}
+
+// setOnClose registers a function which is called once the connection has ended. It receives the
+// reason, which is nil if the connection has been closed by the caller.
+func (conn *P2PConnection) setOnClose(fn func(err error)) {
+	conn.mu.Lock()
+	conn.onClose = fn
+	conn.mu.Unlock()
+}
+
+// notifyClosed calls the function registered with setOnClose, if any.
+func (conn *P2PConnection) notifyClosed() {
+	conn.mu.Lock()
+	onClose, err := conn.onClose, conn.err
+	conn.mu.Unlock()
+
+	if onClose != nil {
+		onClose(err)
+	}
+}
+
+// setOnReset registers a function which is called after the connection has been reset.
+func (conn *P2PConnection) setOnReset(fn func()) {
+	conn.mu.Lock()
+	conn.onReset = fn
+	conn.mu.Unlock()
+}
+
+// SetOnExchange registers a function which receives the request and the matched response of every
+// Send that completes, which helps debugging misbehaving devices without tracing all frames. The
+// function is called on its own goroutine, so that it cannot stall the connection. A nil function
+// removes the hook.
+func (conn *P2PConnection) SetOnExchange(fn func(sent, received cemi.Message)) {
+	conn.mu.Lock()
+	conn.onExchange = fn
+	conn.mu.Unlock()
+}
+
+// WithBudget runs fn, which usually performs a multi-step procedure like ReadMemory, within an
+// overall time budget. Every telegram of the procedure still waits at most for its own timeout, but
+// never beyond the end of the budget. Once the budget is exhausted, the pending step fails with
+// ErrBudgetExhausted and so does every further step. A budget of zero means no limit. Budgets may
+// be nested, in which case the one that ends first applies; the enclosing budget is restored once
+// fn has returned.
+func (conn *P2PConnection) WithBudget(budget time.Duration, fn func() error) error {
+	conn.mu.Lock()
+	previous := conn.deadline
+	if budget > 0 {
+		deadline := time.Now().Add(budget)
+		if previous.IsZero() || deadline.Before(previous) {
+			conn.deadline = deadline
+		}
+	}
+	conn.mu.Unlock()
+
+	defer func() {
+		conn.mu.Lock()
+		conn.deadline = previous
+		conn.mu.Unlock()
+	}()
+
+	return fn()
+}
+
+// limitTimeout shortens the timeout of a single step to what is left of the time budget.
+func (conn *P2PConnection) limitTimeout(t time.Duration) (time.Duration, error) {
+	conn.mu.Lock()
+	deadline := conn.deadline
+	conn.mu.Unlock()
+
+	if deadline.IsZero() {
+		return t, nil
+	}
+
+	remaining := time.Until(deadline)
+	if remaining <= 0 {
+		return 0, ErrBudgetExhausted
+	}
+
+	if remaining < t {
+		return remaining, nil
+	}
+
+	return t, nil
+}
+
+// timeoutErr returns ErrBudgetExhausted instead of err if a step has timed out because the time
+// budget has been exhausted.
+func (conn *P2PConnection) timeoutErr(err error) error {
+	if _, budgetErr := conn.limitTimeout(0); budgetErr != nil {
+		return budgetErr
+	}
+
+	return err
+}
+
+// newRequest creates a L_Data.req which carries the given application data to the device.
+func (conn *P2PConnection) newRequest(cmd cemi.APCI, data []byte) *cemi.LDataReq {
+	return &cemi.LDataReq{
+		LData: cemi.LData{
+			Control1:    cemi.Control1StdFrame | cemi.Control1NoRepeat | cemi.Control1NoSysBroadcast,
+			Control2:    cemi.Control2Hops(6),
+			Source:      conn.tunnel.SourceAddr(),
+			Destination: uint16(conn.targetAddr),
+			Data: &cemi.AppData{
+				Command: cmd,
+				Data:    data,
+			},
+		},
+	}
+}
+
+// SendSegmented sends a request like Send, but collects a response which the device spreads over
+// several telegrams. For each received segment, segment extracts the payload and reports whether
+// more segments follow, which is specific to the procedure. The payloads of all segments are
+// concatenated in the order of their sequence numbers.
+func (conn *P2PConnection) SendSegmented(
+	req cemi.Message,
+	exp cemi.APCI,
+	t time.Duration,
+	segment func(app *cemi.AppData) (data []byte, more bool),
+) ([]byte, error) {
+	if err := conn.begin(); err != nil {
+		return nil, err
+	}
+	defer conn.active.Done()
+
+	res, err := conn.send(req, exp, t)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, err
+	}
+
+	app := res.LData.Data.(*cemi.AppData)
+	data, more := segment(app)
+	result := append([]byte(nil), data...)
+
+	for more {
+		seq := app.SeqNumber
+
+		ind, err := conn.awaitResponse(exp, t)
+		if err != nil {
+			return nil, err
+		}
+
+		app = ind.LData.Data.(*cemi.AppData)
+
+		// A repeated segment has already been collected.
+		if app.SeqNumber == seq {
+			continue
+		}
+
+		if app.SeqNumber != (seq+1)%16 {
+			return nil, fmt.Errorf(
+				"segment sequence number %d must follow sequence number %d", app.SeqNumber, seq,
+			)
+		}
+
+		data, more = segment(app)
+		result = append(result, data...)
+	}
+
+	return result, nil
+}
+
+// awaitResponse waits for a response with the expected command and acknowledges it.
+func (conn *P2PConnection) awaitResponse(exp cemi.APCI, t time.Duration) (*cemi.LDataInd, error) {
+	if t <= 0 {
+		t = conn.ApplicationTimeout()
 	}
 
-	// TODO: Retry once?
-	err = conn.awaitAck()
+	t, err := conn.limitTimeout(t)
 	if err != nil {
 		return nil, err
 	}
 
-	// Wait for a response from the device.
-	timeout := time.After(t) // 6 * time.Second
+	timeout := time.After(t)
 
 	for {
 		select {
 		// The response has timed out.
 		case <-timeout:
-			return nil, errors.New("response timed out")
+			return nil, conn.timeoutErr(errors.New("response timed out"))
 
 		// The connection has been closed.
 		case <-conn.done:
-			return nil, errors.New("connection was closed")
+			return nil, conn.closedErr()
 
 		// A response has been received.
 		case res := <-conn.inbound:
 			// Messages other than Indication primitives can be ignored.
 			ind, ok := res.(*cemi.LDataInd)
-			if !ok {
+			if !ok || !conn.isFromTarget(ind) {
 				continue
 			}
 
@@ -111,7 +541,8 @@ func (conn *P2PConnection) Send(req cemi.Message, exp cemi.APCI, t time.Duration
 
 			conn.applyRateLimit()
 
-			// Send an Ack to the device.
+			// Acknowledge the response with its own sequence number. The device numbers its
+			// telegrams independently of the ones sent by the connection.
 			req := cemi.NewAck(conn.tunnel.SourceAddr(), ind.LData.Source, app.SeqNumber)
 			err := conn.tunnel.Send(req)
 			if err != nil {
@@ -123,8 +554,26 @@ func (conn *P2PConnection) Send(req cemi.Message, exp cemi.APCI, t time.Duration
 	}
 }
 
-// Disconnect closes the point-to-point connection to the device.
-func (conn *P2PConnection) Disconnect() error {
+// Disconnect closes the point-to-point connection to the device. It is safe to call it more than
+// once and from several goroutines, the connection is torn down only once. All calls return after
+// the teardown, later calls return nil.
+func (conn *P2PConnection) Disconnect() (err error) {
+	conn.discOnce.Do(func() {
+		err = conn.disconnect()
+	})
+
+	return err
+}
+
+// closeDone signals the serving goroutine to stop. It may be called more than once.
+func (conn *P2PConnection) closeDone() {
+	conn.closeOnce.Do(func() {
+		close(conn.done)
+	})
+}
+
+// disconnect sends the T_DISCONNECT and waits until the connection has stopped serving.
+func (conn *P2PConnection) disconnect() error {
 	conn.mu.Lock()
 	if !conn.connected {
 		conn.mu.Unlock()
@@ -146,12 +595,7 @@ func (conn *P2PConnection) Disconnect() error {
 	conn.mu.Unlock()
 
 	// Signal to stop the processor goroutine.
-	select {
-	case <-conn.done:
-		// Already closed.
-	default:
-		close(conn.done)
-	}
+	conn.closeDone()
 
 	// Wait for the processor goroutine to finish.
 	conn.wait.Wait()
@@ -159,6 +603,131 @@ func (conn *P2PConnection) Disconnect() error {
 	return err
 }
 
+// SetMaxAPDU sets the maximum APDU length supported by the device, e.g. as learned from the
+// TunnellingInfoDIB or by reading the device's max APDU length property. A length of zero resets
+// it to the length of a standard frame.
+func (conn *P2PConnection) SetMaxAPDU(length uint) {
+	if length == 0 {
+		length = defaultMaxAPDULength
+	}
+
+	conn.mu.Lock()
+	conn.maxAPDU = length
+	conn.mu.Unlock()
+}
+
+// MaxAPDU returns the maximum APDU length supported by the device.
+func (conn *P2PConnection) MaxAPDU() uint {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	return conn.maxAPDU
+}
+
+// defaultResponseTimeout returns the response timeout of the tunnel's configuration, which
+// connections use unless configured otherwise.
+func defaultResponseTimeout(tunnel *Tunnel) time.Duration {
+	if tunnel.config.ResponseTimeout > 0 {
+		return tunnel.config.ResponseTimeout
+	}
+
+	return DefaultTunnelConfig.ResponseTimeout
+}
+
+// SetResponseTimeout sets how long the connection waits for the transport layer, i.e. for the
+// confirmation of a T_CONNECT and for the T_ACK of each telegram. A timeout of zero resets it to
+// the ResponseTimeout of the tunnel's configuration.
+func (conn *P2PConnection) SetResponseTimeout(t time.Duration) {
+	if t <= 0 {
+		t = defaultResponseTimeout(conn.tunnel)
+	}
+
+	conn.mu.Lock()
+	conn.resTimeout = t
+	conn.mu.Unlock()
+}
+
+// ResponseTimeout returns how long the connection waits for the transport layer.
+func (conn *P2PConnection) ResponseTimeout() time.Duration {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	return conn.resTimeout
+}
+
+// SetApplicationTimeout sets how long the connection waits for the response of the device to a
+// request sent with Send, unless Send is given its own timeout. The device needs to process the
+// request first, hence this is usually longer than the response timeout. A timeout of zero resets
+// it to 6 seconds.
+func (conn *P2PConnection) SetApplicationTimeout(t time.Duration) {
+	if t <= 0 {
+		t = defaultApplicationTimeout
+	}
+
+	conn.mu.Lock()
+	conn.appTimeout = t
+	conn.mu.Unlock()
+}
+
+// ApplicationTimeout returns how long the connection waits for the response of the device.
+func (conn *P2PConnection) ApplicationTimeout() time.Duration {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	return conn.appTimeout
+}
+
+// MemoryChunkSize returns the maximum number of bytes a single memory read or write may transfer.
+// It is limited by the maximum APDU length and by the 6-bit byte count of the memory services.
+func (conn *P2PConnection) MemoryChunkSize() uint {
+	// APCI and byte count occupy one octet, the memory address two more.
+	size := conn.MaxAPDU()
+	if size < 3 {
+		return 0
+	}
+
+	size -= 3
+	if size > 63 {
+		size = 63
+	}
+
+	return size
+}
+
+// isConnected reports whether the connection is established.
+func (conn *P2PConnection) isConnected() bool {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	return conn.connected
+}
+
+// closedErr returns the error which describes why the connection is no longer usable.
+func (conn *P2PConnection) closedErr() error {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	if conn.err != nil {
+		return conn.err
+	}
+
+	if conn.peerClosed {
+		return ErrPeerDisconnected
+	}
+
+	return ErrNotConnected
+}
+
+// Err returns the reason why the connection has ended. It is set once the inbound channel has been
+// closed or the connection has been reset. Err returns nil while the connection is established and
+// after Disconnect has been called.
+func (conn *P2PConnection) Err() error {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	return conn.err
+}
+
 // Inbound returns the channel for receiving messages from the connection.
 func (conn *P2PConnection) Inbound() <-chan cemi.Message {
 	return conn.inbound
@@ -181,7 +750,7 @@ func (conn *P2PConnection) requestConn() error {
 	}
 
 	// Setup timeout.
-	timeout := time.After(conn.tunnel.config.ResponseTimeout)
+	timeout := time.After(conn.ResponseTimeout())
 
 	// Cycle until a confirmation is received.
 	for {
@@ -203,7 +772,10 @@ func (conn *P2PConnection) requestConn() error {
 				}
 
 				// The connection was established successfully.
+				conn.mu.Lock()
 				conn.connected = true
+				conn.mu.Unlock()
+
 				return nil
 			}
 		}
@@ -213,12 +785,19 @@ func (conn *P2PConnection) requestConn() error {
 // serve processes messages from the tunnels inbound channel.
 func (conn *P2PConnection) serve() {
 	defer conn.wait.Done()
+	defer conn.notifyClosed()
 	defer close(conn.inbound)
 
 	for {
 		select {
 		// Connection is being closed.
 		case <-conn.done:
+			conn.mu.Lock()
+			if conn.peerClosed {
+				conn.err = ErrPeerDisconnected
+			}
+			conn.mu.Unlock()
+
 			return
 
 		// A message has been received or the tunnel is closed.
@@ -244,6 +823,14 @@ func (conn *P2PConnection) serve() {
 	}
 }
 
+// isFromTarget checks whether the indication has been sent by the target device to the tunnel.
+// Telegrams of this connection travel in the opposite direction, from the tunnel to the device.
+func (conn *P2PConnection) isFromTarget(ind *cemi.LDataInd) bool {
+	return !ind.Control2.IsGroupAddr() &&
+		ind.LData.Source == conn.targetAddr &&
+		ind.LData.Destination == uint16(conn.tunnel.SourceAddr())
+}
+
 // handleDisconnect processes a disconnect requests received from the tunnel.
 func (conn *P2PConnection) handleDisconnect(msg cemi.Message) bool {
 	// We only care about L_Data.ind messages.
@@ -253,25 +840,24 @@ func (conn *P2PConnection) handleDisconnect(msg cemi.Message) bool {
 	}
 
 	// Ensure the message is for this connection.
-	if ind.LData.Destination != uint16(conn.targetAddr) || ind.LData.Source != conn.tunnel.SourceAddr() {
+	if !conn.isFromTarget(ind) {
 		return false
 	}
 
 	// Check if the message is a disconnect request.
 	if _, ok := ind.LData.Data.(*cemi.ControlDisc); ok {
+		// The device has closed the connection, hence there is no need to send a T_DISCONNECT.
+		conn.mu.Lock()
 		if !conn.connected {
+			conn.mu.Unlock()
 			return true
 		}
-
-		conn.Disconnect()
+		conn.connected = false
+		conn.peerClosed = true
+		conn.mu.Unlock()
 
 		// Signal disconnection.
-		select {
-		case <-conn.done:
-			// Already closed.
-		default:
-			close(conn.done)
-		}
+		conn.closeDone()
 
 		return true
 	}
@@ -285,15 +871,11 @@ func (conn *P2PConnection) handleTunnelClosed() {
 	// Mark the connection as disconnected.
 	conn.mu.Lock()
 	conn.connected = false
+	conn.err = ErrTunnelClosed
 	conn.mu.Unlock()
 
 	// Signal that the connection is closed.
-	select {
-	case <-conn.done:
-		// Already closed.
-	default:
-		close(conn.done)
-	}
+	conn.closeDone()
 }
 
 // nextSeqNum increments the sequence number for the connection.
@@ -308,27 +890,43 @@ func (conn *P2PConnection) nextSeqNum() uint8 {
 }
 
 // awaitAck waits for a T_Ack from the device after sending a request.
-func (conn *P2PConnection) awaitAck() error {
-	timeout := time.After(conn.tunnel.config.ResponseTimeout)
+func (conn *P2PConnection) awaitAck(t time.Duration) error {
+	t, err := conn.limitTimeout(t)
+	if err != nil {
+		return err
+	}
+
+	timeout := time.After(t)
 
 	for {
 		select {
 		// The ACK has timed out.
 		case <-timeout:
-			return errors.New("timed out while waiting for ACK")
+			return conn.timeoutErr(errAckTimeout)
 
 		// The connection has been closed.
 		case <-conn.done:
-			return errors.New("connection was closed")
+			return conn.closedErr()
 
 		// A response has been received.
 		case res := <-conn.inbound:
 			// The Ack must be encapsulated in an indication primitive.
 			ind, ok := res.(*cemi.LDataInd)
-			if !ok {
+			if !ok || !conn.isFromTarget(ind) {
 				continue
 			}
 
+			if nak, ok := ind.LData.Data.(*cemi.ControlNak); ok {
+				if nak.SeqNumber != conn.seqNumber {
+					return fmt.Errorf(
+						"%w: nak sequence number %d does not match request sequence number %d",
+						errSeqMismatch, nak.SeqNumber, conn.seqNumber,
+					)
+				}
+
+				return errNak
+			}
+
 			ack, ok := ind.LData.Data.(*cemi.ControlAck)
 			if !ok {
 				continue
@@ -367,6 +965,40 @@ func (conn *P2PConnection) setSeqNum(req cemi.Message, seq uint8) error {
 	return nil
 }
 
+// checkAPDULength makes sure the request fits into the maximum APDU length of the device.
+func (conn *P2PConnection) checkAPDULength(req cemi.Message) error {
+	ind, ok := req.(*cemi.LDataReq)
+	if !ok {
+		return fmt.Errorf("expected LDataReq, got %T", req)
+	}
+
+	app, ok := ind.LData.Data.(*cemi.AppData)
+	if !ok {
+		return fmt.Errorf("expected AppData, got %T", ind.LData.Data)
+	}
+
+	// The packed size includes the length and TPCI octets which do not count towards the APDU.
+	length, limit := app.Size()-2, conn.MaxAPDU()
+	if length > limit {
+		return fmt.Errorf("APDU length %d exceeds maximum APDU length %d", length, limit)
+	}
+
+	return nil
+}
+
+// rateLimitDelay returns how long the rate limit delays the next telegram.
+func (conn *P2PConnection) rateLimitDelay() time.Duration {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	interval := time.Second / time.Duration(conn.rateLimit)
+	if elapsed := time.Since(conn.lastSend); elapsed < interval {
+		return interval - elapsed
+	}
+
+	return 0
+}
+
 // applyRateLimit ensures the connections rate limit is respected.
 func (conn *P2PConnection) applyRateLimit() {
 	conn.mu.Lock()
@@ -384,10 +1016,17 @@ func (conn *P2PConnection) applyRateLimit() {
 
 // Management handles point-to-point connections to individual devices.
 type Management struct {
-	tunnel      *Tunnel
-	connections map[cemi.IndividualAddr]*P2PConnection
-	mu          sync.Mutex
-	done        chan struct{}
+	tunnel       *Tunnel
+	connections  map[cemi.IndividualAddr]*P2PConnection
+	groupConfirm time.Duration // How long to wait for a L_Data.con of group telegrams
+	reconnects   int           // How often procedures are retried after a connection reset
+	dryRun       bool          // Whether the connections run write procedures in dry run mode
+	mu           sync.Mutex
+	done         chan struct{}
+
+	subscriptions map[*Subscription]struct{}
+	lost          map[cemi.IndividualAddr]bool // Devices whose connection has ended unexpectedly
+	eventsMu      sync.Mutex
 }
 
 // NewManagement creates a new Management instance with the given tunnel.
@@ -397,32 +1036,113 @@ func NewManagement(tunnel *Tunnel) *Management {
 		connections: make(map[cemi.IndividualAddr]*P2PConnection),
 		mu:          sync.Mutex{},
 		done:        make(chan struct{}),
+
+		subscriptions: make(map[*Subscription]struct{}),
+		lost:          make(map[cemi.IndividualAddr]bool),
 	}
 }
 
-// Close stops all management operations and closes all connections.
+// Close stops all management operations and closes all connections. The connections are
+// disconnected without holding the lock, so that disconnecting cannot block other calls.
 func (m *Management) Close() {
+	m.mu.Lock()
+
+	if m.isClosed() {
+		m.mu.Unlock()
+		return
+	}
+
 	// Signal that the management is closing.
 	close(m.done)
 
+	conns := make([]*P2PConnection, 0, len(m.connections))
+	for _, conn := range m.connections {
+		conns = append(conns, conn)
+	}
+	m.connections = make(map[cemi.IndividualAddr]*P2PConnection)
+
+	m.mu.Unlock()
+
 	// Close all connections.
+	for _, conn := range conns {
+		conn.Disconnect()
+	}
+}
+
+// Shutdown closes the management gracefully. Connecting fails from now on and the connections
+// reject further sends with ErrShuttingDown, while the operations in progress may complete until
+// the timeout expires. Afterwards all connections are disconnected and the tunnel is shut down with
+// what is left of the timeout. Shutdown reports whether everything has completed in time.
+func (m *Management) Shutdown(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+
 	m.mu.Lock()
-	defer m.mu.Unlock()
 
+	if m.isClosed() {
+		m.mu.Unlock()
+		return m.tunnel.Shutdown(timeout)
+	}
+
+	close(m.done)
+
+	conns := make([]*P2PConnection, 0, len(m.connections))
 	for _, conn := range m.connections {
-		conn.Disconnect()
+		conns = append(conns, conn)
+	}
+	m.connections = make(map[cemi.IndividualAddr]*P2PConnection)
+
+	m.mu.Unlock()
+
+	// Drain all connections at the same time, so that they share the timeout.
+	var drains sync.WaitGroup
+	var mu sync.Mutex
+	drained := true
+
+	for _, conn := range conns {
+		drains.Add(1)
+		go func(conn *P2PConnection) {
+			defer drains.Done()
+
+			ok := conn.drain(time.Until(deadline))
+			conn.Disconnect()
+
+			mu.Lock()
+			drained = drained && ok
+			mu.Unlock()
+		}(conn)
+	}
+	drains.Wait()
+
+	return m.tunnel.Shutdown(time.Until(deadline)) && drained
+}
+
+// isClosed reports whether Close has been called.
+func (m *Management) isClosed() bool {
+	select {
+	case <-m.done:
+		return true
+	default:
+		return false
 	}
 }
 
 // Connect establishes a new point-to-point connection to a device.
 func (m *Management) Connect(addr cemi.IndividualAddr) (*P2PConnection, error) {
+	if !cemi.IsValidIndividualAddr(addr) {
+		return nil, fmt.Errorf("invalid target address %s", addr)
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if m.isClosed() {
+		return nil, ErrManagementClosed
+	}
+
 	// Return the connection if it already exists.
 	conn, exists := m.connections[addr]
 	if exists {
-		if !conn.connected {
+		if !conn.isConnected() {
 			delete(m.connections, addr)
 		} else {
 			return conn, nil
@@ -435,29 +1155,177 @@ func (m *Management) Connect(addr cemi.IndividualAddr) (*P2PConnection, error) {
 		return nil, err
 	}
 
+	// Forget the connection once it has been reset, so that the next call creates a new one.
+	conn.setOnReset(func() {
+		m.mu.Lock()
+		if m.connections[addr] == conn {
+			delete(m.connections, addr)
+		}
+		m.mu.Unlock()
+	})
+
+	conn.setOnClose(func(err error) {
+		m.connectionClosed(addr, err)
+	})
+
+	conn.SetDryRun(m.dryRun)
+
 	// Store the connection.
 	m.connections[addr] = conn
+	m.connectionOpened(addr)
 
 	return conn, nil
 }
 
-// Disconnect closes the point-to-point connection to a device if it exists.
-func (m *Management) Disconnect(addr cemi.IndividualAddr) error {
+// SetReconnectAttempts configures how often WithConnection and WithTransientConnection retry a
+// procedure with a new connection after the connection has been reset. Procedures must therefore
+// be safe to repeat. Zero disables retrying, which is the default.
+func (m *Management) SetReconnectAttempts(n int) {
 	m.mu.Lock()
-	defer m.mu.Unlock()
+	m.reconnects = n
+	m.mu.Unlock()
+}
 
-	conn, exists := m.connections[addr]
-	if !exists {
-		return fmt.Errorf("connection not found")
+// retryOnReset runs the procedure again as long as it fails because its connection has been reset
+// and reconnect attempts are left.
+func (m *Management) retryOnReset(fn func() error) error {
+	m.mu.Lock()
+	attempts := m.reconnects
+	m.mu.Unlock()
+
+	err := fn()
+	for ; attempts > 0 && errors.Is(err, ErrConnectionReset); attempts-- {
+		err = fn()
 	}
 
-	err := conn.Disconnect()
+	return err
+}
+
+// WithConnection runs fn with a point-to-point connection to the device. An existing connection is
+// reused and the connection is left open afterwards, so that subsequent calls can reuse it.
+func (m *Management) WithConnection(addr cemi.IndividualAddr, fn func(*P2PConnection) error) error {
+	return m.retryOnReset(func() error {
+		conn, err := m.Connect(addr)
+		if err != nil {
+			return err
+		}
+
+		return fn(conn)
+	})
+}
+
+// WithTransientConnection runs fn with a point-to-point connection to the device like
+// WithConnection, but always disconnects afterwards. An error returned by fn takes precedence over
+// an error that occurs while disconnecting.
+func (m *Management) WithTransientConnection(addr cemi.IndividualAddr, fn func(*P2PConnection) error) error {
+	return m.retryOnReset(func() error {
+		return m.withTransientConnection(addr, fn)
+	})
+}
+
+// withTransientConnection runs fn once for WithTransientConnection.
+func (m *Management) withTransientConnection(
+	addr cemi.IndividualAddr,
+	fn func(*P2PConnection) error,
+) (err error) {
+	conn, err := m.Connect(addr)
 	if err != nil {
 		return err
 	}
 
+	defer func() {
+		discErr := m.Disconnect(addr)
+		if err == nil {
+			err = discErr
+		}
+	}()
+
+	return fn(conn)
+}
+
+// Ping checks whether the device is reachable. It sends a T_CONNECT to the device, measures how
+// long it takes until the L_Data.con reports that the device has acknowledged it on the bus, and
+// closes the transport connection with a T_DISCONNECT again. A device which does not acknowledge
+// the T_CONNECT within the timeout is unreachable, which is not an error. Ping does not use the
+// connections of the Management. It fails if one to the device is open, because the T_CONNECT would
+// end it.
+func (m *Management) Ping(addr cemi.IndividualAddr, timeout time.Duration) (bool, time.Duration, error) {
+	if !cemi.IsValidIndividualAddr(addr) {
+		return false, 0, fmt.Errorf("invalid target address %s", addr)
+	}
+
+	m.mu.Lock()
+	closed := m.isClosed()
+	conn := m.connections[addr]
+	m.mu.Unlock()
+
+	if closed {
+		return false, 0, ErrManagementClosed
+	} else if conn != nil && conn.isConnected() {
+		return false, 0, fmt.Errorf("connection to %s is in use", addr)
+	}
+
+	// Watch for the confirmation before sending, so that it cannot be missed.
+	w := m.tunnel.watch(func(msg cemi.Message) bool {
+		con, ok := msg.(*cemi.LDataCon)
+		if !ok || con.Control2.IsGroupAddr() || con.Destination != uint16(addr) {
+			return false
+		}
+
+		_, ok = con.Data.(*cemi.ControlConn)
+		return ok
+	})
+	defer m.tunnel.unwatch(w)
+
+	start := time.Now()
+
+	err := m.tunnel.Send(cemi.NewConnReq(m.tunnel.SourceAddr(), addr))
+	if err != nil {
+		return false, 0, err
+	}
+
+	reachable := false
+
+	select {
+	case <-time.After(timeout):
+
+	case msg, open := <-w.ch:
+		if !open {
+			return false, 0, ErrTunnelClosed
+		}
+
+		reachable = msg.(*cemi.LDataCon).Control1&cemi.Control1HasError == 0
+	}
+
+	latency := time.Since(start)
+
+	// Close the transport connection, which the device may have opened.
+	err = m.tunnel.Send(cemi.NewDiscReq(m.tunnel.SourceAddr(), addr))
+	if err != nil {
+		return false, 0, err
+	}
+
+	if !reachable {
+		return false, 0, nil
+	}
+
+	return true, latency, nil
+}
+
+// Disconnect closes the point-to-point connection to a device if it exists.
+func (m *Management) Disconnect(addr cemi.IndividualAddr) error {
+	m.mu.Lock()
+	conn, exists := m.connections[addr]
+	if !exists {
+		m.mu.Unlock()
+		return fmt.Errorf("connection not found")
+	}
+
+	// The connection is unusable afterwards, even if disconnecting fails.
 	delete(m.connections, addr)
-	return nil
+	m.mu.Unlock()
+
+	return conn.Disconnect()
 }
 
 // Connection returns an existing point-to-point connection if it exists,
@@ -473,3 +1341,139 @@ func (m *Management) GetConnection(addr cemi.IndividualAddr) *P2PConnection {
 
 	return conn
 }
+
+// SetGroupConfirmation configures how long group telegrams wait for their L_Data.con. A timeout of
+// zero disables waiting, which is the default. Waiting does not take any messages away from the
+// consumers of the tunnel's inbound channel.
+func (m *Management) SetGroupConfirmation(timeout time.Duration) {
+	m.mu.Lock()
+	m.groupConfirm = timeout
+	m.mu.Unlock()
+}
+
+// GroupRead sends an A_GroupValue_Read to the group address.
+func (m *Management) GroupRead(dst cemi.GroupAddr) error {
+	return m.sendGroup(GroupRead, dst, []byte{0})
+}
+
+// GroupReadValue sends an A_GroupValue_Read to the group address and waits for the first
+// A_GroupValue_Response. The returned data can be unpacked into a datapoint value. Waiting does
+// not take any messages away from the consumers of the tunnel's inbound channel.
+func (m *Management) GroupReadValue(dst cemi.GroupAddr, t time.Duration) ([]byte, error) {
+	// Watch for the response before sending the request, so that it cannot be missed.
+	w := m.tunnel.watch(func(msg cemi.Message) bool {
+		_, ok := matchGroupResponse(msg, dst)
+		return ok
+	})
+	defer m.tunnel.unwatch(w)
+
+	err := m.GroupRead(dst)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-time.After(t):
+		return nil, errors.New("timed out while waiting for group response")
+
+	case msg, open := <-w.ch:
+		if !open {
+			return nil, errors.New("tunnel was closed before a group response was received")
+		}
+
+		data, _ := matchGroupResponse(msg, dst)
+		return data, nil
+	}
+}
+
+// matchGroupResponse checks whether the message is a group response for the group address.
+// Group communication is connectionless, therefore the sequence fields are not considered.
+func matchGroupResponse(msg cemi.Message, dst cemi.GroupAddr) ([]byte, bool) {
+	ind, ok := msg.(*cemi.LDataInd)
+	if !ok || !ind.Control2.IsGroupAddr() || ind.Destination != uint16(dst) {
+		return nil, false
+	}
+
+	app, ok := ind.Data.(*cemi.AppData)
+	if !ok || app.Command != cemi.GroupValueResponse {
+		return nil, false
+	}
+
+	return app.Data, true
+}
+
+// GroupWrite sends an A_GroupValue_Write with the given value to the group address.
+func (m *Management) GroupWrite(dst cemi.GroupAddr, value dpt.DatapointValue) error {
+	return m.sendGroup(GroupWrite, dst, value.Pack())
+}
+
+// GroupResponse sends an A_GroupValue_Response with the given value to the group address.
+func (m *Management) GroupResponse(dst cemi.GroupAddr, value dpt.DatapointValue) error {
+	return m.sendGroup(GroupResponse, dst, value.Pack())
+}
+
+// GroupWriteBool writes a boolean (DPT 1.001) to the group address.
+func (m *Management) GroupWriteBool(dst cemi.GroupAddr, v bool) error {
+	value := dpt.DPT_1001(v)
+	return m.GroupWrite(dst, &value)
+}
+
+// GroupWriteScaling writes a percentage (DPT 5.001) to the group address.
+func (m *Management) GroupWriteScaling(dst cemi.GroupAddr, percent float32) error {
+	value := dpt.DPT_5001(percent)
+	return m.GroupWrite(dst, &value)
+}
+
+// GroupWriteFloat writes a 2-byte float value (DPT 9.xxx) such as a temperature to the group
+// address.
+func (m *Management) GroupWriteFloat(dst cemi.GroupAddr, v float32) error {
+	value := dpt.DPT_9001(v)
+	return m.GroupWrite(dst, &value)
+}
+
+// sendGroup sends a group telegram through the tunnel and waits for its confirmation, if enabled.
+// Group communication is connectionless, hence no point-to-point connection is involved.
+func (m *Management) sendGroup(cmd GroupCommand, dst cemi.GroupAddr, data []byte) error {
+	ldata := buildGroupOutbound(GroupEvent{
+		Command:     cmd,
+		Source:      m.tunnel.SourceAddr(),
+		Destination: dst,
+		Data:        data,
+	})
+
+	m.mu.Lock()
+	confirm := m.groupConfirm
+	m.mu.Unlock()
+
+	if confirm <= 0 {
+		return m.tunnel.Send(&cemi.LDataReq{LData: ldata})
+	}
+
+	// Watch for the confirmation before sending, so that it cannot be missed.
+	w := m.tunnel.watch(func(msg cemi.Message) bool {
+		con, ok := msg.(*cemi.LDataCon)
+		return ok && con.Control2.IsGroupAddr() && con.Destination == uint16(dst)
+	})
+	defer m.tunnel.unwatch(w)
+
+	err := m.tunnel.Send(&cemi.LDataReq{LData: ldata})
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-time.After(confirm):
+		return errors.New("timed out while waiting for L_Data.con")
+
+	case msg, open := <-w.ch:
+		if !open {
+			return errors.New("tunnel was closed before the telegram was confirmed")
+		}
+
+		if msg.(*cemi.LDataCon).Control1&cemi.Control1HasError != 0 {
+			return fmt.Errorf("group telegram to %v has not been confirmed", dst)
+		}
+
+		return nil
+	}
+}
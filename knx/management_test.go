@@ -0,0 +1,1460 @@
+// Licensed under the MIT license which can be found in the LICENSE file.
+
+package knx
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/LB-00/knx-go/knx/cemi"
+	"github.com/LB-00/knx-go/knx/knxnet"
+)
+
+func TestNewP2PConnection(t *testing.T) {
+	// Individual address 0.0.0 must be rejected before anything is sent.
+	t.Run("ZeroAddress", func(t *testing.T) {
+		client, gateway := newDummySockets()
+		defer client.Close()
+		defer gateway.Close()
+
+		tunnel := makeTunnelConn(client, DefaultTunnelConfig, 1)
+
+		conn, err := NewP2PConnection(tunnel, cemi.IndividualAddr(0))
+		if err == nil {
+			t.Fatal("Should not succeed")
+		}
+
+		if conn != nil {
+			t.Fatal("Connection should be nil")
+		}
+	})
+}
+
+func TestManagement_Connect(t *testing.T) {
+	// Individual address 0.0.0 must be rejected before anything is sent.
+	t.Run("ZeroAddress", func(t *testing.T) {
+		client, gateway := newDummySockets()
+		defer client.Close()
+		defer gateway.Close()
+
+		m := NewManagement(makeTunnelConn(client, DefaultTunnelConfig, 1))
+
+		_, err := m.Connect(cemi.IndividualAddr(0))
+		if err == nil {
+			t.Fatal("Should not succeed")
+		}
+
+		if m.GetConnection(cemi.IndividualAddr(0)) != nil {
+			t.Fatal("Connection should not be stored")
+		}
+	})
+}
+
+func TestP2PConnection_MaxAPDU(t *testing.T) {
+	conn := &P2PConnection{maxAPDU: defaultMaxAPDULength}
+
+	if size := conn.MemoryChunkSize(); size != 12 {
+		t.Errorf("Unexpected chunk size for standard frames: %d", size)
+	}
+
+	// A memory write filling a standard frame: byte count, address and 12 bytes of data.
+	makeReq := func(n int) *cemi.LDataReq {
+		return &cemi.LDataReq{LData: cemi.LData{Data: &cemi.AppData{
+			Command: cemi.MemoryWrite,
+			Data:    make([]byte, 3+n),
+		}}}
+	}
+
+	if err := conn.checkAPDULength(makeReq(12)); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if err := conn.checkAPDULength(makeReq(13)); err == nil {
+		t.Error("Should not succeed")
+	}
+
+	// A larger APDU allows bigger memory chunks.
+	conn.SetMaxAPDU(55)
+
+	if size := conn.MemoryChunkSize(); size != 52 {
+		t.Errorf("Unexpected chunk size: %d", size)
+	}
+
+	if err := conn.checkAPDULength(makeReq(52)); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	// The byte count of memory services limits the chunk size.
+	conn.SetMaxAPDU(254)
+
+	if size := conn.MemoryChunkSize(); size != 63 {
+		t.Errorf("Unexpected chunk size: %d", size)
+	}
+
+	// Zero resets to standard frames.
+	conn.SetMaxAPDU(0)
+
+	if limit := conn.MaxAPDU(); limit != defaultMaxAPDULength {
+		t.Errorf("Unexpected maximum APDU length: %d", limit)
+	}
+}
+
+// makeTestManagement creates a Management on top of a tunnel whose gateway accepts every request.
+// The tunnel uses TCP mode so that no KNXnet/IP acknowledgements are required.
+func makeTestManagement(t *testing.T) (*Management, *Tunnel) {
+	m, tunnel, _ := makeRecordingManagement(t)
+	return m, tunnel
+}
+
+// makeRecordingManagement works like makeTestManagement, but also provides the L_Data.req
+// messages which arrive at the gateway.
+func makeRecordingManagement(t *testing.T) (*Management, *Tunnel, <-chan *cemi.LDataReq) {
+	client, gateway := newDummySockets()
+	t.Cleanup(func() {
+		client.Close()
+		gateway.Close()
+	})
+
+	sent := make(chan *cemi.LDataReq, 100)
+
+	go func() {
+		for msg := range gateway.Inbound() {
+			if req, ok := msg.(*knxnet.TunnelReq); ok {
+				if ldata, ok := req.Payload.(*cemi.LDataReq); ok {
+					select {
+					case sent <- ldata:
+					default:
+					}
+				}
+			}
+		}
+	}()
+
+	config := DefaultTunnelConfig
+	config.UseTCP = true
+
+	tunnel := makeTunnelConn(client, config, 1)
+
+	return NewManagement(tunnel), tunnel, sent
+}
+
+// confirmConnect queues the confirmation of the next T_CONNECT.
+func confirmConnect(tunnel *Tunnel) {
+	tunnel.inbound <- &cemi.LDataCon{LData: cemi.LData{Data: cemi.TConnect()}}
+}
+
+func TestManagement_WithConnection(t *testing.T) {
+	addr := cemi.NewIndividualAddr3(1, 1, 5)
+
+	// The connection is kept open and reused.
+	t.Run("Reuse", func(t *testing.T) {
+		m, tunnel := makeTestManagement(t)
+		confirmConnect(tunnel)
+
+		var first, second *P2PConnection
+
+		err := m.WithConnection(addr, func(conn *P2PConnection) error {
+			first = conn
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = m.WithConnection(addr, func(conn *P2PConnection) error {
+			second = conn
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if first != second {
+			t.Error("Connection has not been reused")
+		}
+
+		if m.GetConnection(addr) == nil {
+			t.Error("Connection should remain open")
+		}
+
+		m.Disconnect(addr)
+	})
+
+	// Errors of the procedure are returned.
+	t.Run("Error", func(t *testing.T) {
+		m, tunnel := makeTestManagement(t)
+		confirmConnect(tunnel)
+
+		errProcedure := errors.New("procedure failed")
+
+		err := m.WithConnection(addr, func(conn *P2PConnection) error {
+			return errProcedure
+		})
+		if err != errProcedure {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		m.Disconnect(addr)
+	})
+}
+
+func TestManagement_WithTransientConnection(t *testing.T) {
+	addr := cemi.NewIndividualAddr3(1, 1, 5)
+
+	// The connection is closed after the procedure.
+	t.Run("Disconnect", func(t *testing.T) {
+		m, tunnel := makeTestManagement(t)
+		confirmConnect(tunnel)
+
+		called := false
+
+		err := m.WithTransientConnection(addr, func(conn *P2PConnection) error {
+			called = true
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !called {
+			t.Error("Procedure has not been called")
+		}
+
+		if m.GetConnection(addr) != nil {
+			t.Error("Connection should be closed")
+		}
+	})
+
+	// Errors of the procedure are returned and the connection is closed nonetheless.
+	t.Run("Error", func(t *testing.T) {
+		m, tunnel := makeTestManagement(t)
+		confirmConnect(tunnel)
+
+		errProcedure := errors.New("procedure failed")
+
+		err := m.WithTransientConnection(addr, func(conn *P2PConnection) error {
+			return errProcedure
+		})
+		if err != errProcedure {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if m.GetConnection(addr) != nil {
+			t.Error("Connection should be closed")
+		}
+	})
+
+	// The procedure is not called if the connection cannot be established.
+	t.Run("ConnectFails", func(t *testing.T) {
+		m, _ := makeTestManagement(t)
+
+		err := m.WithTransientConnection(cemi.IndividualAddr(0), func(conn *P2PConnection) error {
+			t.Error("Procedure should not be called")
+			return nil
+		})
+		if err == nil {
+			t.Fatal("Should not succeed")
+		}
+	})
+}
+
+// expectGroupWrite receives the next tunnel request from the gateway socket and verifies that it
+// is a group write with the given payload.
+func expectGroupWrite(t *testing.T, gateway *dummySocket, dst cemi.GroupAddr, data []byte) {
+	t.Helper()
+
+	msg := <-gateway.Inbound()
+
+	req, ok := msg.(*knxnet.TunnelReq)
+	if !ok {
+		t.Fatalf("Unexpected type %T", msg)
+	}
+
+	ldata, ok := req.Payload.(*cemi.LDataReq)
+	if !ok {
+		t.Fatalf("Unexpected payload %T", req.Payload)
+	}
+
+	if !ldata.Control2.IsGroupAddr() || ldata.Destination != uint16(dst) {
+		t.Errorf("Unexpected destination: %v", ldata.Destination)
+	}
+
+	app, ok := ldata.Data.(*cemi.AppData)
+	if !ok {
+		t.Fatalf("Unexpected transport unit %T", ldata.Data)
+	}
+
+	if app.Command != cemi.GroupValueWrite {
+		t.Errorf("Unexpected command: %v", app.Command)
+	}
+
+	if !bytes.Equal(app.Data, data) {
+		t.Errorf("Unexpected data: %v", app.Data)
+	}
+}
+
+func TestP2PConnection_OnExchange(t *testing.T) {
+	addr := cemi.NewIndividualAddr3(1, 1, 5)
+
+	conn, tunnel, _ := connectRecording(t, addr)
+
+	type exchange struct{ sent, received cemi.Message }
+	exchanges := make(chan exchange, 1)
+
+	conn.SetOnExchange(func(sent, received cemi.Message) {
+		exchanges <- exchange{sent, received}
+	})
+
+	queueResponses(tunnel, addr, cemi.MaskVersionResponse, []byte{0x07, 0xb0})
+
+	req := conn.newRequest(cemi.MaskVersionRead, []byte{0})
+
+	res, err := conn.Send(req, cemi.MaskVersionResponse, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case ex := <-exchanges:
+		if ex.sent != req || ex.received != res {
+			t.Errorf("Unexpected exchange: %v, %v", ex.sent, ex.received)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Hook has not been called")
+	}
+}
+
+func TestManagement_Shutdown(t *testing.T) {
+	addr := cemi.NewIndividualAddr3(1, 1, 5)
+
+	m, tunnel, sent := makeRecordingManagement(t)
+	tunnel.done = make(chan struct{})
+	confirmConnect(tunnel)
+
+	conn, err := m.Connect(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Skip the T_CONNECT.
+	<-sent
+
+	goroutines := runtime.NumGoroutine()
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := conn.Send(conn.newRequest(cemi.MaskVersionRead, []byte{0}), cemi.MaskVersionResponse, time.Second)
+		result <- err
+	}()
+
+	expectRequest(t, sent, cemi.MaskVersionRead)
+
+	drained := make(chan bool, 1)
+	go func() {
+		drained <- m.Shutdown(time.Second)
+	}()
+
+	// Further sends are rejected while the pending one is drained.
+	for deadline := time.Now().Add(time.Second); ; {
+		conn.mu.Lock()
+		draining := conn.draining
+		conn.mu.Unlock()
+
+		if draining {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Connection is not drained")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := conn.SendNoResponse(conn.newRequest(cemi.MaskVersionRead, []byte{0})); err != ErrShuttingDown {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	queueResponses(tunnel, addr, cemi.MaskVersionResponse, []byte{0x07, 0xb0})
+
+	if err := <-result; err != nil {
+		t.Errorf("Pending send failed: %v", err)
+	}
+
+	select {
+	case ok := <-drained:
+		if !ok {
+			t.Error("Shutdown did not drain the pending send")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Shutdown did not return")
+	}
+
+	if conn.isConnected() {
+		t.Error("Connection is still open")
+	}
+
+	if err := tunnel.Send(conn.newRequest(cemi.MaskVersionRead, []byte{0})); err != ErrShuttingDown {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	// No goroutine started for the shutdown may outlive it.
+	for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() > goroutines; {
+		if time.Now().After(deadline) {
+			t.Fatalf("Leaked %d goroutines", runtime.NumGoroutine()-goroutines)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestManagement_Close(t *testing.T) {
+	// Closing while connections are being established must neither deadlock nor leak connections.
+	t.Run("ConcurrentConnect", func(t *testing.T) {
+		m, tunnel := makeTestManagement(t)
+
+		first := make(chan struct{})
+		finished := make(chan []*P2PConnection)
+
+		go func() {
+			var conns []*P2PConnection
+
+			for i := 1; i <= 5; i++ {
+				// Additional confirmations may be consumed by connections which already exist.
+				confirmConnect(tunnel)
+				confirmConnect(tunnel)
+
+				conn, err := m.Connect(cemi.NewIndividualAddr3(1, 1, uint8(i)))
+				if err == nil {
+					conns = append(conns, conn)
+				}
+
+				if i == 1 {
+					close(first)
+				}
+
+				if err == ErrManagementClosed {
+					break
+				}
+			}
+
+			finished <- conns
+		}()
+
+		<-first
+
+		closed := make(chan struct{})
+		go func() {
+			m.Close()
+			close(closed)
+		}()
+
+		var conns []*P2PConnection
+
+		select {
+		case <-closed:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Close did not return")
+		}
+
+		select {
+		case conns = <-finished:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Connect did not return")
+		}
+
+		if len(conns) == 0 {
+			t.Fatal("No connection was established")
+		}
+
+		for _, conn := range conns {
+			if conn.isConnected() {
+				t.Errorf("Connection to %v is still open", conn.targetAddr)
+			}
+		}
+
+		if _, err := m.Connect(cemi.NewIndividualAddr3(1, 1, 10)); err != ErrManagementClosed {
+			t.Errorf("Unexpected error %v", err)
+		}
+
+		// Closing again is a no-op.
+		m.Close()
+	})
+}
+
+func TestManagement_GroupWrite(t *testing.T) {
+	dst := cemi.NewGroupAddr3(1, 2, 3)
+
+	makeManagement := func() (*Management, *Tunnel, *dummySocket) {
+		client, gateway := newDummySockets()
+		t.Cleanup(func() {
+			client.Close()
+			gateway.Close()
+		})
+
+		config := DefaultTunnelConfig
+		config.UseTCP = true
+
+		tunnel := makeTunnelConn(client, config, 1)
+
+		return NewManagement(tunnel), tunnel, gateway
+	}
+
+	t.Run("Bool", func(t *testing.T) {
+		m, _, gateway := makeManagement()
+
+		if err := m.GroupWriteBool(dst, true); err != nil {
+			t.Fatal(err)
+		}
+
+		expectGroupWrite(t, gateway, dst, []byte{1})
+	})
+
+	t.Run("Scaling", func(t *testing.T) {
+		m, _, gateway := makeManagement()
+
+		if err := m.GroupWriteScaling(dst, 100); err != nil {
+			t.Fatal(err)
+		}
+
+		expectGroupWrite(t, gateway, dst, []byte{0, 255})
+	})
+
+	t.Run("Confirmed", func(t *testing.T) {
+		m, tunnel, gateway := makeManagement()
+		m.SetGroupConfirmation(time.Second)
+
+		result := make(chan error)
+		go func() {
+			result <- m.GroupWriteBool(dst, false)
+		}()
+
+		expectGroupWrite(t, gateway, dst, []byte{0})
+
+		other := &cemi.LDataInd{LData: cemi.LData{Source: cemi.NewIndividualAddr3(1, 1, 5)}}
+		con := &cemi.LDataCon{LData: cemi.LData{
+			Control2:    cemi.Control2GroupAddr,
+			Destination: uint16(dst),
+		}}
+
+		tunnel.pushInbound(other)
+		tunnel.pushInbound(con)
+
+		if err := <-result; err != nil {
+			t.Fatal(err)
+		}
+
+		// Waiting for the confirmation leaves the messages to the consumers of the tunnel.
+		for _, expected := range []cemi.Message{other, con} {
+			if msg := <-tunnel.Inbound(); msg != expected {
+				t.Errorf("Unexpected message %v", msg)
+			}
+		}
+	})
+
+	t.Run("NotConfirmed", func(t *testing.T) {
+		m, tunnel, gateway := makeManagement()
+		m.SetGroupConfirmation(time.Second)
+
+		result := make(chan error)
+		go func() {
+			result <- m.GroupWriteBool(dst, false)
+		}()
+
+		expectGroupWrite(t, gateway, dst, []byte{0})
+
+		tunnel.pushInbound(&cemi.LDataCon{LData: cemi.LData{
+			Control1:    cemi.Control1HasError,
+			Control2:    cemi.Control2GroupAddr,
+			Destination: uint16(dst),
+		}})
+
+		if err := <-result; err == nil {
+			t.Fatal("Should not succeed")
+		}
+	})
+}
+
+func TestP2PConnection_Send(t *testing.T) {
+	addr := cemi.NewIndividualAddr3(1, 1, 5)
+
+	makeReq := func() *cemi.LDataReq {
+		return &cemi.LDataReq{LData: cemi.LData{
+			Destination: uint16(addr),
+			Data:        &cemi.AppData{Command: cemi.MaskVersionRead, Data: []byte{0}},
+		}}
+	}
+
+	// The device closes the connection.
+	t.Run("PeerDisconnected", func(t *testing.T) {
+		m, tunnel := makeTestManagement(t)
+		confirmConnect(tunnel)
+
+		conn, err := m.Connect(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tunnel.inbound <- &cemi.LDataInd{LData: cemi.LData{
+			Source:      addr,
+			Destination: uint16(tunnel.SourceAddr()),
+			Data:        cemi.TDisconnect(),
+		}}
+
+		select {
+		case <-conn.done:
+		case <-time.After(time.Second):
+			t.Fatal("Connection has not been closed")
+		}
+
+		if _, err := conn.Send(makeReq(), cemi.MaskVersionResponse, time.Second); err != ErrPeerDisconnected {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+
+	// The caller closes the connection.
+	t.Run("NotConnected", func(t *testing.T) {
+		m, tunnel := makeTestManagement(t)
+		confirmConnect(tunnel)
+
+		conn, err := m.Connect(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := m.Disconnect(addr); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := conn.Send(makeReq(), cemi.MaskVersionResponse, time.Second); err != ErrNotConnected {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+}
+
+func TestP2PConnection_SendSegmented(t *testing.T) {
+	addr := cemi.NewIndividualAddr3(1, 1, 5)
+
+	m, tunnel := makeTestManagement(t)
+	confirmConnect(tunnel)
+
+	conn, err := m.Connect(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Disconnect(addr)
+
+	// The device splits 40 bytes of property data into three segments, each of them preceded by
+	// the 4 byte property header.
+	expected := make([]byte, 40)
+	for i := range expected {
+		expected[i] = byte(i)
+	}
+
+	header := []byte{0x00, 0x0b, 0x10, 0x01}
+
+	tunnel.inbound <- &cemi.LDataInd{LData: cemi.LData{Source: addr, Data: cemi.TAck(0)}}
+
+	for i, chunk := range [][]byte{expected[:14], expected[14:28], expected[28:]} {
+		tunnel.inbound <- &cemi.LDataInd{LData: cemi.LData{
+			Source: addr,
+			Data: &cemi.AppData{
+				Numbered:  true,
+				SeqNumber: uint8(i),
+				Command:   cemi.PropertyValueResponse,
+				Data:      append(append([]byte(nil), header...), chunk...),
+			},
+		}}
+	}
+
+	req := &cemi.LDataReq{LData: cemi.LData{
+		Destination: uint16(addr),
+		Data:        &cemi.AppData{Command: cemi.PropertyValueRead, Data: header},
+	}}
+
+	received := 0
+	data, err := conn.SendSegmented(
+		req, cemi.PropertyValueResponse, time.Second,
+		func(app *cemi.AppData) ([]byte, bool) {
+			received += len(app.Data) - len(header)
+			return app.Data[len(header):], received < len(expected)
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(data, expected) {
+		t.Errorf("Unexpected data: %v", data)
+	}
+}
+
+func TestP2PConnection_Err(t *testing.T) {
+	addr := cemi.NewIndividualAddr3(1, 1, 5)
+
+	// drain waits for the inbound channel of the connection to be closed.
+	drain := func(t *testing.T, conn *P2PConnection) {
+		timeout := time.After(time.Second)
+
+		for {
+			select {
+			case _, open := <-conn.Inbound():
+				if !open {
+					return
+				}
+
+			case <-timeout:
+				t.Fatal("Inbound channel has not been closed")
+			}
+		}
+	}
+
+	// Closing the connection on purpose is not an error.
+	t.Run("Disconnect", func(t *testing.T) {
+		m, tunnel := makeTestManagement(t)
+		confirmConnect(tunnel)
+
+		conn, err := m.Connect(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := m.Disconnect(addr); err != nil {
+			t.Fatal(err)
+		}
+
+		drain(t, conn)
+
+		if err := conn.Err(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+
+	// The tunnel fails while the connection is established.
+	t.Run("TunnelClosed", func(t *testing.T) {
+		m, tunnel := makeTestManagement(t)
+		confirmConnect(tunnel)
+
+		conn, err := m.Connect(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		close(tunnel.inbound)
+
+		drain(t, conn)
+
+		if err := conn.Err(); err != ErrTunnelClosed {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+}
+
+func TestManagement_GroupReadValue(t *testing.T) {
+	dst := cemi.NewGroupAddr3(1, 2, 3)
+
+	m, tunnel, sent := makeRecordingManagement(t)
+
+	type result struct {
+		data []byte
+		err  error
+	}
+
+	results := make(chan result)
+	go func() {
+		data, err := m.GroupReadValue(dst, time.Second)
+		results <- result{data, err}
+	}()
+
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Fatal("A_GroupValue_Read has not been sent")
+	}
+
+	messages := []cemi.Message{
+		// Responses to other group addresses and other commands are ignored.
+		&cemi.LDataInd{LData: cemi.LData{
+			Control2:    cemi.Control2GroupAddr,
+			Destination: uint16(cemi.NewGroupAddr3(1, 2, 4)),
+			Data:        &cemi.AppData{Command: cemi.GroupValueResponse, Data: []byte{0, 0x11}},
+		}},
+		&cemi.LDataInd{LData: cemi.LData{
+			Control2:    cemi.Control2GroupAddr,
+			Destination: uint16(dst),
+			Data:        &cemi.AppData{Command: cemi.GroupValueWrite, Data: []byte{0, 0x22}},
+		}},
+
+		// The response is unnumbered and carries an arbitrary sequence number.
+		&cemi.LDataInd{LData: cemi.LData{
+			Control2:    cemi.Control2GroupAddr,
+			Destination: uint16(dst),
+			Data: &cemi.AppData{
+				SeqNumber: 7,
+				Command:   cemi.GroupValueResponse,
+				Data:      []byte{0, 0x33},
+			},
+		}},
+	}
+
+	for _, msg := range messages {
+		tunnel.pushInbound(msg)
+	}
+
+	res := <-results
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+
+	if !bytes.Equal(res.data, []byte{0, 0x33}) {
+		t.Errorf("Unexpected data: %v", res.data)
+	}
+
+	// Waiting for the response leaves the messages to the consumers of the tunnel.
+	for _, expected := range messages {
+		if msg := <-tunnel.Inbound(); msg != expected {
+			t.Errorf("Unexpected message %v", msg)
+		}
+	}
+}
+
+func TestP2PConnection_handleDisconnect(t *testing.T) {
+	addr := cemi.NewIndividualAddr3(1, 1, 5)
+
+	// A T_DISCONNECT initiated by the device closes the connection.
+	t.Run("DeviceInitiated", func(t *testing.T) {
+		m, tunnel := makeTestManagement(t)
+		confirmConnect(tunnel)
+
+		conn, err := m.Connect(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tunnel.inbound <- &cemi.LDataInd{LData: cemi.LData{
+			Source:      addr,
+			Destination: uint16(tunnel.SourceAddr()),
+			Data:        cemi.TDisconnect(),
+		}}
+
+		select {
+		case <-conn.done:
+		case <-time.After(time.Second):
+			t.Fatal("Connection has not been closed")
+		}
+
+		if conn.isConnected() {
+			t.Error("Connection should be closed")
+		}
+	})
+
+	// Telegrams of other devices are not considered.
+	t.Run("OtherDevice", func(t *testing.T) {
+		conn := &P2PConnection{
+			tunnel:     makeTunnelConn(nil, DefaultTunnelConfig, 1),
+			targetAddr: addr,
+			connected:  true,
+			done:       make(chan struct{}),
+		}
+
+		handled := conn.handleDisconnect(&cemi.LDataInd{LData: cemi.LData{
+			Source:      cemi.NewIndividualAddr3(1, 1, 6),
+			Destination: uint16(conn.tunnel.SourceAddr()),
+			Data:        cemi.TDisconnect(),
+		}})
+		if handled || !conn.isConnected() {
+			t.Error("T_DISCONNECT of another device must not close the connection")
+		}
+	})
+	// Regression: a T_DISCONNECT travelling from the tunnel to the device, as sent by Disconnect,
+	// used to be mistaken for one of the device, while the device's own went unnoticed.
+	t.Run("ControllerDirection", func(t *testing.T) {
+		conn := &P2PConnection{
+			tunnel:     makeTunnelConn(nil, DefaultTunnelConfig, 1),
+			targetAddr: addr,
+			connected:  true,
+			done:       make(chan struct{}),
+		}
+
+		handled := conn.handleDisconnect(&cemi.LDataInd{LData: cemi.LData{
+			Source:      conn.tunnel.SourceAddr(),
+			Destination: uint16(addr),
+			Data:        cemi.TDisconnect(),
+		}})
+		if handled || !conn.isConnected() {
+			t.Fatal("T_DISCONNECT to the device must not close the connection")
+		}
+
+		handled = conn.handleDisconnect(&cemi.LDataInd{LData: cemi.LData{
+			Source:      addr,
+			Destination: uint16(conn.tunnel.SourceAddr()),
+			Data:        cemi.TDisconnect(),
+		}})
+		if !handled || conn.isConnected() {
+			t.Fatal("T_DISCONNECT of the device must close the connection")
+		}
+
+		if err := conn.closedErr(); err != ErrPeerDisconnected {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+}
+
+func TestManagement_ConnectionReset(t *testing.T) {
+	addr := cemi.NewIndividualAddr3(1, 1, 5)
+
+	makeReq := func() *cemi.LDataReq {
+		return &cemi.LDataReq{LData: cemi.LData{
+			Destination: uint16(addr),
+			Data:        &cemi.AppData{Command: cemi.MemoryWrite, Data: []byte{0x01, 0x00, 0x60, 0x00}},
+		}}
+	}
+
+	// rejectAll queues a T_NAK for the first request and each of its repetitions.
+	rejectAll := func(tunnel *Tunnel) {
+		for i := 0; i <= maxRepetitions; i++ {
+			tunnel.inbound <- &cemi.LDataInd{LData: cemi.LData{
+				Source:      addr,
+				Destination: uint16(tunnel.SourceAddr()),
+				Data:        cemi.TNak(0),
+			}}
+		}
+	}
+
+	// A connection which has been reset is forgotten, so that the next Connect creates a new one.
+	t.Run("Reconnect", func(t *testing.T) {
+		m, tunnel := makeTestManagement(t)
+		confirmConnect(tunnel)
+
+		conn, err := m.Connect(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rejectAll(tunnel)
+
+		if err := conn.SendNoResponse(makeReq()); err != ErrConnectionReset {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if err := conn.Err(); err != ErrConnectionReset {
+			t.Errorf("Unexpected connection error: %v", err)
+		}
+
+		if m.GetConnection(addr) != nil {
+			t.Fatal("Connection should have been removed")
+		}
+
+		confirmConnect(tunnel)
+
+		fresh, err := m.Connect(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer m.Disconnect(addr)
+
+		if fresh == conn {
+			t.Fatal("Connection has been reused")
+		}
+
+		tunnel.inbound <- &cemi.LDataInd{LData: cemi.LData{
+			Source:      addr,
+			Destination: uint16(tunnel.SourceAddr()),
+			Data:        cemi.TAck(0),
+		}}
+
+		if err := fresh.SendNoResponse(makeReq()); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+
+	// Procedures are repeated with a new connection if reconnect attempts are configured.
+	t.Run("Retry", func(t *testing.T) {
+		m, tunnel := makeTestManagement(t)
+		m.SetReconnectAttempts(1)
+
+		confirmConnect(tunnel)
+		rejectAll(tunnel)
+
+		calls := 0
+
+		err := m.WithConnection(addr, func(conn *P2PConnection) error {
+			calls++
+
+			if calls == 2 {
+				tunnel.inbound <- &cemi.LDataInd{LData: cemi.LData{
+					Source:      addr,
+					Destination: uint16(tunnel.SourceAddr()),
+					Data:        cemi.TAck(0),
+				}}
+			}
+
+			err := conn.SendNoResponse(makeReq())
+
+			// The new connection is established once the first one has been reset.
+			if calls == 1 {
+				confirmConnect(tunnel)
+			}
+
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if calls != 2 {
+			t.Errorf("Unexpected number of calls: %d", calls)
+		}
+
+		m.Disconnect(addr)
+	})
+}
+
+func TestP2PConnection_Resync(t *testing.T) {
+	addr := cemi.NewIndividualAddr3(1, 1, 5)
+
+	conn, tunnel, sent := connectRecording(t, addr)
+
+	// The device expects another sequence number and rejects the telegram. After reconnecting,
+	// the repeated telegram is acknowledged.
+	tunnel.inbound <- &cemi.LDataInd{LData: cemi.LData{Source: addr, Data: cemi.TNak(5)}}
+	confirmConnect(tunnel)
+	tunnel.inbound <- &cemi.LDataInd{LData: cemi.LData{Source: addr, Data: cemi.TAck(0)}}
+
+	if err := conn.WriteMemoryBits(0x0116, []byte{0xfe}, []byte{0x01}, time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []cemi.TransportUnit{
+		&cemi.AppData{Numbered: true, SeqNumber: 0, Command: cemi.MemoryBitWrite},
+		cemi.TDisconnect(),
+		cemi.TConnect(),
+		&cemi.AppData{Numbered: true, SeqNumber: 0, Command: cemi.MemoryBitWrite},
+	}
+
+	for _, exp := range expected {
+		var req *cemi.LDataReq
+
+		select {
+		case req = <-sent:
+		case <-time.After(time.Second):
+			t.Fatal("No request has been sent")
+		}
+
+		if app, ok := exp.(*cemi.AppData); ok {
+			got, ok := req.Data.(*cemi.AppData)
+			if !ok || got.Command != app.Command || !got.Numbered || got.SeqNumber != app.SeqNumber {
+				t.Fatalf("Unexpected transport unit %#v", req.Data)
+			}
+
+			continue
+		}
+
+		if reflect.TypeOf(req.Data) != reflect.TypeOf(exp) {
+			t.Fatalf("Unexpected transport unit %T, expected %T", req.Data, exp)
+		}
+	}
+
+	if stats := conn.Stats(); stats.Resyncs != 1 {
+		t.Errorf("Unexpected number of resyncs: %d", stats.Resyncs)
+	}
+
+	if !conn.isConnected() {
+		t.Error("Connection should remain established")
+	}
+}
+
+func TestP2PConnection_AckResponse(t *testing.T) {
+	addr := cemi.NewIndividualAddr3(1, 1, 5)
+	source := cemi.NewIndividualAddr3(1, 1, 250)
+
+	m, tunnel, sent := makeRecordingManagement(t)
+	tunnel.addr = source
+	confirmConnect(tunnel)
+
+	conn, err := m.Connect(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Disconnect(addr)
+
+	// Skip the T_CONNECT.
+	<-sent
+
+	// The device numbers its response independently of the request.
+	tunnel.inbound <- &cemi.LDataInd{LData: cemi.LData{
+		Source:      addr,
+		Destination: uint16(source),
+		Data:        cemi.TAck(0),
+	}}
+	tunnel.inbound <- &cemi.LDataInd{LData: cemi.LData{
+		Source:      addr,
+		Destination: uint16(source),
+		Data: &cemi.AppData{
+			Numbered:  true,
+			SeqNumber: 7,
+			Command:   cemi.MaskVersionResponse,
+			Data:      []byte{0x07, 0x05},
+		},
+	}}
+
+	req := conn.newRequest(cemi.MaskVersionRead, []byte{0})
+	if _, err := conn.Send(req, cemi.MaskVersionResponse, time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	// Skip the request.
+	<-sent
+
+	var ack *cemi.LDataReq
+
+	select {
+	case ack = <-sent:
+	case <-time.After(time.Second):
+		t.Fatal("No ACK has been sent")
+	}
+
+	ctrl, ok := ack.Data.(*cemi.ControlAck)
+	if !ok {
+		t.Fatalf("Unexpected transport unit %T", ack.Data)
+	}
+
+	if ctrl.SeqNumber != 7 {
+		t.Errorf("Unexpected sequence number: %d", ctrl.SeqNumber)
+	}
+
+	if ack.Source != source || ack.Destination != uint16(addr) || ack.Control2.IsGroupAddr() {
+		t.Errorf("Unexpected addressing: %v -> %v", ack.Source, ack.Destination)
+	}
+}
+
+func TestP2PConnection_DefaultTimeouts(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Waits for the default timeouts")
+	}
+
+	addr := cemi.NewIndividualAddr3(1, 1, 5)
+
+	procedures := []struct {
+		name    string
+		timeout time.Duration
+		run     func(conn *P2PConnection) error
+	}{
+		{"ReadMemory", 3 * time.Second, func(conn *P2PConnection) error {
+			_, err := conn.ReadMemoryDefault(0x4000, 4)
+			return err
+		}},
+		{"ReadProperty", 3 * time.Second, func(conn *P2PConnection) error {
+			_, err := conn.ReadPropertyDefault(0, 11, 1, 1)
+			return err
+		}},
+		{"Restart", 10 * time.Second, func(conn *P2PConnection) error {
+			_, err := conn.RestartDefault(EraseConfirmedRestart, 0)
+			return err
+		}},
+	}
+
+	// The device acknowledges the requests, but never responds.
+	for _, proc := range procedures {
+		proc := proc
+		t.Run(proc.name, func(t *testing.T) {
+			t.Parallel()
+
+			conn, tunnel, sent := connectRecording(t, addr)
+			serveFakeDevice(t, tunnel, sent, addr, func(*cemi.AppData) *cemi.AppData { return nil })
+
+			start := time.Now()
+
+			if err := proc.run(conn); err == nil {
+				t.Fatal("Should not succeed")
+			}
+
+			if elapsed := time.Since(start); elapsed < proc.timeout || elapsed > proc.timeout+time.Second {
+				t.Errorf("Waited %v, expected %v", elapsed, proc.timeout)
+			}
+		})
+	}
+}
+
+func TestP2PConnection_Timeouts(t *testing.T) {
+	addr := cemi.NewIndividualAddr3(1, 1, 5)
+
+	makeReq := func() *cemi.LDataReq {
+		return &cemi.LDataReq{LData: cemi.LData{
+			Destination: uint16(addr),
+			Data:        &cemi.AppData{Command: cemi.MaskVersionRead, Data: []byte{0}},
+		}}
+	}
+
+	// The connection starts with the tunnel's response timeout and resets to it.
+	t.Run("Defaults", func(t *testing.T) {
+		conn, tunnel, _ := connectRecording(t, addr)
+
+		if timeout := conn.ResponseTimeout(); timeout != tunnel.config.ResponseTimeout {
+			t.Errorf("Unexpected response timeout: %v", timeout)
+		}
+
+		if timeout := conn.ApplicationTimeout(); timeout != defaultApplicationTimeout {
+			t.Errorf("Unexpected application timeout: %v", timeout)
+		}
+
+		conn.SetResponseTimeout(time.Millisecond)
+		conn.SetResponseTimeout(0)
+
+		if timeout := conn.ResponseTimeout(); timeout != tunnel.config.ResponseTimeout {
+			t.Errorf("Unexpected response timeout after reset: %v", timeout)
+		}
+	})
+
+	// The T_ACK of the device is awaited for the response timeout.
+	t.Run("ResponseTimeout", func(t *testing.T) {
+		conn, _, _ := connectRecording(t, addr)
+		conn.SetResponseTimeout(100 * time.Millisecond)
+
+		start := time.Now()
+
+		if err := conn.SendNoResponse(makeReq()); err == nil {
+			t.Fatal("Should not succeed")
+		}
+
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Waited %v for the T_ACK", elapsed)
+		}
+	})
+
+	// Without a timeout of its own, Send waits for the application timeout.
+	t.Run("ApplicationTimeout", func(t *testing.T) {
+		conn, tunnel, _ := connectRecording(t, addr)
+		conn.SetApplicationTimeout(100 * time.Millisecond)
+
+		tunnel.inbound <- &cemi.LDataInd{LData: cemi.LData{Source: addr, Data: cemi.TAck(0)}}
+
+		start := time.Now()
+
+		if _, err := conn.Send(makeReq(), cemi.MaskVersionResponse, 0); err == nil {
+			t.Fatal("Should not succeed")
+		}
+
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Waited %v for the response", elapsed)
+		}
+	})
+}
+
+func TestManagement_Ping(t *testing.T) {
+	addr := cemi.NewIndividualAddr3(1, 1, 5)
+
+	// fakeDevice confirms the T_CONNECT after a delay and returns the transport units which have
+	// been sent up to the T_DISCONNECT.
+	fakeDevice := func(
+		tunnel *Tunnel,
+		sent <-chan *cemi.LDataReq,
+		delay time.Duration,
+		ctrl1 cemi.ControlField1,
+	) <-chan []cemi.TransportUnit {
+		done := make(chan []cemi.TransportUnit, 1)
+
+		go func() {
+			var units []cemi.TransportUnit
+
+			for req := range sent {
+				units = append(units, req.Data)
+
+				switch req.Data.(type) {
+				case *cemi.ControlConn:
+					time.Sleep(delay)
+					tunnel.pushInbound(&cemi.LDataCon{LData: cemi.LData{
+						Control1:    ctrl1,
+						Destination: uint16(addr),
+						Data:        cemi.TConnect(),
+					}})
+
+				case *cemi.ControlDisc:
+					done <- units
+					return
+				}
+			}
+		}()
+
+		return done
+	}
+
+	// expectUnits checks that the ping has sent a T_CONNECT and a T_DISCONNECT.
+	expectUnits := func(t *testing.T, done <-chan []cemi.TransportUnit) {
+		t.Helper()
+
+		select {
+		case units := <-done:
+			if len(units) != 2 {
+				t.Errorf("Unexpected telegrams: %v", units)
+			}
+
+		case <-time.After(time.Second):
+			t.Fatal("Transport connection has not been closed")
+		}
+	}
+
+	// The device acknowledges the T_CONNECT after a delay.
+	t.Run("Reachable", func(t *testing.T) {
+		m, tunnel, sent := makeRecordingManagement(t)
+
+		const delay = 100 * time.Millisecond
+		done := fakeDevice(tunnel, sent, delay, 0)
+
+		reachable, latency, err := m.Ping(addr, time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reachable {
+			t.Fatal("Device should be reachable")
+		}
+
+		if latency < delay || latency > delay+500*time.Millisecond {
+			t.Errorf("Unexpected latency: %v", latency)
+		}
+
+		expectUnits(t, done)
+
+		if m.GetConnection(addr) != nil {
+			t.Error("No connection should be open")
+		}
+	})
+
+	// The device does not acknowledge the T_CONNECT on the bus.
+	t.Run("NotAcknowledged", func(t *testing.T) {
+		m, tunnel, sent := makeRecordingManagement(t)
+		done := fakeDevice(tunnel, sent, 0, cemi.Control1HasError)
+
+		reachable, _, err := m.Ping(addr, time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if reachable {
+			t.Error("Device should not be reachable")
+		}
+
+		expectUnits(t, done)
+	})
+
+	// The T_CONNECT is not confirmed at all.
+	t.Run("Unreachable", func(t *testing.T) {
+		m, _ := makeTestManagement(t)
+
+		reachable, _, err := m.Ping(addr, 100*time.Millisecond)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if reachable {
+			t.Error("Device should not be reachable")
+		}
+
+		if m.GetConnection(addr) != nil {
+			t.Error("No connection should be open")
+		}
+	})
+
+	// A connection of the caller is neither used nor closed.
+	t.Run("ConnectionInUse", func(t *testing.T) {
+		m, tunnel, sent := makeRecordingManagement(t)
+		confirmConnect(tunnel)
+
+		conn, err := m.Connect(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		<-sent // T_CONNECT of the connection
+
+		timeout := conn.ResponseTimeout()
+
+		if _, _, err := m.Ping(addr, time.Second); err == nil {
+			t.Fatal("Should not succeed")
+		}
+
+		if !conn.isConnected() || m.GetConnection(addr) != conn {
+			t.Error("Connection should still be open")
+		}
+
+		if conn.ResponseTimeout() != timeout {
+			t.Errorf("Response timeout has been changed to %v", conn.ResponseTimeout())
+		}
+
+		select {
+		case req := <-sent:
+			t.Errorf("Unexpected telegram: %v", req.Data)
+		default:
+		}
+	})
+}
+
+func TestP2PConnection_DisconnectConcurrent(t *testing.T) {
+	addr := cemi.NewIndividualAddr3(1, 1, 5)
+
+	m, tunnel, sent := makeRecordingManagement(t)
+
+	sub := m.Subscribe(10)
+	defer sub.Cancel()
+
+	confirmConnect(tunnel)
+	conn, err := m.Connect(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Skip the T_CONNECT and the event of opening the connection.
+	<-sent
+	expectEvent(t, sub)
+
+	start := make(chan struct{})
+	errs := make(chan error, 3)
+
+	for i := 0; i < 3; i++ {
+		go func() {
+			<-start
+			errs <- conn.Disconnect()
+		}()
+	}
+
+	close(start)
+
+	for i := 0; i < 3; i++ {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+
+	// Exactly one T_DISCONNECT has been sent.
+	select {
+	case req := <-sent:
+		if _, ok := req.Data.(*cemi.ControlDisc); !ok {
+			t.Errorf("Unexpected request %+v", req)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("No T_DISCONNECT has been sent")
+	}
+
+	select {
+	case req := <-sent:
+		t.Errorf("Unexpected request %+v", req)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// The connection has been closed exactly once.
+	if event := expectEvent(t, sub); event.Type != ConnectionClosed {
+		t.Errorf("Unexpected event %+v", event)
+	}
+
+	select {
+	case event := <-sub.Events():
+		t.Errorf("Unexpected event %+v", event)
+	default:
+	}
+
+	if err := conn.Disconnect(); err != nil {
+		t.Errorf("Disconnecting again failed: %v", err)
+	}
+}
@@ -0,0 +1,177 @@
+// Licensed under the MIT license which can be found in the LICENSE file.
+
+package knx
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/LB-00/knx-go/knx/cemi"
+)
+
+// DefaultMemoryReadTimeout is how long ReadMemoryDefault waits for each response of the device.
+const DefaultMemoryReadTimeout = 3 * time.Second
+
+// ReadMemoryDefault works like ReadMemory, but waits up to DefaultMemoryReadTimeout for each
+// response.
+func (conn *P2PConnection) ReadMemoryDefault(addr uint16, count uint) ([]byte, error) {
+	return conn.ReadMemory(addr, count, DefaultMemoryReadTimeout)
+}
+
+// ReadMemory reads count bytes of the memory of the device starting at the given address using
+// A_Memory_Read. Reads which exceed the maximum APDU length are split into several requests, each
+// of which waits up to t. Use WithBudget to limit the duration of all requests together. If the
+// device responds with fewer bytes than requested, the remaining bytes are requested in turn.
+func (conn *P2PConnection) ReadMemory(addr uint16, count uint, t time.Duration) ([]byte, error) {
+	if uint(addr)+count > 0x10000 {
+		return nil, errors.New("memory range exceeds the address space")
+	}
+
+	chunkSize := conn.MemoryChunkSize()
+	if chunkSize == 0 {
+		return nil, errors.New("maximum APDU length is too small for memory reads")
+	}
+
+	result := make([]byte, 0, count)
+
+	for count > 0 {
+		n := count
+		if n > chunkSize {
+			n = chunkSize
+		}
+
+		data, err := conn.readMemoryChunk(addr, uint8(n), t)
+		if err != nil {
+			return nil, err
+		}
+
+		// The response may be partial, in which case the next request continues after it.
+		result = append(result, data...)
+		addr += uint16(len(data))
+		count -= uint(len(data))
+	}
+
+	return result, nil
+}
+
+// readMemoryChunk reads up to 63 bytes of memory with a single A_Memory_Read. The device may
+// respond with fewer bytes than requested.
+func (conn *P2PConnection) readMemoryChunk(addr uint16, count uint8, t time.Duration) ([]byte, error) {
+	header := []byte{count, byte(addr >> 8), byte(addr)}
+
+	res, err := conn.Send(conn.newRequest(cemi.MemoryRead, header), cemi.MemoryResponse, t)
+	if err != nil {
+		return nil, err
+	}
+
+	data := res.(*cemi.LDataInd).LData.Data.(*cemi.AppData).Data
+	if len(data) < len(header) || !bytes.Equal(data[1:len(header)], header[1:]) {
+		return nil, errors.New("memory response does not match the request")
+	}
+
+	// A device signals an error by responding with zero bytes.
+	n := data[0] & 0x3f
+	if n == 0 || n > count || len(data)-len(header) != int(n) {
+		return nil, fmt.Errorf("unable to read %d bytes of memory at %#04x", count, addr)
+	}
+
+	return data[len(header):], nil
+}
+
+// WriteMemoryBits modifies the memory of the device at the given address using A_MemoryBit_Write.
+// The device combines each byte with the masks as (old AND andMask) XOR xorMask, which allows
+// flipping individual bits without touching their neighbours. Both masks must have the same length.
+func (conn *P2PConnection) WriteMemoryBits(addr uint16, andMask, xorMask []byte, t time.Duration) error {
+	if len(andMask) != len(xorMask) {
+		return errors.New("AND and XOR masks must have the same length")
+	}
+
+	if len(andMask) == 0 || len(andMask) > 255 {
+		return errors.New("number of bytes must be between 1 and 255")
+	}
+
+	data := make([]byte, 3, 3+2*len(andMask))
+	data[0] = byte(len(andMask))
+	data[1] = byte(addr >> 8)
+	data[2] = byte(addr)
+	data = append(data, andMask...)
+	data = append(data, xorMask...)
+
+	_, err := conn.sendWrite(conn.newRequest(cemi.MemoryBitWrite, data), t)
+	return err
+}
+
+// packUserMemoryHeader packs the header of the user memory services. The 4 most significant bits
+// of the 20-bit address share an octet with the 4-bit byte count.
+func packUserMemoryHeader(addr uint32, count uint8) ([]byte, error) {
+	if addr > 0xfffff {
+		return nil, fmt.Errorf("user memory address %#x exceeds 20 bits", addr)
+	}
+
+	if count == 0 || count > 15 {
+		return nil, errors.New("number of bytes must be between 1 and 15")
+	}
+
+	return []byte{byte(addr>>12)&0xf0 | count, byte(addr >> 8), byte(addr)}, nil
+}
+
+// ReadUserMemory reads count bytes of the user memory of the device at the given 20-bit address
+// using A_UserMemory_Read. User memory is found in older devices such as BCU 1 and BCU 2.
+func (conn *P2PConnection) ReadUserMemory(addr uint32, count uint8, t time.Duration) ([]byte, error) {
+	header, err := packUserMemoryHeader(addr, count)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := conn.Send(conn.newRequest(cemi.UserMemoryRead, header), cemi.UserMemoryResponse, t)
+	if err != nil {
+		return nil, err
+	}
+
+	data := res.(*cemi.LDataInd).LData.Data.(*cemi.AppData).Data
+	if len(data) < len(header) || !bytes.Equal(data[:len(header)], header) {
+		return nil, errors.New("user memory response does not match the request")
+	}
+
+	if len(data)-len(header) != int(count) {
+		return nil, fmt.Errorf("expected %d bytes of user memory, got %d", count, len(data)-len(header))
+	}
+
+	return data[len(header):], nil
+}
+
+// WriteUserMemory writes the data to the user memory of the device at the given 20-bit address
+// using A_UserMemory_Write.
+func (conn *P2PConnection) WriteUserMemory(addr uint32, data []byte, t time.Duration) error {
+	if len(data) > 15 {
+		return errors.New("number of bytes must be between 1 and 15")
+	}
+
+	header, err := packUserMemoryHeader(addr, uint8(len(data)))
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.sendWrite(conn.newRequest(cemi.UserMemoryWrite, append(header, data...)), t)
+	return err
+}
+
+// ReadManufacturerInfo reads the manufacturer identification from the user area of the device
+// using A_UserManufacturerInfo_Read.
+func (conn *P2PConnection) ReadManufacturerInfo(t time.Duration) (manufacturerID uint8, manufacturerData [2]byte, err error) {
+	res, err := conn.Send(conn.newRequest(cemi.UserManufacturerInfoRead, nil), cemi.UserManufacturerInfoResponse, t)
+	if err != nil {
+		return 0, manufacturerData, err
+	}
+
+	data := res.(*cemi.LDataInd).LData.Data.(*cemi.AppData).Data
+	if len(data) != 3 {
+		return 0, manufacturerData, fmt.Errorf("expected 3 bytes of manufacturer info, got %d", len(data))
+	}
+
+	copy(manufacturerData[:], data[1:])
+
+	return data[0], manufacturerData, nil
+}
@@ -0,0 +1,343 @@
+// Licensed under the MIT license which can be found in the LICENSE file.
+
+package knx
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/LB-00/knx-go/knx/cemi"
+)
+
+// connectRecording establishes a connection to the device and returns the L_Data.req messages
+// which are sent afterwards.
+func connectRecording(t *testing.T, addr cemi.IndividualAddr) (*P2PConnection, *Tunnel, <-chan *cemi.LDataReq) {
+	m, tunnel, sent := makeRecordingManagement(t)
+	confirmConnect(tunnel)
+
+	conn, err := m.Connect(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { m.Disconnect(addr) })
+
+	// Skip the T_CONNECT.
+	<-sent
+
+	return conn, tunnel, sent
+}
+
+// expectRequest receives the next request and returns its application data.
+func expectRequest(t *testing.T, sent <-chan *cemi.LDataReq, cmd cemi.APCI) []byte {
+	t.Helper()
+
+	select {
+	case req := <-sent:
+		app, ok := req.Data.(*cemi.AppData)
+		if !ok {
+			t.Fatalf("Unexpected transport unit %T", req.Data)
+		}
+
+		if app.Command != cmd {
+			t.Fatalf("Unexpected command: %v", app.Command)
+		}
+
+		return app.Data
+
+	case <-time.After(time.Second):
+		t.Fatal("No request has been sent")
+	}
+
+	return nil
+}
+
+func TestP2PConnection_ReadMemoryPartial(t *testing.T) {
+	addr := cemi.NewIndividualAddr3(1, 1, 5)
+
+	conn, tunnel, sent := connectRecording(t, addr)
+
+	memory := map[uint16]byte{}
+	writeMemory(memory, 0x4000, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
+	device := makeMemoryDevice(0x07B0, memory, nil)
+
+	// The device responds with at most 4 bytes at a time.
+	var requests [][]byte
+	serveFakeDevice(t, tunnel, sent, addr, func(req *cemi.AppData) *cemi.AppData {
+		requests = append(requests, append([]byte(nil), req.Data...))
+
+		if count := req.Data[0] & 0x3f; count > 4 {
+			req.Data[0] = req.Data[0]&0xc0 | 4
+		}
+
+		return device(req)
+	})
+
+	data, err := conn.ReadMemory(0x4000, 10, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(data, []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}) {
+		t.Errorf("Unexpected data: %v", data)
+	}
+
+	expected := [][]byte{{10, 0x40, 0x00}, {6, 0x40, 0x04}, {2, 0x40, 0x08}}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Errorf("Unexpected requests: %x", requests)
+	}
+}
+
+func TestP2PConnection_WriteMemoryBits(t *testing.T) {
+	addr := cemi.NewIndividualAddr3(1, 1, 5)
+
+	t.Run("Masks", func(t *testing.T) {
+		conn, tunnel, sent := connectRecording(t, addr)
+
+		tunnel.inbound <- &cemi.LDataInd{LData: cemi.LData{Source: addr, Data: cemi.TAck(0)}}
+
+		err := conn.WriteMemoryBits(0x0116, []byte{0xfe, 0xff}, []byte{0x01, 0x80}, time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data := expectRequest(t, sent, cemi.MemoryBitWrite)
+		expected := []byte{0x02, 0x01, 0x16, 0xfe, 0xff, 0x01, 0x80}
+
+		if !bytes.Equal(data, expected) {
+			t.Errorf("Unexpected data: %v", data)
+		}
+	})
+
+	t.Run("MismatchingMasks", func(t *testing.T) {
+		conn, _, _ := connectRecording(t, addr)
+
+		if err := conn.WriteMemoryBits(0x0116, []byte{0xfe}, nil, time.Second); err == nil {
+			t.Fatal("Should not succeed")
+		}
+	})
+}
+
+func TestPackUserMemoryHeader(t *testing.T) {
+	header, err := packUserMemoryHeader(0x12345, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(header, []byte{0x13, 0x23, 0x45}) {
+		t.Errorf("Unexpected header: %x", header)
+	}
+
+	if _, err := packUserMemoryHeader(0x100000, 1); err == nil {
+		t.Error("Address exceeding 20 bits should not succeed")
+	}
+
+	if _, err := packUserMemoryHeader(0, 16); err == nil {
+		t.Error("Byte count exceeding 4 bits should not succeed")
+	}
+}
+
+func TestP2PConnection_ReadUserMemory(t *testing.T) {
+	addr := cemi.NewIndividualAddr3(1, 1, 5)
+
+	conn, tunnel, sent := connectRecording(t, addr)
+
+	tunnel.inbound <- &cemi.LDataInd{LData: cemi.LData{Source: addr, Data: cemi.TAck(0)}}
+	tunnel.inbound <- &cemi.LDataInd{LData: cemi.LData{
+		Source: addr,
+		Data: &cemi.AppData{
+			Numbered: true,
+			Command:  cemi.UserMemoryResponse,
+			Data:     []byte{0x13, 0x23, 0x45, 0xaa, 0xbb, 0xcc},
+		},
+	}}
+
+	data, err := conn.ReadUserMemory(0x12345, 3, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(data, []byte{0xaa, 0xbb, 0xcc}) {
+		t.Errorf("Unexpected data: %x", data)
+	}
+
+	if req := expectRequest(t, sent, cemi.UserMemoryRead); !bytes.Equal(req, []byte{0x13, 0x23, 0x45}) {
+		t.Errorf("Unexpected request: %x", req)
+	}
+}
+
+func TestP2PConnection_WriteUserMemory(t *testing.T) {
+	addr := cemi.NewIndividualAddr3(1, 1, 5)
+
+	conn, tunnel, sent := connectRecording(t, addr)
+
+	tunnel.inbound <- &cemi.LDataInd{LData: cemi.LData{Source: addr, Data: cemi.TAck(0)}}
+
+	if err := conn.WriteUserMemory(0xf0102, []byte{0x01, 0x02}, time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	req := expectRequest(t, sent, cemi.UserMemoryWrite)
+	if !bytes.Equal(req, []byte{0xf2, 0x01, 0x02, 0x01, 0x02}) {
+		t.Errorf("Unexpected request: %x", req)
+	}
+}
+
+func TestP2PConnection_ReadManufacturerInfo(t *testing.T) {
+	addr := cemi.NewIndividualAddr3(1, 1, 5)
+
+	conn, tunnel, sent := connectRecording(t, addr)
+
+	// Captured A_UserManufacturerInfo_Response of a device made by manufacturer 0x83.
+	var res cemi.LData
+	if _, err := res.Unpack([]byte{
+		0x00, 0xb0, 0x60, 0x11, 0x05, 0x00, 0x00, 0x04, 0x42, 0xc6, 0x83, 0x01, 0x02,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tunnel.inbound <- &cemi.LDataInd{LData: cemi.LData{Source: addr, Data: cemi.TAck(0)}}
+	tunnel.inbound <- &cemi.LDataInd{LData: res}
+
+	id, data, err := conn.ReadManufacturerInfo(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id != 0x83 || data != [2]byte{0x01, 0x02} {
+		t.Errorf("Unexpected manufacturer info: %#x %x", id, data)
+	}
+
+	// The request consists of the APCI only.
+	if req := expectRequest(t, sent, cemi.UserManufacturerInfoRead); len(req) != 0 {
+		t.Errorf("Unexpected request data: %x", req)
+	}
+}
+
+func TestP2PConnection_WithBudget(t *testing.T) {
+	addr := cemi.NewIndividualAddr3(1, 1, 5)
+
+	// serveSlowly acknowledges and answers each memory read after the given delay.
+	serveSlowly := func(t *testing.T, tunnel *Tunnel, sent <-chan *cemi.LDataReq, delay time.Duration) {
+		stop := make(chan struct{})
+		t.Cleanup(func() { close(stop) })
+
+		go func() {
+			var seq uint8
+
+			for {
+				var req *cemi.LDataReq
+
+				select {
+				case req = <-sent:
+				case <-stop:
+					return
+				}
+
+				app, ok := req.Data.(*cemi.AppData)
+				if !ok || app.Command != cemi.MemoryRead {
+					continue
+				}
+
+				time.Sleep(delay)
+
+				count := app.Data[0]
+				data := append(append([]byte(nil), app.Data...), make([]byte, count)...)
+
+				tunnel.inbound <- &cemi.LDataInd{LData: cemi.LData{Source: addr, Data: cemi.TAck(app.SeqNumber)}}
+				tunnel.inbound <- &cemi.LDataInd{LData: cemi.LData{
+					Source: addr,
+					Data: &cemi.AppData{
+						Numbered:  true,
+						SeqNumber: seq,
+						Command:   cemi.MemoryResponse,
+						Data:      data,
+					},
+				}}
+
+				seq = (seq + 1) % 16
+			}
+		}()
+	}
+
+	// Reading 48 bytes takes four requests, which a slow device cannot answer within the budget.
+	t.Run("Exhausted", func(t *testing.T) {
+		conn, tunnel, sent := connectRecording(t, addr)
+		serveSlowly(t, tunnel, sent, 100*time.Millisecond)
+
+		start := time.Now()
+
+		err := conn.WithBudget(250*time.Millisecond, func() error {
+			_, err := conn.ReadMemory(0x0100, 48, time.Second)
+			return err
+		})
+		if err != ErrBudgetExhausted {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if elapsed := time.Since(start); elapsed > 400*time.Millisecond {
+			t.Errorf("Procedure took %v despite its budget", elapsed)
+		}
+	})
+
+	// A sufficient budget does not interfere with the procedure.
+	t.Run("Sufficient", func(t *testing.T) {
+		conn, tunnel, sent := connectRecording(t, addr)
+		serveSlowly(t, tunnel, sent, 10*time.Millisecond)
+
+		err := conn.WithBudget(5*time.Second, func() error {
+			data, err := conn.ReadMemory(0x0100, 48, time.Second)
+			if err == nil && len(data) != 48 {
+				t.Errorf("Unexpected data: %v", data)
+			}
+
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	// A nested budget cannot extend the enclosing one, which applies again afterwards.
+	t.Run("Nested", func(t *testing.T) {
+		conn, _, _ := connectRecording(t, addr)
+
+		limited := func() time.Duration {
+			timeout, err := conn.limitTimeout(time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			return timeout
+		}
+
+		conn.WithBudget(time.Second, func() error {
+			conn.WithBudget(time.Hour, func() error {
+				if timeout := limited(); timeout > time.Second {
+					t.Errorf("Inner budget extended the outer one to %v", timeout)
+				}
+
+				return nil
+			})
+
+			conn.WithBudget(100*time.Millisecond, func() error {
+				if timeout := limited(); timeout > 100*time.Millisecond {
+					t.Errorf("Inner budget was not applied, timeout is %v", timeout)
+				}
+
+				return nil
+			})
+
+			if timeout := limited(); timeout > time.Second || timeout <= 100*time.Millisecond {
+				t.Errorf("Outer budget was not restored, timeout is %v", timeout)
+			}
+
+			return nil
+		})
+
+		if timeout := limited(); timeout != time.Minute {
+			t.Errorf("Budget was not cleared, timeout is %v", timeout)
+		}
+	})
+}
@@ -0,0 +1,106 @@
+// Licensed under the MIT license which can be found in the LICENSE file.
+
+package knx
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/LB-00/knx-go/knx/cemi"
+)
+
+var (
+	// ErrNoProgModeDevice is returned when no device is in programming mode.
+	ErrNoProgModeDevice = errors.New("no device is in programming mode")
+
+	// ErrMultipleProgModeDevices is returned when more than one device is in programming mode.
+	ErrMultipleProgModeDevices = errors.New("more than one device is in programming mode")
+)
+
+// ProgModeResult contains the devices which responded to an A_IndividualAddress_Read.
+type ProgModeResult struct {
+	addrs []cemi.IndividualAddr
+}
+
+// Count returns the number of devices in programming mode.
+func (res *ProgModeResult) Count() int {
+	return len(res.addrs)
+}
+
+// Addresses returns the individual addresses of the devices in programming mode, in the order in
+// which they responded.
+func (res *ProgModeResult) Addresses() []cemi.IndividualAddr {
+	addrs := make([]cemi.IndividualAddr, len(res.addrs))
+	copy(addrs, res.addrs)
+
+	return addrs
+}
+
+// Single returns the individual address of the only device in programming mode. It fails if there
+// is no such device or more than one, as addresses can only be assigned safely to a single device.
+func (res *ProgModeResult) Single() (cemi.IndividualAddr, error) {
+	switch len(res.addrs) {
+	case 0:
+		return 0, ErrNoProgModeDevice
+	case 1:
+		return res.addrs[0], nil
+	}
+
+	return 0, fmt.Errorf("%w: %v", ErrMultipleProgModeDevices, res.addrs)
+}
+
+// progModeBuffer is the number of responses which are buffered while FindProgrammingDevices
+// collects them.
+const progModeBuffer = 16
+
+// FindProgrammingDevices broadcasts an A_IndividualAddress_Read and collects the responses of all
+// devices in programming mode until the timeout has passed. Other messages received on the tunnel
+// are left to the consumers of its inbound channel.
+func (m *Management) FindProgrammingDevices(t time.Duration) (*ProgModeResult, error) {
+	// Watch for the responses before sending the request, so that none of them can be missed.
+	w := m.tunnel.watchBuffered(isProgModeResponse, progModeBuffer)
+	defer m.tunnel.unwatch(w)
+
+	req := cemi.NewBroadcastReq(m.tunnel.SourceAddr(), cemi.IndividualAddrRequest, nil)
+
+	err := m.tunnel.Send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ProgModeResult{}
+	seen := make(map[cemi.IndividualAddr]bool)
+	timeout := time.After(t)
+
+	for {
+		select {
+		case <-timeout:
+			return res, nil
+
+		case msg, open := <-w.ch:
+			if !open {
+				return nil, ErrTunnelClosed
+			}
+
+			src := msg.(*cemi.LDataInd).Source
+			if seen[src] {
+				continue
+			}
+
+			seen[src] = true
+			res.addrs = append(res.addrs, src)
+		}
+	}
+}
+
+// isProgModeResponse checks whether the message is an A_IndividualAddress_Response.
+func isProgModeResponse(msg cemi.Message) bool {
+	ind, ok := msg.(*cemi.LDataInd)
+	if !ok {
+		return false
+	}
+
+	app, ok := ind.Data.(*cemi.AppData)
+	return ok && app.Command == cemi.IndividualAddrResponse
+}
@@ -0,0 +1,131 @@
+// Licensed under the MIT license which can be found in the LICENSE file.
+
+package knx
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/LB-00/knx-go/knx/cemi"
+)
+
+// makeProgModeResponse creates an A_IndividualAddress_Response telegram of the device.
+func makeProgModeResponse(addr cemi.IndividualAddr) cemi.Message {
+	return &cemi.LDataInd{LData: cemi.LData{
+		Control2: cemi.Control2GroupAddr,
+		Source:   addr,
+		Data:     &cemi.AppData{Command: cemi.IndividualAddrResponse},
+	}}
+}
+
+// findProgrammingDevices runs FindProgrammingDevices and delivers the messages once the request
+// has been sent.
+func findProgrammingDevices(t *testing.T, messages ...cemi.Message) *ProgModeResult {
+	m, tunnel, sent := makeRecordingManagement(t)
+
+	type result struct {
+		res *ProgModeResult
+		err error
+	}
+
+	results := make(chan result)
+	go func() {
+		res, err := m.FindProgrammingDevices(100 * time.Millisecond)
+		results <- result{res, err}
+	}()
+
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Fatal("A_IndividualAddress_Read has not been sent")
+	}
+
+	for _, msg := range messages {
+		tunnel.pushInbound(msg)
+	}
+
+	r := <-results
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+
+	// The messages are not taken away from the consumers of the tunnel.
+	for range messages {
+		select {
+		case <-tunnel.Inbound():
+		case <-time.After(time.Second):
+			t.Fatal("Message did not arrive at the inbound channel")
+		}
+	}
+
+	return r.res
+}
+
+func TestManagement_FindProgrammingDevices(t *testing.T) {
+	first := cemi.NewIndividualAddr3(1, 1, 255)
+	second := cemi.NewIndividualAddr3(15, 15, 255)
+
+	t.Run("None", func(t *testing.T) {
+		m, _, sent := makeRecordingManagement(t)
+
+		res, err := m.FindProgrammingDevices(100 * time.Millisecond)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case req := <-sent:
+			app, ok := req.Data.(*cemi.AppData)
+			if !ok || app.Command != cemi.IndividualAddrRequest ||
+				!req.Control2.IsGroupAddr() || req.Destination != 0 {
+				t.Errorf("Unexpected request %+v", req)
+			}
+
+		case <-time.After(time.Second):
+			t.Fatal("No request was sent")
+		}
+
+		if res.Count() != 0 || len(res.Addresses()) != 0 {
+			t.Errorf("Unexpected devices %v", res.Addresses())
+		}
+
+		if _, err := res.Single(); !errors.Is(err, ErrNoProgModeDevice) {
+			t.Errorf("Unexpected error %v", err)
+		}
+	})
+
+	t.Run("One", func(t *testing.T) {
+		// Other telegrams are ignored, repeated responses are only counted once.
+		res := findProgrammingDevices(t,
+			&cemi.LDataInd{LData: cemi.LData{
+				Source: second,
+				Data:   &cemi.AppData{Command: cemi.GroupValueWrite},
+			}},
+			makeProgModeResponse(first),
+			makeProgModeResponse(first),
+		)
+
+		addr, err := res.Single()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if addr != first || res.Count() != 1 {
+			t.Errorf("Unexpected devices %v", res.Addresses())
+		}
+	})
+
+	t.Run("Multiple", func(t *testing.T) {
+		res := findProgrammingDevices(t, makeProgModeResponse(first), makeProgModeResponse(second))
+
+		addrs := res.Addresses()
+		if res.Count() != 2 || addrs[0] != first || addrs[1] != second {
+			t.Errorf("Unexpected devices %v", addrs)
+		}
+
+		if _, err := res.Single(); !errors.Is(err, ErrMultipleProgModeDevices) {
+			t.Errorf("Unexpected error %v", err)
+		}
+	})
+}
@@ -0,0 +1,284 @@
+// Licensed under the MIT license which can be found in the LICENSE file.
+
+package knx
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/LB-00/knx-go/knx/cemi"
+)
+
+// maxPropertyIndex limits the enumeration of properties on devices which never report the end.
+const maxPropertyIndex = 255
+
+// maxObjectIndex limits the enumeration of interface objects.
+const maxObjectIndex = 255
+
+// pidObjectType is the ID of the property which holds the type of an interface object.
+const pidObjectType = 1
+
+// ErrPropertyNotFound is returned when the device does not know the requested property.
+var ErrPropertyNotFound = errors.New("property not found")
+
+// ObjectType identifies the type of an interface object.
+type ObjectType uint16
+
+// These are known interface object types.
+const (
+	ObjectTypeDevice                 ObjectType = 0
+	ObjectTypeAddressTable           ObjectType = 1
+	ObjectTypeAssociationTable       ObjectType = 2
+	ObjectTypeApplicationProgram     ObjectType = 3
+	ObjectTypeInterfaceProgram       ObjectType = 4
+	ObjectTypeObjectAssociation      ObjectType = 5
+	ObjectTypeRouter                 ObjectType = 6
+	ObjectTypeLTEAddressRoutingTable ObjectType = 7
+	ObjectTypeCEMIServer             ObjectType = 8
+	ObjectTypeGroupObjectTable       ObjectType = 9
+	ObjectTypePollingMaster          ObjectType = 10
+	ObjectTypeKNXnetIPParameter      ObjectType = 11
+	ObjectTypeFileServer             ObjectType = 13
+	ObjectTypeSecurity               ObjectType = 17
+	ObjectTypeRFMedium               ObjectType = 19
+)
+
+// String returns the name of the object type.
+func (ty ObjectType) String() string {
+	switch ty {
+	case ObjectTypeDevice:
+		return "Device"
+	case ObjectTypeAddressTable:
+		return "Address Table"
+	case ObjectTypeAssociationTable:
+		return "Association Table"
+	case ObjectTypeApplicationProgram:
+		return "Application Program"
+	case ObjectTypeInterfaceProgram:
+		return "Interface Program"
+	case ObjectTypeObjectAssociation:
+		return "EIB Object Association Table"
+	case ObjectTypeRouter:
+		return "Router"
+	case ObjectTypeLTEAddressRoutingTable:
+		return "LTE Address Routing Table"
+	case ObjectTypeCEMIServer:
+		return "cEMI Server"
+	case ObjectTypeGroupObjectTable:
+		return "Group Object Table"
+	case ObjectTypePollingMaster:
+		return "Polling Master"
+	case ObjectTypeKNXnetIPParameter:
+		return "KNXnet/IP Parameter"
+	case ObjectTypeFileServer:
+		return "File Server"
+	case ObjectTypeSecurity:
+		return "Security"
+	case ObjectTypeRFMedium:
+		return "RF Medium"
+	}
+
+	return fmt.Sprintf("Unknown(%d)", uint16(ty))
+}
+
+// InterfaceObject is an interface object implemented by a device.
+type InterfaceObject struct {
+	Index uint8
+	Type  ObjectType
+}
+
+// PropertyDescription describes a property of an interface object.
+type PropertyDescription struct {
+	ObjectIndex   uint8
+	PropertyID    uint8
+	PropertyIndex uint8
+	Writable      bool
+	DataType      uint8
+	MaxElements   uint16
+	ReadLevel     uint8
+	WriteLevel    uint8
+}
+
+// unpackPropertyDescription parses the data of an A_PropertyDescription_Response.
+func unpackPropertyDescription(data []byte) (PropertyDescription, error) {
+	if len(data) != 7 {
+		return PropertyDescription{}, fmt.Errorf("expected 7 bytes of property description, got %d", len(data))
+	}
+
+	return PropertyDescription{
+		ObjectIndex:   data[0],
+		PropertyID:    data[1],
+		PropertyIndex: data[2],
+		Writable:      data[3]&0x80 != 0,
+		DataType:      data[3] & 0x3f,
+		MaxElements:   (uint16(data[4])<<8 | uint16(data[5])) & 0x0fff,
+		ReadLevel:     data[6] >> 4,
+		WriteLevel:    data[6] & 0x0f,
+	}, nil
+}
+
+// ReadPropertyDescription reads the description of a property using A_PropertyDescription_Read.
+// The property is identified by its ID or, if the ID is zero, by its index within the object. A
+// device which does not know the property responds with a property ID of zero.
+func (conn *P2PConnection) ReadPropertyDescription(
+	objIndex, propID, propIndex uint8,
+	t time.Duration,
+) (PropertyDescription, error) {
+	req := conn.newRequest(cemi.PropertyDescriptionRead, []byte{objIndex, propID, propIndex})
+
+	res, err := conn.Send(req, cemi.PropertyDescriptionResponse, t)
+	if err != nil {
+		return PropertyDescription{}, err
+	}
+
+	return unpackPropertyDescription(res.(*cemi.LDataInd).LData.Data.(*cemi.AppData).Data)
+}
+
+// EnumerateProperties reads the descriptions of all properties of an interface object. It walks the
+// property indices until the device reports a property ID of zero.
+func (conn *P2PConnection) EnumerateProperties(objIndex uint8, t time.Duration) ([]PropertyDescription, error) {
+	var props []PropertyDescription
+
+	for i := 0; i < maxPropertyIndex; i++ {
+		desc, err := conn.ReadPropertyDescription(objIndex, 0, uint8(i), t)
+		if err != nil {
+			return nil, err
+		}
+
+		if desc.PropertyID == 0 {
+			break
+		}
+
+		props = append(props, desc)
+	}
+
+	return props, nil
+}
+
+// DefaultPropertyReadTimeout is how long ReadPropertyDefault waits for each response of the device.
+const DefaultPropertyReadTimeout = 3 * time.Second
+
+// ReadPropertyDefault works like ReadProperty, but waits up to DefaultPropertyReadTimeout for each
+// response.
+func (conn *P2PConnection) ReadPropertyDefault(objIndex, propID uint8, start uint16, count uint8) ([]byte, error) {
+	return conn.ReadProperty(objIndex, propID, start, count, DefaultPropertyReadTimeout)
+}
+
+// ReadProperty reads count elements of a property starting at the given element index using
+// A_PropertyValue_Read. Element index 0 holds the current number of elements. If the device
+// responds with fewer elements than requested, the remaining elements are requested in turn.
+func (conn *P2PConnection) ReadProperty(
+	objIndex, propID uint8,
+	start uint16,
+	count uint8,
+	t time.Duration,
+) ([]byte, error) {
+	if count == 0 || count > 15 || start > 0x0fff || uint(start)+uint(count) > 0x1000 {
+		return nil, errors.New("invalid element range")
+	}
+
+	var result []byte
+
+	for count > 0 {
+		data, n, err := conn.readPropertyChunk(objIndex, propID, start, count, t)
+		if err != nil {
+			return nil, err
+		}
+
+		// The response may be partial, in which case the next request continues after it.
+		result = append(result, data...)
+		start += uint16(n)
+		count -= n
+	}
+
+	return result, nil
+}
+
+// readPropertyChunk reads count elements of a property with a single A_PropertyValue_Read. It
+// returns the data and the number of elements, which may be fewer than requested.
+func (conn *P2PConnection) readPropertyChunk(
+	objIndex, propID uint8,
+	start uint16,
+	count uint8,
+	t time.Duration,
+) ([]byte, uint8, error) {
+	header := []byte{objIndex, propID, count<<4 | byte(start>>8), byte(start)}
+
+	res, err := conn.Send(conn.newRequest(cemi.PropertyValueRead, header), cemi.PropertyValueResponse, t)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	data := res.(*cemi.LDataInd).LData.Data.(*cemi.AppData).Data
+	if len(data) < len(header) ||
+		data[0] != objIndex || data[1] != propID || data[3] != header[3] || data[2]&0x0f != header[2]&0x0f {
+		return nil, 0, errors.New("property response does not match the request")
+	}
+
+	// A device signals an error by responding with zero elements.
+	n := data[2] >> 4
+	if n == 0 {
+		return nil, 0, ErrPropertyNotFound
+	}
+
+	if n > count {
+		return nil, 0, fmt.Errorf("expected at most %d elements, got %d", count, n)
+	}
+
+	return data[len(header):], n, nil
+}
+
+// EnumerateObjects reads the type of successive interface objects until the device does not know
+// the object, which yields all interface objects the device implements.
+func (conn *P2PConnection) EnumerateObjects(t time.Duration) ([]InterfaceObject, error) {
+	var objects []InterfaceObject
+
+	for i := 0; i < maxObjectIndex; i++ {
+		data, err := conn.ReadProperty(uint8(i), pidObjectType, 1, 1, t)
+		if errors.Is(err, ErrPropertyNotFound) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		if len(data) != 2 {
+			return nil, fmt.Errorf("expected 2 bytes of object type, got %d", len(data))
+		}
+
+		objects = append(objects, InterfaceObject{
+			Index: uint8(i),
+			Type:  ObjectType(uint16(data[0])<<8 | uint16(data[1])),
+		})
+	}
+
+	return objects, nil
+}
+
+// Properties of the device object which hold the individual address.
+const (
+	objIndexDevice = 0
+	pidSubnetAddr  = 57
+	pidDeviceAddr  = 58
+)
+
+// ReadIndividualAddress reads the individual address of the device from the PID_SUBNET_ADDR and
+// PID_DEVICE_ADDR properties of its device object. Unlike A_IndividualAddress_Read, this works over
+// the established connection, which allows confirming the address the device actually uses.
+func (conn *P2PConnection) ReadIndividualAddress(t time.Duration) (cemi.IndividualAddr, error) {
+	subnet, err := conn.ReadProperty(objIndexDevice, pidSubnetAddr, 1, 1, t)
+	if err != nil {
+		return 0, fmt.Errorf("unable to read subnet address: %w", err)
+	}
+
+	device, err := conn.ReadProperty(objIndexDevice, pidDeviceAddr, 1, 1, t)
+	if err != nil {
+		return 0, fmt.Errorf("unable to read device address: %w", err)
+	}
+
+	if len(subnet) != 1 || len(device) != 1 {
+		return 0, fmt.Errorf("expected 1 byte of subnet and device address, got %d and %d", len(subnet), len(device))
+	}
+
+	return cemi.NewIndividualAddr2(subnet[0], device[0]), nil
+}
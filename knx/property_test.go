@@ -0,0 +1,182 @@
+// Licensed under the MIT license which can be found in the LICENSE file.
+
+package knx
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/LB-00/knx-go/knx/cemi"
+)
+
+// queueResponses makes the fake device acknowledge each request and answer it with the given
+// application data. The connection buffers only a few messages, so keep the number of responses
+// small.
+func queueResponses(tunnel *Tunnel, addr cemi.IndividualAddr, cmd cemi.APCI, responses ...[]byte) {
+	for i, data := range responses {
+		tunnel.inbound <- &cemi.LDataInd{LData: cemi.LData{Source: addr, Data: cemi.TAck(uint8(i) % 16)}}
+		tunnel.inbound <- &cemi.LDataInd{LData: cemi.LData{
+			Source: addr,
+			Data: &cemi.AppData{
+				Numbered:  true,
+				SeqNumber: uint8(i) % 16,
+				Command:   cmd,
+				Data:      data,
+			},
+		}}
+	}
+}
+
+func TestP2PConnection_EnumerateProperties(t *testing.T) {
+	addr := cemi.NewIndividualAddr3(1, 1, 5)
+
+	conn, tunnel, _ := connectRecording(t, addr)
+
+	// The device object exposes PID_OBJECT_TYPE, PID_SERIAL_NUMBER and PID_MANUFACTURER_ID.
+	queueResponses(tunnel, addr, cemi.PropertyDescriptionResponse,
+		[]byte{0, 1, 0, 0x04, 0x00, 0x01, 0x30},
+		[]byte{0, 11, 1, 0x11, 0x00, 0x01, 0x30},
+		[]byte{0, 12, 2, 0x84, 0x00, 0x01, 0x32},
+		[]byte{0, 0, 3, 0x00, 0x00, 0x00, 0x00},
+	)
+
+	props, err := conn.EnumerateProperties(0, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []PropertyDescription{
+		{PropertyID: 1, PropertyIndex: 0, DataType: 0x04, MaxElements: 1, ReadLevel: 3},
+		{PropertyID: 11, PropertyIndex: 1, DataType: 0x11, MaxElements: 1, ReadLevel: 3},
+		{PropertyID: 12, PropertyIndex: 2, Writable: true, DataType: 0x04, MaxElements: 1, ReadLevel: 3, WriteLevel: 2},
+	}
+
+	if len(props) != len(expected) {
+		t.Fatalf("Unexpected number of properties: %d", len(props))
+	}
+
+	for i := range expected {
+		if props[i] != expected[i] {
+			t.Errorf("Unexpected property description: %+v", props[i])
+		}
+	}
+}
+
+func TestP2PConnection_ReadPropertyPartial(t *testing.T) {
+	addr := cemi.NewIndividualAddr3(1, 1, 5)
+
+	conn, tunnel, sent := connectRecording(t, addr)
+
+	// The device responds with at most 2 elements of 2 bytes each at a time.
+	var requests [][]byte
+	serveFakeDevice(t, tunnel, sent, addr, func(req *cemi.AppData) *cemi.AppData {
+		requests = append(requests, append([]byte(nil), req.Data...))
+
+		start := uint16(req.Data[2]&0x0f)<<8 | uint16(req.Data[3])
+		count := req.Data[2] >> 4
+		if count > 2 {
+			count = 2
+		}
+
+		data := []byte{req.Data[0], req.Data[1], count<<4 | req.Data[2]&0x0f, req.Data[3]}
+		for i := uint16(0); i < uint16(count); i++ {
+			data = append(data, 0, byte(start+i))
+		}
+
+		return &cemi.AppData{Command: cemi.PropertyValueResponse, Data: data}
+	})
+
+	data, err := conn.ReadProperty(1, 23, 1, 5, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(data, []byte{0, 1, 0, 2, 0, 3, 0, 4, 0, 5}) {
+		t.Errorf("Unexpected data: %v", data)
+	}
+
+	expected := [][]byte{{1, 23, 0x50, 1}, {1, 23, 0x30, 3}, {1, 23, 0x10, 5}}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Errorf("Unexpected requests: %x", requests)
+	}
+}
+
+func TestP2PConnection_EnumerateObjects(t *testing.T) {
+	addr := cemi.NewIndividualAddr3(1, 1, 5)
+
+	conn, tunnel, _ := connectRecording(t, addr)
+
+	// The device implements the device object, an address table and an association table.
+	queueResponses(tunnel, addr, cemi.PropertyValueResponse,
+		[]byte{0, pidObjectType, 0x10, 0x01, 0x00, 0x00},
+		[]byte{1, pidObjectType, 0x10, 0x01, 0x00, 0x01},
+		[]byte{2, pidObjectType, 0x10, 0x01, 0x00, 0x02},
+		[]byte{3, pidObjectType, 0x00, 0x01},
+	)
+
+	objects, err := conn.EnumerateObjects(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []InterfaceObject{
+		{Index: 0, Type: ObjectTypeDevice},
+		{Index: 1, Type: ObjectTypeAddressTable},
+		{Index: 2, Type: ObjectTypeAssociationTable},
+	}
+
+	if len(objects) != len(expected) {
+		t.Fatalf("Unexpected number of objects: %d", len(objects))
+	}
+
+	for i := range expected {
+		if objects[i] != expected[i] {
+			t.Errorf("Unexpected object: %+v", objects[i])
+		}
+	}
+
+	if name := objects[1].Type.String(); name != "Address Table" {
+		t.Errorf("Unexpected object type name: %s", name)
+	}
+
+	if name := ObjectType(0x1234).String(); name != "Unknown(4660)" {
+		t.Errorf("Unexpected object type name: %s", name)
+	}
+}
+
+func TestP2PConnection_ReadIndividualAddress(t *testing.T) {
+	addr := cemi.NewIndividualAddr3(1, 1, 5)
+
+	t.Run("Supported", func(t *testing.T) {
+		conn, tunnel, sent := connectRecording(t, addr)
+
+		props := map[[2]uint8][]byte{
+			{objIndexDevice, pidSubnetAddr}: {0x11},
+			{objIndexDevice, pidDeviceAddr}: {0x05},
+		}
+
+		serveFakeDevice(t, tunnel, sent, addr, makeMemoryDevice(0x07B0, nil, props))
+
+		read, err := conn.ReadIndividualAddress(time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if read != addr {
+			t.Errorf("Read %v, expected %v", read, addr)
+		}
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		conn, tunnel, sent := connectRecording(t, addr)
+
+		serveFakeDevice(t, tunnel, sent, addr, makeMemoryDevice(0x07B0, nil, nil))
+
+		if _, err := conn.ReadIndividualAddress(time.Second); !errors.Is(err, ErrPropertyNotFound) {
+			t.Errorf("Unexpected error %v", err)
+		}
+	})
+}
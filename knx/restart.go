@@ -0,0 +1,102 @@
+// Licensed under the MIT license which can be found in the LICENSE file.
+
+package knx
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/LB-00/knx-go/knx/cemi"
+)
+
+// EraseCode selects what a master reset erases.
+type EraseCode uint8
+
+// These are the erase codes of a master reset.
+const (
+	EraseConfirmedRestart         EraseCode = 0x01
+	EraseFactoryReset             EraseCode = 0x02
+	EraseIndividualAddr           EraseCode = 0x03
+	EraseApplicationProgram       EraseCode = 0x04
+	EraseApplicationParameters    EraseCode = 0x05
+	EraseLinks                    EraseCode = 0x06
+	EraseFactoryResetWithoutAddrs EraseCode = 0x07
+)
+
+// RestartError is the error code of a restart response. The device refuses the master reset for
+// any code other than RestartNoError.
+type RestartError uint8
+
+// These are the error codes of a restart response.
+const (
+	RestartNoError              RestartError = 0x00
+	RestartAccessDenied         RestartError = 0x01
+	RestartUnsupportedEraseCode RestartError = 0x02
+	RestartInvalidChannel       RestartError = 0x03
+)
+
+// Error returns a description of the error code.
+func (code RestartError) Error() string {
+	switch code {
+	case RestartNoError:
+		return "restart succeeded"
+	case RestartAccessDenied:
+		return "restart refused: access denied"
+	case RestartUnsupportedEraseCode:
+		return "restart refused: unsupported erase code"
+	case RestartInvalidChannel:
+		return "restart refused: invalid channel number"
+	}
+
+	return fmt.Sprintf("restart refused with error code %#02x", uint8(code))
+}
+
+// restartMasterReset is the restart type of a master reset, restartResponse flags the response.
+const (
+	restartMasterReset = 0x01
+	restartResponse    = 0x20
+)
+
+// DefaultRestartTimeout is how long RestartDefault waits for the response of the device. Devices
+// may take a while to confirm a master reset, hence it exceeds the other default timeouts.
+const DefaultRestartTimeout = 10 * time.Second
+
+// RestartDefault works like Restart, but waits up to DefaultRestartTimeout for the response.
+func (conn *P2PConnection) RestartDefault(code EraseCode, channel uint8) (time.Duration, error) {
+	return conn.Restart(code, channel, DefaultRestartTimeout)
+}
+
+// Restart performs a master reset of the device using A_Restart, which erases what the erase code
+// selects. The channel number is only relevant to the erase codes which refer to a channel and
+// zero otherwise. On success, it returns how long the device expects the restart to take. A
+// refusal of the device is returned as RestartError. In dry run mode, the request is only recorded
+// and the process time is zero.
+func (conn *P2PConnection) Restart(code EraseCode, channel uint8, t time.Duration) (time.Duration, error) {
+	req := conn.newRequest(cemi.Restart, []byte{restartMasterReset, byte(code), channel})
+
+	if conn.DryRun() {
+		_, err := conn.sendWrite(req, t)
+		return 0, err
+	}
+
+	res, err := conn.Send(req, cemi.Restart, t)
+	if err != nil {
+		return 0, err
+	}
+
+	return unpackRestartResponse(res.(*cemi.LDataInd).LData.Data.(*cemi.AppData).Data)
+}
+
+// unpackRestartResponse parses the data of an A_Restart_Response, which carries the error code and
+// the process time in seconds.
+func unpackRestartResponse(data []byte) (time.Duration, error) {
+	if len(data) != 4 || data[0]&0x3f != restartResponse|restartMasterReset {
+		return 0, fmt.Errorf("unexpected restart response %x", data)
+	}
+
+	if code := RestartError(data[1]); code != RestartNoError {
+		return 0, code
+	}
+
+	return time.Duration(uint16(data[2])<<8|uint16(data[3])) * time.Second, nil
+}
@@ -0,0 +1,63 @@
+// Licensed under the MIT license which can be found in the LICENSE file.
+
+package knx
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/LB-00/knx-go/knx/cemi"
+)
+
+func TestP2PConnection_Restart(t *testing.T) {
+	addr := cemi.NewIndividualAddr3(1, 1, 5)
+
+	// The device accepts the master reset and takes 5 seconds.
+	t.Run("Success", func(t *testing.T) {
+		conn, tunnel, sent := connectRecording(t, addr)
+
+		queueResponses(tunnel, addr, cemi.Restart, []byte{0x21, 0x00, 0x00, 0x05})
+
+		processTime, err := conn.Restart(EraseFactoryReset, 0, time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if processTime != 5*time.Second {
+			t.Errorf("Unexpected process time: %v", processTime)
+		}
+
+		data := expectRequest(t, sent, cemi.Restart)
+		if !bytes.Equal(data, []byte{0x01, 0x02, 0x00}) {
+			t.Errorf("Unexpected data: %v", data)
+		}
+	})
+
+	// The device refuses the master reset.
+	for _, code := range []RestartError{RestartAccessDenied, RestartUnsupportedEraseCode} {
+		t.Run(code.Error(), func(t *testing.T) {
+			conn, tunnel, _ := connectRecording(t, addr)
+
+			queueResponses(tunnel, addr, cemi.Restart, []byte{0x21, byte(code), 0x00, 0x00})
+
+			_, err := conn.Restart(EraseLinks, 0, time.Second)
+
+			var restartErr RestartError
+			if !errors.As(err, &restartErr) || restartErr != code {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestRestartError_Error(t *testing.T) {
+	if msg := RestartAccessDenied.Error(); msg != "restart refused: access denied" {
+		t.Errorf("Unexpected message: %s", msg)
+	}
+
+	if msg := RestartError(0x42).Error(); msg != "restart refused with error code 0x42" {
+		t.Errorf("Unexpected message: %s", msg)
+	}
+}
@@ -31,6 +31,17 @@ type TunnelConfig struct {
 
 	// UseTCP configures whether to connect to the gateway using TCP.
 	UseTCP bool
+
+	// InboundBufferSize specifies the capacity of a ring buffer that backs the inbound channel. If
+	// it is zero, incoming messages are never dropped, but every message that cannot be delivered
+	// immediately occupies a goroutine. Otherwise the oldest buffered message is dropped once the
+	// buffer is full. Use DroppedCount to learn about dropped messages.
+	InboundBufferSize uint
+
+	// Preflight requests a description of the gateway before connecting. Connecting fails early if
+	// the gateway does not offer tunnelling, or only a version which does not support TCP if UseTCP
+	// is set.
+	Preflight bool
 }
 
 // DefaultTunnelConfig is a good default configuration for a Tunnel client.
@@ -63,6 +74,46 @@ var (
 	errResponseTimeout = errors.New("response timeout reached")
 )
 
+// checkTunnellingSupport verifies that the described device offers the tunnelling service family.
+// Tunnelling over TCP requires version 2 of the family.
+func checkTunnellingSupport(di *knxnet.DescriptionBlock, useTCP bool) error {
+	var minVersion uint8 = 1
+	if useTCP {
+		minVersion = 2
+	}
+
+	for _, family := range di.SupportedServices.Families {
+		if family.Type != knxnet.ServiceFamilyTypeIPTunnelling {
+			continue
+		}
+
+		if family.Version < minVersion {
+			return fmt.Errorf(
+				"gateway supports tunnelling version %d, but version %d is required",
+				family.Version, minVersion,
+			)
+		}
+
+		return nil
+	}
+
+	return errors.New("gateway does not support tunnelling")
+}
+
+// preflightTunnel requests a description of the gateway and checks that it supports tunnelling.
+func preflightTunnel(gatewayAddr string, config TunnelConfig) error {
+	res, err := DescribeTunnel(gatewayAddr, config.ResponseTimeout)
+	if err != nil {
+		return err
+	}
+
+	if res == nil {
+		return errors.New("gateway did not respond to the description request")
+	}
+
+	return checkTunnellingSupport((*knxnet.DescriptionBlock)(res), config.UseTCP)
+}
+
 // A Tunnel provides methods to communicate with a KNXnet/IP gateway.
 type Tunnel struct {
 	// Communication methods
@@ -82,8 +133,19 @@ type Tunnel struct {
 	seqNumber uint8
 	ack       chan *knxnet.TunnelRes
 
+	// Sends in progress, rejected once the tunnel is shutting down
+	sendMu   sync.Mutex
+	closing  bool
+	inflight sync.WaitGroup
+
 	// Incoming requests
 	inbound chan cemi.Message
+	ring    *inboundRing
+
+	// Filtered observers of incoming requests
+	watchMu sync.Mutex
+	watches map[*tunnelWatch]struct{}
+	stopped bool
 
 	// Goroutine controller
 	done chan struct{}
@@ -361,6 +423,14 @@ func (conn *Tunnel) handleDiscRes(res *knxnet.DiscRes) error {
 // pushInbound sends the message through the inbound channel. If the sending blocks, it will launch
 // a goroutine which will do the sending.
 func (conn *Tunnel) pushInbound(msg cemi.Message) {
+	conn.notifyWatches(msg)
+
+	// The ring buffer takes care of the sending, if there is one.
+	if conn.ring != nil {
+		conn.ring.push(msg)
+		return
+	}
+
 	select {
 	case conn.inbound <- msg:
 
@@ -375,6 +445,77 @@ func (conn *Tunnel) pushInbound(msg cemi.Message) {
 	}
 }
 
+// A tunnelWatch observes the incoming messages of a tunnel which match a filter. Unlike reading
+// from the inbound channel, watching does not take the messages away from its consumers.
+type tunnelWatch struct {
+	match func(cemi.Message) bool
+	ch    chan cemi.Message
+}
+
+// watch registers a watch for incoming messages which match the filter. Only the first match is
+// buffered until it is received; further matches are skipped in the meantime. The channel of the
+// watch is closed once the tunnel has stopped. A watch must be released with unwatch.
+func (conn *Tunnel) watch(match func(cemi.Message) bool) *tunnelWatch {
+	return conn.watchBuffered(match, 1)
+}
+
+// watchBuffered works like watch, but buffers up to size matches. This suits callers which
+// collect several responses to a single request.
+func (conn *Tunnel) watchBuffered(match func(cemi.Message) bool, size int) *tunnelWatch {
+	w := &tunnelWatch{match: match, ch: make(chan cemi.Message, size)}
+
+	conn.watchMu.Lock()
+	defer conn.watchMu.Unlock()
+
+	if conn.stopped {
+		close(w.ch)
+		return w
+	}
+
+	if conn.watches == nil {
+		conn.watches = make(map[*tunnelWatch]struct{})
+	}
+	conn.watches[w] = struct{}{}
+
+	return w
+}
+
+// unwatch releases the watch.
+func (conn *Tunnel) unwatch(w *tunnelWatch) {
+	conn.watchMu.Lock()
+	delete(conn.watches, w)
+	conn.watchMu.Unlock()
+}
+
+// notifyWatches offers the message to the watches whose filter matches it.
+func (conn *Tunnel) notifyWatches(msg cemi.Message) {
+	conn.watchMu.Lock()
+	defer conn.watchMu.Unlock()
+
+	for w := range conn.watches {
+		if !w.match(msg) {
+			continue
+		}
+
+		select {
+		case w.ch <- msg:
+		default:
+		}
+	}
+}
+
+// stopWatches closes the channels of all watches, as no more messages will be received.
+func (conn *Tunnel) stopWatches() {
+	conn.watchMu.Lock()
+	defer conn.watchMu.Unlock()
+
+	conn.stopped = true
+	for w := range conn.watches {
+		close(w.ch)
+	}
+	conn.watches = nil
+}
+
 // handleTunnelReq validates the request, pushes the data to the client and acknowledges the
 // request for the gateway.
 func (conn *Tunnel) handleTunnelReq(req *knxnet.TunnelReq, seqNumber *uint8) error {
@@ -554,6 +695,24 @@ func (conn *Tunnel) serve() {
 	defer close(conn.ack)
 	defer close(conn.inbound)
 	defer conn.wait.Done()
+	defer conn.stopWatches()
+
+	// Relay messages from the ring buffer. The relay must have stopped before the inbound channel
+	// is closed.
+	if conn.ring != nil {
+		stop := make(chan struct{})
+		relayDone := make(chan struct{})
+
+		go func() {
+			defer close(relayDone)
+			conn.ring.serve(conn.inbound, stop)
+		}()
+
+		defer func() {
+			close(stop)
+			<-relayDone
+		}()
+	}
 
 	for {
 		err := conn.process()
@@ -589,6 +748,13 @@ func NewTunnel(
 ) (tunnel *Tunnel, err error) {
 	var sock knxnet.Socket
 
+	if config.Preflight {
+		err = preflightTunnel(gatewayAddr, checkTunnelConfig(config))
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Create socket which will be used for communication.
 	if config.UseTCP {
 		sock, err = knxnet.DialTunnelTCP(gatewayAddr)
@@ -600,27 +766,7 @@ func NewTunnel(
 		return nil, err
 	}
 
-	// Initialize the Client structure.
-	client := &Tunnel{
-		sock:    sock,
-		config:  checkTunnelConfig(config),
-		layer:   layer,
-		ack:     make(chan *knxnet.TunnelRes),
-		inbound: make(chan cemi.Message),
-		done:    make(chan struct{}),
-	}
-
-	// Connect to the gateway.
-	err = client.requestConn()
-	if err != nil {
-		sock.Close()
-		return nil, err
-	}
-
-	client.wait.Add(1)
-	go client.serve()
-
-	return client, nil
+	return newTunnel(sock, layer, config)
 }
 
 // NewTunnelFromConn establishes a tunnel connection using an existing net.Conn.
@@ -635,6 +781,34 @@ func NewTunnelFromConn(
 		return nil, fmt.Errorf("failed to create tunnel socket: %w", err)
 	}
 
+	return newTunnel(sock, layer, config)
+}
+
+// NewTunnelFromPacketConn establishes a tunnel connection to the gateway at the given address
+// "ip:port" over an existing net.PacketConn. This allows binding to a specific local address or
+// port, e.g. one which a firewall expects, or setting socket options. The tunnel takes ownership
+// of the connection and closes it when it is closed itself.
+func NewTunnelFromPacketConn(
+	conn net.PacketConn,
+	gatewayAddr string,
+	layer knxnet.TunnelLayer,
+	config TunnelConfig,
+) (tunnel *Tunnel, err error) {
+	addr, err := net.ResolveUDPAddr("udp4", gatewayAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	sock, err := knxnet.NewTunnelSocketFromPacketConn(conn, addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tunnel socket: %w", err)
+	}
+
+	return newTunnel(sock, layer, config)
+}
+
+// newTunnel connects to the gateway over the socket, which is closed if connecting fails.
+func newTunnel(sock knxnet.Socket, layer knxnet.TunnelLayer, config TunnelConfig) (*Tunnel, error) {
 	// Initialize the Client structure.
 	client := &Tunnel{
 		sock:    sock,
@@ -645,9 +819,12 @@ func NewTunnelFromConn(
 		done:    make(chan struct{}),
 	}
 
+	if client.config.InboundBufferSize > 0 {
+		client.ring = newInboundRing(client.config.InboundBufferSize)
+	}
+
 	// Connect to the gateway.
-	err = client.requestConn()
-	if err != nil {
+	if err := client.requestConn(); err != nil {
 		sock.Close()
 		return nil, err
 	}
@@ -671,17 +848,102 @@ func (conn *Tunnel) Close() {
 	})
 }
 
+// Shutdown closes the tunnel gracefully. Further sends fail with ErrShuttingDown, while the sends in
+// progress may complete until the timeout expires. Afterwards the tunnel is closed like with Close.
+// Shutdown reports whether all sends have completed in time.
+func (conn *Tunnel) Shutdown(timeout time.Duration) bool {
+	conn.sendMu.Lock()
+	conn.closing = true
+	conn.sendMu.Unlock()
+
+	drained := waitTimeout(&conn.inflight, timeout)
+	conn.Close()
+
+	return drained
+}
+
+// beginSend registers a send in progress, unless the tunnel is shutting down.
+func (conn *Tunnel) beginSend() error {
+	conn.sendMu.Lock()
+	defer conn.sendMu.Unlock()
+
+	if conn.closing {
+		return ErrShuttingDown
+	}
+
+	conn.inflight.Add(1)
+	return nil
+}
+
+// waitTimeout waits for the wait group up to timeout and reports whether it has completed.
+func waitTimeout(wg *sync.WaitGroup, timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
 // Inbound retrieves the channel which transmits incoming data. The channel is closed when the
 // underlying Socket closes its inbound channel or when the connection is terminated.
 func (conn *Tunnel) Inbound() <-chan cemi.Message {
 	return conn.inbound
 }
 
+// DroppedCount returns the number of incoming messages that have been dropped, because the
+// consumer of the inbound channel fell behind. Messages can only be dropped if the tunnel has been
+// configured with an InboundBufferSize.
+func (conn *Tunnel) DroppedCount() uint64 {
+	if conn.ring == nil {
+		return 0
+	}
+
+	return conn.ring.droppedCount()
+}
+
 // Send relays a tunnel request to the gateway with the given contents.
 func (conn *Tunnel) Send(data cemi.Message) error {
+	if err := conn.beginSend(); err != nil {
+		return err
+	}
+	defer conn.inflight.Done()
+
 	return conn.requestTunnel(data)
 }
 
+// SendRaw transmits an already encoded KNXnet/IP datagram to the gateway, e.g. to experiment with
+// services this package does not support or to replay captured frames. The datagram bypasses the
+// service encoding and is neither validated nor acknowledged, and the tunnel's sequence number is
+// not advanced. Datagrams exceeding knxnet.MaxFrameSize are rejected. It is sent while no tunnel
+// request is outstanding. The underlying socket must implement knxnet.RawSocket.
+func (conn *Tunnel) SendRaw(data []byte) error {
+	sock, ok := conn.sock.(knxnet.RawSocket)
+	if !ok {
+		return fmt.Errorf("socket %T cannot send raw datagrams", conn.sock)
+	}
+
+	if err := knxnet.CheckFrameSize(uint(len(data))); err != nil {
+		return err
+	}
+
+	if err := conn.beginSend(); err != nil {
+		return err
+	}
+	defer conn.inflight.Done()
+
+	conn.seqMu.Lock()
+	defer conn.seqMu.Unlock()
+
+	return sock.SendRaw(data)
+}
+
 // SourceAddr returns the individual address assigned to the tunnel connection.
 func (conn *Tunnel) SourceAddr() cemi.IndividualAddr {
 	return conn.addr
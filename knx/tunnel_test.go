@@ -4,6 +4,9 @@
 package knx
 
 import (
+	"bytes"
+	"errors"
+	"net"
 	"testing"
 	"time"
 
@@ -984,3 +987,174 @@ func TestTunnelConn_handleTunnelRes(t *testing.T) {
 		})
 	})
 }
+
+func TestCheckTunnellingSupport(t *testing.T) {
+	makeDescription := func(families ...knxnet.ServiceFamily) *knxnet.DescriptionBlock {
+		di := &knxnet.DescriptionBlock{}
+		di.SupportedServices.Families = families
+
+		return di
+	}
+
+	core := knxnet.ServiceFamily{Type: knxnet.ServiceFamilyTypeIPCore, Version: 2}
+	routing := knxnet.ServiceFamily{Type: knxnet.ServiceFamilyTypeIPRouting, Version: 2}
+
+	t.Run("RoutingOnly", func(t *testing.T) {
+		if err := checkTunnellingSupport(makeDescription(core, routing), false); err == nil {
+			t.Fatal("Should not succeed")
+		}
+	})
+
+	t.Run("Tunnelling", func(t *testing.T) {
+		tunnelling := knxnet.ServiceFamily{Type: knxnet.ServiceFamilyTypeIPTunnelling, Version: 1}
+
+		if err := checkTunnellingSupport(makeDescription(core, tunnelling), false); err != nil {
+			t.Fatal(err)
+		}
+
+		// Tunnelling over TCP requires version 2.
+		if err := checkTunnellingSupport(makeDescription(core, tunnelling), true); err == nil {
+			t.Fatal("Should not succeed")
+		}
+	})
+}
+
+func TestNewTunnel_Preflight(t *testing.T) {
+	gateway, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gateway.Close()
+
+	// The gateway is a router which does not offer tunnelling.
+	description := &knxnet.DescriptionRes{}
+	description.DeviceHardware.Type = knxnet.DescriptionTypeDeviceInfo
+	description.DeviceHardware.HardwareAddr = make([]byte, 6)
+	description.SupportedServices.Type = knxnet.DescriptionTypeSupportedServiceFamilies
+	description.SupportedServices.Families = []knxnet.ServiceFamily{
+		{Type: knxnet.ServiceFamilyTypeIPCore, Version: 2},
+		{Type: knxnet.ServiceFamilyTypeIPRouting, Version: 2},
+	}
+
+	go serveFakeGateway(gateway, description)
+
+	config := DefaultTunnelConfig
+	config.ResponseTimeout = time.Second
+	config.Preflight = true
+
+	tunnel, err := NewTunnel(gateway.LocalAddr().String(), knxnet.TunnelLayerData, config)
+	if err == nil {
+		tunnel.Close()
+		t.Fatal("Should not succeed")
+	}
+
+	if err.Error() != "gateway does not support tunnelling" {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+// rawSocket is a dummySocket which records the raw datagrams sent through it.
+type rawSocket struct {
+	*dummySocket
+	raw [][]byte
+}
+
+func (sock *rawSocket) SendRaw(data []byte) error {
+	sock.raw = append(sock.raw, append([]byte(nil), data...))
+	return nil
+}
+
+func TestTunnel_SendRaw(t *testing.T) {
+	// The datagram reaches the socket unchanged.
+	t.Run("Ok", func(t *testing.T) {
+		client, gateway := newDummySockets()
+		defer client.Close()
+		defer gateway.Close()
+
+		sock := &rawSocket{dummySocket: client}
+		tunnel := makeTunnelConn(sock, DefaultTunnelConfig, 1)
+
+		// A connection state request with a bogus total length.
+		data := []byte{0x06, 0x10, 0x02, 0x07, 0x00, 0xff, 0x01, 0x00}
+
+		if err := tunnel.SendRaw(data); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(sock.raw) != 1 || !bytes.Equal(sock.raw[0], data) {
+			t.Fatalf("Unexpected datagrams: %v", sock.raw)
+		}
+	})
+
+	// Datagrams exceeding the maximum frame size never reach the socket.
+	t.Run("TooLarge", func(t *testing.T) {
+		client, gateway := newDummySockets()
+		defer client.Close()
+		defer gateway.Close()
+
+		sock := &rawSocket{dummySocket: client}
+		tunnel := makeTunnelConn(sock, DefaultTunnelConfig, 1)
+
+		data := make([]byte, knxnet.DefaultMaxFrameSize+1)
+		if err := tunnel.SendRaw(data); !errors.Is(err, knxnet.ErrFrameTooLarge) {
+			t.Fatalf("Unexpected error %v", err)
+		}
+
+		if len(sock.raw) != 0 {
+			t.Fatalf("Unexpected datagrams: %v", sock.raw)
+		}
+	})
+
+	// Sockets which cannot send raw datagrams are rejected.
+	t.Run("Unsupported", func(t *testing.T) {
+		client, gateway := newDummySockets()
+		defer client.Close()
+		defer gateway.Close()
+
+		tunnel := makeTunnelConn(client, DefaultTunnelConfig, 1)
+
+		if err := tunnel.SendRaw([]byte{0x06, 0x10}); err == nil {
+			t.Fatal("Should not succeed")
+		}
+	})
+}
+
+func TestTunnel_watch(t *testing.T) {
+	tunnel := makeTunnelConn(nil, DefaultTunnelConfig, 1)
+
+	w := tunnel.watch(func(msg cemi.Message) bool {
+		_, ok := msg.(*cemi.LDataCon)
+		return ok
+	})
+
+	ind := &cemi.LDataInd{}
+	con := &cemi.LDataCon{}
+
+	tunnel.pushInbound(ind)
+	tunnel.pushInbound(con)
+
+	// The watch only receives matching messages.
+	if msg := <-w.ch; msg != con {
+		t.Errorf("Unexpected message %v", msg)
+	}
+
+	// All messages still arrive at the inbound channel.
+	for _, expected := range []cemi.Message{ind, con} {
+		if msg := <-tunnel.Inbound(); msg != expected {
+			t.Errorf("Unexpected message %v", msg)
+		}
+	}
+
+	// The watch ends with the tunnel.
+	tunnel.stopWatches()
+
+	if _, open := <-w.ch; open {
+		t.Error("Channel should be closed")
+	}
+
+	if _, open := <-tunnel.watch(nil).ch; open {
+		t.Error("Watches of a stopped tunnel should be closed")
+	}
+
+	tunnel.unwatch(w)
+}
@@ -4,6 +4,7 @@
 package util
 
 import (
+	"errors"
 	"fmt"
 
 	"golang.org/x/text/encoding/charmap"
@@ -117,22 +118,38 @@ func AllocAndPack(inputs ...Packable) []byte {
 	return buffer
 }
 
-// PackString packs a string into the buffer
-func PackString(buffer []byte, maxLen uint, input string) (uint, error) {
-	encoded, err := stringEncoder.Bytes([]byte(input))
-	if err != nil {
-		return 0, fmt.Errorf("unable to encode string: %s", err)
-	}
+// ErrStringTooLong is returned by PackString if the encoded string exceeds the maximum length.
+var ErrStringTooLong = errors.New("string exceeds the maximum length")
 
-	if len(encoded) >= int(maxLen) {
-		encoded = encoded[:maxLen]
-		encoded[maxLen] = 0x00
+// PackString packs a string into the buffer. Exactly maxLen bytes are written: the space after the
+// encoded string is explicitly padded with zeros, hence the buffer does not have to be zeroed
+// beforehand and may be reused. A string which does not fit is truncated between two characters
+// and ErrStringTooLong is returned. Characters which cannot be encoded are replaced by '?' and
+// reported as error as well. In both cases, the buffer contains the packed string nonetheless.
+func PackString(buffer []byte, maxLen uint, input string) (uint, error) {
+	var err error
+
+	n := 0
+	for _, r := range input {
+		encoded, encErr := stringEncoder.Bytes([]byte(string(r)))
+		if encErr != nil {
+			if err == nil {
+				err = fmt.Errorf("unable to encode %q: %s", r, encErr)
+			}
+			encoded = []byte{'?'}
+		}
+
+		if n+len(encoded) > int(maxLen) {
+			err = fmt.Errorf("%w: %q does not fit into %d bytes", ErrStringTooLong, input, maxLen)
+			break
+		}
+
+		n += copy(buffer[n:], encoded)
 	}
 
-	copy(buffer, encoded)
-	for i := len(encoded); i < int(maxLen); i++ {
+	for i := n; i < int(maxLen); i++ {
 		buffer[i] = 0x00
 	}
 
-	return maxLen, nil
+	return maxLen, err
 }
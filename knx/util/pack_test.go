@@ -4,6 +4,7 @@
 package util
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -36,3 +37,31 @@ func TestPackString(t *testing.T) {
 	}
 
 }
+
+func TestPackString_Invalid(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Data     string
+		Expected []byte
+		Err      bool
+	}{
+		{"Exact", "0123", []byte("0123"), false},
+		{"Latin1Truncated", "Küche", []byte{'K', 0xfc, 'c', 'h'}, true},
+		{"TooLong", "012345", []byte("0123"), true},
+		{"Unsupported", "a€b", []byte{'a', '?', 'b', 0x00}, true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			buffer := []byte{0xff, 0xff, 0xff, 0xff}
+
+			n, err := PackString(buffer, 4, testCase.Data)
+			assert.Equal(t, uint(4), n, "Produced bytes not equal")
+			assert.Equal(t, testCase.Expected, buffer, "Packed buffer not equal")
+			assert.Equal(t, testCase.Err, err != nil, "Unexpected error %v", err)
+		})
+	}
+
+	_, err := PackString(make([]byte, 4), 4, "012345")
+	assert.True(t, errors.Is(err, ErrStringTooLong))
+}
@@ -0,0 +1,42 @@
+// Licensed under the MIT license which can be found in the LICENSE file.
+
+package util
+
+import (
+	"sync"
+)
+
+// pooledBufferSize is the capacity of the buffers kept in the pool. It suffices for most
+// KNXnet/IP frames, larger buffers are allocated on demand and never pooled.
+const pooledBufferSize = 512
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new([pooledBufferSize]byte)
+	},
+}
+
+// AcquireBuffer returns a zeroed buffer of the given size. The buffer should be handed back
+// using ReleaseBuffer once it is no longer in use.
+func AcquireBuffer(size uint) []byte {
+	if size > pooledBufferSize {
+		return make([]byte, size)
+	}
+
+	buffer := bufferPool.Get().(*[pooledBufferSize]byte)[:size]
+	for i := range buffer {
+		buffer[i] = 0
+	}
+
+	return buffer
+}
+
+// ReleaseBuffer hands a buffer obtained from AcquireBuffer back to the pool. The buffer must not
+// be used afterwards.
+func ReleaseBuffer(buffer []byte) {
+	if cap(buffer) != pooledBufferSize {
+		return
+	}
+
+	bufferPool.Put((*[pooledBufferSize]byte)(buffer[:pooledBufferSize]))
+}
@@ -0,0 +1,38 @@
+// Licensed under the MIT license which can be found in the LICENSE file.
+
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAcquireBuffer(t *testing.T) {
+	buffer := AcquireBuffer(32)
+	assert.Len(t, buffer, 32)
+
+	for i := range buffer {
+		buffer[i] = 0xff
+	}
+	ReleaseBuffer(buffer)
+
+	// Buffers taken from the pool must not contain stale data.
+	buffer = AcquireBuffer(64)
+	assert.Equal(t, make([]byte, 64), buffer)
+	ReleaseBuffer(buffer)
+
+	// Oversized buffers are allocated on demand.
+	buffer = AcquireBuffer(pooledBufferSize + 1)
+	assert.Len(t, buffer, pooledBufferSize+1)
+	ReleaseBuffer(buffer)
+}
+
+func BenchmarkAcquireBuffer(b *testing.B) {
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		buffer := AcquireBuffer(64)
+		ReleaseBuffer(buffer)
+	}
+}
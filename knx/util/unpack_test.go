@@ -199,3 +199,10 @@ func TestUnpackString(t *testing.T) {
 	}
 
 }
+
+func TestUnpackString_Short(t *testing.T) {
+	var str string
+	if _, err := UnpackString([]byte("KNX"), 30, &str); err != io.ErrUnexpectedEOF {
+		t.Errorf("Unexpected error %v", err)
+	}
+}